@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiKeyScopes    []string
+	apiKeyRateLimit int
+	apiKeyUsageDays int
+	apiKeyUsageJSON bool
+	apiKeyRepo      database.APIKeyRepository
+)
+
+func init() {
+	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyScopes, "scopes", database.APIKeyScopes, "Comma-separated scopes: "+strings.Join(database.APIKeyScopes, ","))
+	apiKeyCreateCmd.Flags().IntVar(&apiKeyRateLimit, "rate-limit", 0, "Requests per hour (0 for no limit)")
+
+	apiKeyUsageCmd.Flags().IntVar(&apiKeyUsageDays, "days", 30, "Number of days to report")
+	apiKeyUsageCmd.Flags().BoolVar(&apiKeyUsageJSON, "json", false, "Output as JSON")
+
+	apiKeyCmd.AddCommand(apiKeyCreateCmd)
+	apiKeyCmd.AddCommand(apiKeyListCmd)
+	apiKeyCmd.AddCommand(apiKeyRevokeCmd)
+	apiKeyCmd.AddCommand(apiKeyUsageCmd)
+	rootCmd.AddCommand(apiKeyCmd)
+}
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys",
+	Long: `API keys give client groups their own credentials for the web and mobile
+API. Clients send the key id as the basic auth user and the secret as the
+password; BASIC_AUTH_USER and BASIC_AUTH_PASSWORD keep working.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		var err error
+		apiKeyRepo, err = database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		createAPIKey(args[0], apiKeyScopes, apiKeyRateLimit)
+	},
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listAPIKeys()
+	},
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <uuid|key-id>",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		revokeAPIKey(args[0])
+	},
+}
+
+var apiKeyUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report requests per API key",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		reportAPIKeyUsage(apiKeyUsageDays, apiKeyUsageJSON)
+	},
+}
+
+func createAPIKey(name string, scopes []string, rateLimit int) {
+	if strings.TrimSpace(name) == "" {
+		fmt.Fprintf(os.Stderr, "Name must not be empty\n")
+		os.Exit(1)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(database.APIKeyScopes, scope) {
+			fmt.Fprintf(os.Stderr, "Invalid --scopes: %s (allowed: %s)\n", scope, strings.Join(database.APIKeyScopes, ","))
+			os.Exit(1)
+		}
+	}
+	if rateLimit < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --rate-limit: %d\n", rateLimit)
+		os.Exit(1)
+	}
+
+	key, secret, err := database.GenerateAPIKey(name, scopes, rateLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate API key: %v\n", err)
+		os.Exit(1)
+	}
+	if err := apiKeyRepo.CreateAPIKey(key); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create API key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Created API key %q with id=%s\n", key.Name, key.ID)
+	fmt.Printf("User:   %s\n", key.KeyID)
+	fmt.Printf("Secret: %s (shown only once)\n", secret)
+}
+
+func listAPIKeys() {
+	keys, err := apiKeyRepo.ListAPIKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list API keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ID\tKeyID\tName\tScopes\tRateLimit\tLastUsed\tRevoked"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, key := range keys {
+		rateLimit := "-"
+		if key.RateLimit > 0 {
+			rateLimit = fmt.Sprintf("%d/h", key.RateLimit)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.ID,
+			key.KeyID,
+			key.Name,
+			strings.Join(key.Scopes, ","),
+			rateLimit,
+			formatOptionalTime(key.LastUsedAt),
+			formatOptionalTime(key.RevokedAt),
+		); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func revokeAPIKey(input string) {
+	id, err := uuid.Parse(input)
+	if err != nil {
+		key, err := apiKeyRepo.FindAPIKey(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to find API key: %v\n", err)
+			os.Exit(1)
+		}
+		if key == nil {
+			fmt.Fprintf(os.Stderr, "API key not found: %s\n", input)
+			os.Exit(1)
+		}
+		id = key.ID
+	}
+
+	revoked, err := apiKeyRepo.RevokeAPIKey(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to revoke API key: %v\n", err)
+		os.Exit(1)
+	}
+	if !revoked {
+		fmt.Fprintf(os.Stderr, "No active API key: %s\n", input)
+		os.Exit(1)
+	}
+	fmt.Printf("Revoked API key with id=%s\n", id)
+}
+
+func reportAPIKeyUsage(days int, asJSON bool) {
+	if days <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --days: %d\n", days)
+		os.Exit(1)
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour)
+	rows, err := apiKeyRepo.GetAPIKeyUsage(since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get API key usage: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "KeyID\tName\tRequests\tLastUsed\tRevoked"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+			row.KeyID,
+			row.Name,
+			row.Requests,
+			formatOptionalTime(row.LastUsedAt),
+			formatOptionalTime(row.RevokedAt),
+		); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyCommands(t *testing.T) {
+	mock := &mockAPIKeyRepo{}
+	apiKeyRepo = mock
+
+	out := captureOutput(func() {
+		createAPIKey("extension beta", []string{database.ScopeItemsRead, database.ScopeFeedbackWrite}, 600)
+	})
+	if !assert.Len(t, mock.keys, 1) {
+		return
+	}
+	key := mock.keys[0]
+	assert.True(t, strings.HasPrefix(key.KeyID, database.APIKeyPrefix))
+	assert.Contains(t, out, "User:   "+key.KeyID)
+	assert.Regexp(t, `Secret: [0-9a-f]{64} \(shown only once\)`, out)
+	assert.NotContains(t, out, key.SecretHash)
+
+	out = captureOutput(func() {
+		listAPIKeys()
+	})
+	assert.Regexp(t, key.KeyID+`\s+extension beta\s+items:read,feedback:write\s+600/h`, out)
+
+	captureOutput(func() {
+		revokeAPIKey(key.KeyID)
+	})
+	assert.NotNil(t, mock.keys[0].RevokedAt)
+
+	mock.usage = []database.APIKeyUsageReport{{ID: key.ID, KeyID: key.KeyID, Name: key.Name, Requests: 1234}}
+	out = captureOutput(func() {
+		reportAPIKeyUsage(7, false)
+	})
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -7), mock.usageSince, 24*time.Hour)
+	assert.Regexp(t, key.KeyID+`\s+extension beta\s+1234`, out)
+}
+
+// mockAPIKeyRepo keeps the keys the API key commands manage.
+type mockAPIKeyRepo struct {
+	database.APIKeyRepository
+	keys       []database.APIKey
+	usage      []database.APIKeyUsageReport
+	usageSince time.Time
+}
+
+func (m *mockAPIKeyRepo) CreateAPIKey(key *database.APIKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *mockAPIKeyRepo) ListAPIKeys() ([]database.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *mockAPIKeyRepo) FindAPIKey(keyID string) (*database.APIKey, error) {
+	for i := range m.keys {
+		if m.keys[i].KeyID == keyID {
+			return &m.keys[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAPIKeyRepo) RevokeAPIKey(id uuid.UUID) (bool, error) {
+	for i := range m.keys {
+		if m.keys[i].ID == id && m.keys[i].RevokedAt == nil {
+			now := time.Now()
+			m.keys[i].RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockAPIKeyRepo) GetAPIKeyUsage(since time.Time) ([]database.APIKeyUsageReport, error) {
+	m.usageSince = since
+	return m.usage, nil
+}
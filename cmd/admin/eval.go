@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deframer/news-deframer/pkg/eval"
+	"github.com/deframer/news-deframer/pkg/think"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalDataset          string
+	evalPrompt           string
+	evalLLMType          string
+	evalModel            string
+	evalPromptDir        string
+	evalAgainstLLMType   string
+	evalAgainstModel     string
+	evalAgainstPromptDir string
+	evalJSON             bool
+)
+
+func init() {
+	evalCmd.Flags().StringVarP(&evalDataset, "dataset", "f", "", "Labeled JSONL dataset")
+	evalCmd.Flags().StringVar(&evalPrompt, "prompt", "deframer", "Prompt name")
+	evalCmd.Flags().StringVar(&evalLLMType, "llm-type", "", "LLM type (default: LLM_TYPE)")
+	evalCmd.Flags().StringVar(&evalModel, "model", "", "LLM model (default: LLM_MODEL or the ensemble)")
+	evalCmd.Flags().StringVar(&evalPromptDir, "prompt-dir", "", "Prompt directory (default: PROMPT_DIR)")
+	evalCmd.Flags().StringVar(&evalAgainstLLMType, "against-llm-type", "", "Compare against this LLM type")
+	evalCmd.Flags().StringVar(&evalAgainstModel, "against-model", "", "Compare against this LLM model")
+	evalCmd.Flags().StringVar(&evalAgainstPromptDir, "against-prompt-dir", "", "Compare against the prompts in this directory")
+	evalCmd.Flags().BoolVar(&evalJSON, "json", false, "Output as JSON")
+	_ = evalCmd.MarkFlagRequired("dataset")
+
+	rootCmd.AddCommand(evalCmd)
+}
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Score an LLM provider against a labeled dataset",
+	Long: `Runs the configured provider over a labeled JSONL dataset and reports the
+mean absolute error per dimension, category accuracy, failure rate, invalid JSON rate and latency.
+Any --against-* flag runs a second configuration and prints the difference.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runEval(cmd.Context())
+	},
+}
+
+// evalTarget is one provider configuration under evaluation.
+type evalTarget struct {
+	llmType   string
+	model     string
+	promptDir string
+}
+
+func runEval(ctx context.Context) {
+	file, err := os.Open(evalDataset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open dataset: %v\n", err)
+		os.Exit(1)
+	}
+	cases, err := eval.LoadDataset(file)
+	_ = file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read dataset: %v\n", err)
+		os.Exit(1)
+	}
+
+	a := evalTarget{llmType: evalLLMType, model: evalModel, promptDir: evalPromptDir}
+	reportA := evaluate(ctx, a, cases)
+
+	compare := evalAgainstLLMType != "" || evalAgainstModel != "" || evalAgainstPromptDir != ""
+	if !compare {
+		printEvalReport(reportA, evalJSON)
+		return
+	}
+
+	b := a
+	if evalAgainstLLMType != "" {
+		b.llmType = evalAgainstLLMType
+	}
+	if evalAgainstModel != "" {
+		b.model = evalAgainstModel
+	}
+	if evalAgainstPromptDir != "" {
+		b.promptDir = evalAgainstPromptDir
+	}
+	reportB := evaluate(ctx, b, cases)
+	printEvalComparison(reportA, reportB, evalJSON)
+}
+
+func evaluate(ctx context.Context, target evalTarget, cases []eval.Case) *eval.Report {
+	t, label, err := newEvalThink(ctx, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create provider: %v\n", err)
+		os.Exit(1)
+	}
+	return eval.Run(label, t, evalPrompt, cases)
+}
+
+// newEvalThink builds the provider directly, without the analysis cache, so
+// every case is really sent to the LLM. The prompts are process wide, which
+// is why the targets are evaluated one after the other.
+func newEvalThink(ctx context.Context, target evalTarget) (think.Think, string, error) {
+	c := *cfg
+	if target.llmType != "" {
+		if err := c.LLM_Type.UnmarshalText([]byte(target.llmType)); err != nil {
+			return nil, "", err
+		}
+	}
+	if target.model != "" {
+		c.LLM_Model = target.model
+		c.LLM_EnsembleModels = nil
+	}
+
+	promptDir := c.PromptDir
+	if target.promptDir != "" {
+		promptDir = target.promptDir
+	}
+	if err := think.ConfigurePrompts(promptDir); err != nil {
+		return nil, "", fmt.Errorf("prompt dir %s: %w", promptDir, err)
+	}
+	c.PromptDir = ""
+
+	t, err := think.New(ctx, &c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	label := c.ThinkModel()
+	if versions := think.PromptVersions(evalPrompt); versions["en"] != "" {
+		label += "@" + versions["en"]
+	}
+	return t, label, nil
+}
+
+func printEvalReport(report *eval.Report, asJSON bool) {
+	if asJSON {
+		writeEvalJSON(report)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	evalPrintf(w, "Provider\t%s\n", report.Label)
+	evalPrintf(w, "Cases\t%d\n", report.Cases)
+	evalPrintf(w, "Failures\t%d (%.1f%%)\n", report.Failures, 100*report.FailureRate)
+	evalPrintf(w, "Invalid JSON\t%d (%.1f%%)\n", report.InvalidJSON, 100*report.InvalidJSONRate)
+	for _, dim := range eval.Dimensions {
+		if mae, ok := report.MAE[dim]; ok {
+			evalPrintf(w, "MAE %s\t%.3f\n", dim, mae)
+		}
+	}
+	if report.CategoryCases > 0 {
+		evalPrintf(w, "Category accuracy\t%.1f%% (%d cases)\n", 100*report.CategoryAccuracy, report.CategoryCases)
+	}
+	evalPrintf(w, "Latency avg\t%.0fms\n", report.AvgLatencyMs)
+	evalPrintf(w, "Latency p95\t%dms\n", report.P95LatencyMs)
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printEvalComparison(a, b *eval.Report, asJSON bool) {
+	metrics := eval.Compare(a, b)
+	if asJSON {
+		writeEvalJSON(struct {
+			A       *eval.Report  `json:"a"`
+			B       *eval.Report  `json:"b"`
+			Metrics []eval.Metric `json:"metrics"`
+		}{a, b, metrics})
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	evalPrintf(w, "Metric\tA: %s\tB: %s\tDelta\t\n", a.Label, b.Label)
+	for _, m := range metrics {
+		marker := ""
+		if m.Better {
+			marker = "better"
+		} else if m.Delta != 0 {
+			marker = "worse"
+		}
+		evalPrintf(w, "%s\t%.3f\t%.3f\t%+.3f\t%s\n", m.Name, m.A, m.B, m.Delta, marker)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func evalPrintf(w io.Writer, format string, args ...any) {
+	if _, err := fmt.Fprintf(w, format, args...); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func writeEvalJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
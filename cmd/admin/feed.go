@@ -12,6 +12,7 @@ import (
 
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/deframer/news-deframer/pkg/downloader"
+	"github.com/deframer/news-deframer/pkg/langdetect"
 	"github.com/deframer/news-deframer/pkg/syncer"
 	"github.com/deframer/news-deframer/pkg/util/netutil"
 	"github.com/google/uuid"
@@ -30,7 +31,7 @@ var (
 	noRootDomain   bool
 	purgeFeed      bool
 	portalUrl      string
-	repo           database.Repository
+	repo           syncer.Repository // the feed and thinker commands run a syncer on it
 	feedSyncer     *syncer.Syncer
 	feedDownloader downloader.Downloader
 )
@@ -52,6 +53,7 @@ func init() {
 
 	languageCmd.AddCommand(setLanguageCmd)
 	languageCmd.AddCommand(deleteLanguageCmd)
+	languageCmd.AddCommand(languageStatsCmd)
 	feedCmd.AddCommand(languageCmd)
 
 	countryCmd.AddCommand(setCountryCmd)
@@ -221,6 +223,14 @@ var deleteLanguageCmd = &cobra.Command{
 	},
 }
 
+var languageStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show detected item languages against the feed language",
+	Run: func(cmd *cobra.Command, args []string) {
+		listLanguageStats()
+	},
+}
+
 var countryCmd = &cobra.Command{
 	Use:   "country",
 	Short: "Manage feed country",
@@ -414,6 +424,65 @@ func listFeedErrors() {
 	}
 }
 
+func listLanguageStats() {
+	stats, err := repo.GetFeedLanguageStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list language stats: %v\n", err)
+		os.Exit(1)
+	}
+
+	type feedStats struct {
+		url        string
+		declared   string
+		items      int64
+		unknown    int64
+		mismatches int64
+		detected   []string
+	}
+	var order []uuid.UUID
+	byFeed := map[uuid.UUID]*feedStats{}
+	for _, stat := range stats {
+		fs, ok := byFeed[stat.FeedID]
+		if !ok {
+			fs = &feedStats{url: stat.URL, declared: "-"}
+			if stat.DeclaredLanguage != nil {
+				fs.declared = *stat.DeclaredLanguage
+			}
+			byFeed[stat.FeedID] = fs
+			order = append(order, stat.FeedID)
+		}
+		fs.items += stat.Items
+		switch {
+		case stat.Language == langdetect.Undetermined:
+			fs.unknown += stat.Items
+		case stat.Language != fs.declared:
+			fs.mismatches += stat.Items
+		}
+		fs.detected = append(fs.detected, fmt.Sprintf("%s:%d", stat.Language, stat.Items))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "URL\tLanguage\tItems\tUndetermined\tMismatches\tMismatchRate\tDetected"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, id := range order {
+		fs := byFeed[id]
+		rate := 0.0
+		if detected := fs.items - fs.unknown; detected > 0 {
+			rate = float64(fs.mismatches) / float64(detected)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f%%\t%s\n", fs.url, fs.declared, fs.items, fs.unknown, fs.mismatches, 100*rate, strings.Join(fs.detected, ",")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func addFeed(feedUrl string, enabled bool, polling bool, mining bool, noRootDomain bool, language string, resolveItemUrl bool, categories []string, portalUrl string) {
 	u, err := parseAndNormalizeURL(feedUrl)
 
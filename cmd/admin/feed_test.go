@@ -16,6 +16,7 @@ import (
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/deframer/news-deframer/pkg/downloader"
+	"github.com/deframer/news-deframer/pkg/eval"
 	"github.com/deframer/news-deframer/pkg/syncer"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -774,6 +775,199 @@ func TestFeedErrorsCommand(t *testing.T) {
 	assert.Contains(t, out, "boom")
 }
 
+func TestLanguageStatsCommand(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+
+	feedID := uuid.New()
+	de := "de"
+	mock.languageStats = []database.FeedLanguageStatsResult{
+		{FeedID: feedID, URL: "http://example.com/rss", DeclaredLanguage: &de, Language: "de", Items: 90},
+		{FeedID: feedID, URL: "http://example.com/rss", DeclaredLanguage: &de, Language: "en", Items: 9},
+		{FeedID: feedID, URL: "http://example.com/rss", DeclaredLanguage: &de, Language: "und", Items: 1},
+	}
+
+	out := captureOutput(func() {
+		listLanguageStats()
+	})
+
+	assert.Contains(t, out, "MismatchRate")
+	assert.Regexp(t, `http://example.com/rss\s+de\s+100\s+1\s+9\s+9.1%\s+de:90,en:9,und:1`, out)
+}
+
+func TestUsageCommand(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+	cfg = &config.Config{LLM_MonthlyBudget: 10}
+	defer func() { cfg = nil }()
+
+	day := time.Date(2026, time.May, 17, 0, 0, 0, 0, time.UTC)
+	model := "gpt-4o-mini"
+	mock.usageReport = []database.LLMUsageReport{
+		{Day: &day, LLMModel: &model, Calls: 3, PromptTokens: 1200, CompletionTokens: 300, AvgLatencyMs: 812, Cost: 0.0125},
+	}
+	assert.NoError(t, mock.CreateLLMUsages([]database.LLMUsage{{CreatedAt: time.Now(), Cost: 1.5}}))
+
+	out := captureOutput(func() {
+		reportUsage(30, []string{"day", "model"}, false)
+	})
+
+	assert.Equal(t, []string{"day", "model"}, mock.lastUsageGroupBy)
+	assert.Contains(t, out, "Day")
+	assert.Contains(t, out, "Model")
+	assert.NotContains(t, out, "FeedURL")
+	assert.Regexp(t, `2026-05-17\s+gpt-4o-mini\s+3\s+1200\s+300\s+0\s+812\s+0.0125`, out)
+	assert.Contains(t, out, "Month to date: 1.5000 / 10.00 USD")
+
+	out = captureOutput(func() {
+		reportUsage(7, []string{"feed"}, true)
+	})
+	var rows []database.LLMUsageReport
+	assert.NoError(t, json.Unmarshal([]byte(out), &rows))
+	assert.Len(t, rows, 1)
+	assert.Equal(t, []string{"feed"}, mock.lastUsageGroupBy)
+}
+
+func TestEvalCommand(t *testing.T) {
+	cfg = &config.Config{LLM_Type: config.Dummy, LLM_Model: "dummy-a"}
+	defer func() {
+		cfg = nil
+		evalDataset, evalAgainstModel, evalJSON = "", "", false
+	}()
+	evalDataset = "../../docker/eval-example.jsonl"
+	evalPrompt = "deframer"
+
+	out := captureOutput(func() {
+		runEval(context.Background())
+	})
+	assert.Contains(t, out, "dummy-a@")
+	assert.Regexp(t, `Cases\s+3`, out)
+	assert.Contains(t, out, "MAE overall")
+	assert.Contains(t, out, "Category accuracy")
+
+	evalAgainstModel = "dummy-b"
+	evalJSON = true
+	out = captureOutput(func() {
+		runEval(context.Background())
+	})
+	var comparison struct {
+		A       eval.Report   `json:"a"`
+		B       eval.Report   `json:"b"`
+		Metrics []eval.Metric `json:"metrics"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &comparison))
+	assert.Contains(t, comparison.A.Label, "dummy-a")
+	assert.Contains(t, comparison.B.Label, "dummy-b")
+	assert.NotEmpty(t, comparison.Metrics)
+}
+
+func TestThinkerCommands(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+
+	itemID := uuid.New()
+	createdAt := time.Date(2026, time.May, 17, 12, 34, 0, 0, time.UTC)
+	mock.thinkHistory = map[uuid.UUID][]database.ThinkResultRecord{
+		itemID: {
+			{CreatedAt: createdAt, LLMModel: "bad-model", PromptVersion: "v2", ThinkRating: 0.9, ThinkResult: &database.ThinkResult{Category: "politics", OverallReason: "shouting"}},
+			{CreatedAt: createdAt.Add(-time.Hour), LLMModel: "good-model", PromptVersion: "v1", ThinkRating: 0.3, ThinkResult: &database.ThinkResult{Category: "business"}},
+		},
+	}
+
+	out := captureOutput(func() {
+		showThinkHistory(itemID.String(), false, true)
+	})
+	assert.Contains(t, out, "PromptVersion")
+	assert.Regexp(t, `2026-05-17 12:34\s+bad-model\s+v2\s+0.90\s+politics\s+shouting`, out)
+	assert.Contains(t, out, "good-model")
+
+	mock.rolledBack = []uuid.UUID{itemID}
+	out = captureOutput(func() {
+		rollbackThinkResults("bad-model", "2026-05-01")
+	})
+	assert.Equal(t, "bad-model", mock.lastRollback)
+	assert.Contains(t, out, "Rolled back 1 items analyzed by bad-model")
+}
+
+func TestItemSearchCommand(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+
+	mock.searchHits = []database.SearchHit{
+		{
+			Item: database.AnalyzedItem{
+				ID:          uuid.New(),
+				URL:         "http://example.com/floods",
+				ThinkResult: &database.ThinkResult{Category: "environment"},
+				ThinkRating: 0.2,
+				PubDate:     time.Date(2026, 5, 17, 12, 34, 0, 0, time.UTC),
+			},
+			Rank:           0.5,
+			TitleHighlight: "<mark>Floods</mark> hit the coast",
+		},
+	}
+
+	out := captureOutput(func() {
+		searchItems(database.ItemSearch{Query: "flood", Language: "en", Limit: 1}, false)
+	})
+	assert.Equal(t, "flood", mock.lastSearch.Query)
+	assert.Equal(t, "en", mock.lastSearch.Language)
+	assert.Regexp(t, `0.500\s+2026-05-17 12:34\s+0.20\s+environment\s+http://example.com/floods\s+\*Floods\* hit the coast`, out)
+	assert.Contains(t, out, "Next page: --cursor ")
+
+	out = captureOutput(func() {
+		searchItems(database.ItemSearch{Query: "flood", Limit: 20}, true)
+	})
+	assert.Contains(t, out, `"title_highlight": "\u003cmark\u003eFloods\u003c/mark\u003e hit the coast"`)
+	assert.NotContains(t, out, "next_cursor")
+}
+
+func TestThinkerDeadCommands(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+
+	cfg = &config.Config{LLM_Type: config.Dummy, LLM_Model: "dummy-a"}
+	defer func() { cfg = nil }()
+
+	mock.queueDepth = database.ThinkerQueueDepth{Thinker: 12, Fixer: 4, Dead: 7, UpdateLLMModel: 30, UpdatePrompt: 9}
+	out := captureOutput(func() {
+		showThinkerQueue(false)
+	})
+	assert.Regexp(t, `thinker\s+12`, out)
+	assert.Regexp(t, `fixer\s+4`, out)
+	assert.Regexp(t, `dead\s+7`, out)
+	assert.Regexp(t, `update-llm-model\s+30`, out)
+	assert.Regexp(t, `update-prompt\s+9`, out)
+
+	feedID := uuid.New()
+	mock.deadGroups = []database.DeadItemGroup{
+		{FeedID: feedID, FeedURL: "http://example.com/rss", ThinkError: "Context size\nhas been exceeded", Items: 5},
+	}
+	out = captureOutput(func() {
+		listDeadItems(deadItemFilter(nil), false, 0, false)
+	})
+	assert.Regexp(t, `5\s+\S+\s+\S+\s+`+feedID.String()+`\s+http://example.com/rss\s+Context size has been exceeded`, out)
+	assert.Equal(t, syncer.DeadLetterErrorCount, mock.deadFilters[0].MinErrorCount)
+
+	deadFeed, deadError = feedID.String(), "context size"
+	defer func() { deadFeed, deadError = "", "" }()
+	itemID := uuid.New()
+	out = captureOutput(func() {
+		requeueDeadItems(deadItemFilter([]string{itemID.String()}))
+	})
+	assert.Contains(t, out, "Requeued 3 items")
+	filter := mock.deadFilters[1]
+	assert.Equal(t, []uuid.UUID{itemID}, filter.IDs)
+	assert.Equal(t, feedID, *filter.FeedID)
+	assert.Equal(t, "context size", filter.ErrorContains)
+
+	out = captureOutput(func() {
+		purgeDeadItems(deadItemFilter(nil))
+	})
+	assert.Contains(t, out, "Purged 2 items")
+	assert.Equal(t, syncer.DeadLetterErrorCount, mock.deadFilters[2].MinErrorCount)
+}
+
 func TestSyncImportedFeedStopWordsDeletesWhenMissing(t *testing.T) {
 	mock := NewMockRepo()
 	repo = mock
@@ -800,9 +994,21 @@ func TestSyncImportedFeedStopWordsDeletesWhenMissing(t *testing.T) {
 // --- Mock Repository ---
 
 type MockRepo struct {
-	feeds     map[uuid.UUID]*database.Feed
-	stopWords map[uuid.UUID]*database.StopWords
-	items     []database.Item
+	feeds            map[uuid.UUID]*database.Feed
+	stopWords        map[uuid.UUID]*database.StopWords
+	items            []database.Item
+	usages           []database.LLMUsage
+	usageReport      []database.LLMUsageReport
+	lastUsageGroupBy []string
+	thinkHistory     map[uuid.UUID][]database.ThinkResultRecord
+	rolledBack       []uuid.UUID
+	lastRollback     string
+	languageStats    []database.FeedLanguageStatsResult
+	queueDepth       database.ThinkerQueueDepth
+	deadGroups       []database.DeadItemGroup
+	deadFilters      []database.DeadItemFilter
+	lastSearch       database.ItemSearch
+	searchHits       []database.SearchHit
 }
 
 func NewMockRepo() *MockRepo {
@@ -1021,6 +1227,100 @@ func (m *MockRepo) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string, l
 	return nil, nil
 }
 
+func (m *MockRepo) CreateLLMUsages(usages []database.LLMUsage) error {
+	m.usages = append(m.usages, usages...)
+	return nil
+}
+
+func (m *MockRepo) SumLLMCostSince(since time.Time) (float64, error) {
+	var cost float64
+	for _, usage := range m.usages {
+		if !usage.CreatedAt.Before(since) {
+			cost += usage.Cost
+		}
+	}
+	return cost, nil
+}
+
+func (m *MockRepo) AddFeedLanguageStats(feedID uuid.UUID, counts map[string]int64) error {
+	return nil
+}
+
+func (m *MockRepo) GetFeedLanguageStats() ([]database.FeedLanguageStatsResult, error) {
+	return m.languageStats, nil
+}
+
+func (m *MockRepo) ListThinkResultHistory(itemID uuid.UUID) ([]database.ThinkResultRecord, error) {
+	return m.thinkHistory[itemID], nil
+}
+
+func (m *MockRepo) RollbackThinkResults(llmModel string, since time.Time) ([]uuid.UUID, error) {
+	m.lastRollback = llmModel
+	return m.rolledBack, nil
+}
+
+func (m *MockRepo) CountLockWaits() (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepo) Ping(timeout time.Duration) error {
+	return nil
+}
+
+func (m *MockRepo) GetSchemaStatus(timeout time.Duration) (*database.SchemaStatus, error) {
+	return &database.SchemaStatus{Version: database.SchemaVersion}, nil
+}
+
+func (m *MockRepo) WithContext(ctx context.Context) database.Repository {
+	return m
+}
+
+func (m *MockRepo) CountThinkerUpdateCandidates(llmModel string, promptVersions map[string]string) (int64, int64, error) {
+	return m.queueDepth.UpdateLLMModel, m.queueDepth.UpdatePrompt, nil
+}
+
+func (m *MockRepo) GetThinkerQueueDepth(maxRetries int, maxFixerErrorCount int) (*database.ThinkerQueueDepth, error) {
+	depth := m.queueDepth
+	return &depth, nil
+}
+
+func (m *MockRepo) GetDeadItemGroups(filter database.DeadItemFilter) ([]database.DeadItemGroup, error) {
+	m.deadFilters = append(m.deadFilters, filter)
+	return m.deadGroups, nil
+}
+
+func (m *MockRepo) FindDeadItems(filter database.DeadItemFilter, limit int) ([]database.Item, error) {
+	m.deadFilters = append(m.deadFilters, filter)
+	return nil, nil
+}
+
+func (m *MockRepo) RequeueDeadItems(filter database.DeadItemFilter) (int64, error) {
+	m.deadFilters = append(m.deadFilters, filter)
+	return 3, nil
+}
+
+func (m *MockRepo) PurgeDeadItems(filter database.DeadItemFilter) (int64, error) {
+	m.deadFilters = append(m.deadFilters, filter)
+	return 2, nil
+}
+
+func (m *MockRepo) GetLLMUsageReport(since time.Time, groupBy []string) ([]database.LLMUsageReport, error) {
+	m.lastUsageGroupBy = groupBy
+	return m.usageReport, nil
+}
+
+func (m *MockRepo) FindThinkCache(key string) (*database.ThinkResult, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) UpsertThinkCache(entry *database.ThinkCache) error {
+	return nil
+}
+
+func (m *MockRepo) BeginThinkerUpdatePromptVersionBatch(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]database.Item, error) {
+	return nil, nil
+}
+
 func (m *MockRepo) FindFeedScheduleById(feedID uuid.UUID) (*database.FeedSchedule, error) {
 	if f, ok := m.feeds[feedID]; ok {
 		return f.FeedSchedule, nil
@@ -1041,7 +1341,7 @@ func (m *MockRepo) FindItemsByRootDomain(rootDomain string, limit int) ([]databa
 	return nil, nil
 }
 
-func (m *MockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, limit int) ([]database.AnalyzedItem, error) {
+func (m *MockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
 	return nil, nil
 }
 
@@ -1049,6 +1349,23 @@ func (m *MockRepo) FindFirstAnalyzedItemByUrl(u *url.URL) (*database.AnalyzedIte
 	return nil, nil
 }
 
+func (m *MockRepo) FindFirstAnalyzedItemsByUrls(urls []string) (map[string]database.AnalyzedItem, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) SearchItems(search database.ItemSearch) ([]database.SearchHit, error) {
+	m.lastSearch = search
+	return m.searchHits, nil
+}
+
+func (m *MockRepo) FindItemEventsAfter(after int64, filter database.ItemEventFilter, limit int) ([]database.ItemEvent, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) LatestItemEventID() (int64, error) {
+	return 0, nil
+}
+
 func (m *MockRepo) GetTopTrendByDomain(domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error) {
 	return nil, nil
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedbackDays    int
+	feedbackGroupBy string
+	feedbackLimit   int
+	feedbackJSON    bool
+	feedbackRepo    database.FeedbackRepository
+)
+
+func init() {
+	feedbackCmd.Flags().IntVar(&feedbackDays, "days", 30, "Number of days to report")
+	feedbackCmd.Flags().StringVar(&feedbackGroupBy, "group-by", "feed", "Grouping: "+strings.Join(database.FeedbackGroups, ","))
+	feedbackCmd.Flags().IntVar(&feedbackLimit, "limit", 50, "Maximum rows, most flagged first (0 for all)")
+	feedbackCmd.Flags().BoolVar(&feedbackJSON, "json", false, "Output as JSON")
+
+	rootCmd.AddCommand(feedbackCmd)
+}
+
+var feedbackCmd = &cobra.Command{
+	Use:   "feedback",
+	Short: "Report reader feedback on ratings and titles",
+	Long: `Aggregates the feedback readers submitted through the extension and the
+mobile app. Grouping by feed or model shows systematic errors; a high share of
+rating_too_high or rating_too_low points at a prompt or model problem.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		var err error
+		feedbackRepo, err = database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		reportFeedback(feedbackDays, feedbackGroupBy, feedbackLimit, feedbackJSON)
+	},
+}
+
+func reportFeedback(days int, groupBy string, limit int, asJSON bool) {
+	if days <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --days: %d\n", days)
+		os.Exit(1)
+	}
+	if !slices.Contains(database.FeedbackGroups, groupBy) {
+		fmt.Fprintf(os.Stderr, "Invalid --group-by: %s (allowed: %s)\n", groupBy, strings.Join(database.FeedbackGroups, ","))
+		os.Exit(1)
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour)
+	rows, err := feedbackRepo.GetFeedbackReport(since, groupBy, limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get feedback report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	var header []string
+	switch groupBy {
+	case "item":
+		header = []string{"ItemID", "ItemURL", "FeedURL"}
+	case "feed":
+		header = []string{"FeedID", "FeedURL", "Items"}
+	case "model":
+		header = []string{"Model", "Items"}
+	}
+	header = append(header, "Clients", "TooHigh", "TooLow", "TitleWrong", "AvgRating")
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, row := range rows {
+		var cols []string
+		switch groupBy {
+		case "item":
+			cols = []string{optionalUUID(row.ItemID), optionalString(row.ItemURL), optionalString(row.FeedURL)}
+		case "feed":
+			cols = []string{optionalUUID(row.FeedID), optionalString(row.FeedURL), fmt.Sprint(row.Items)}
+		case "model":
+			cols = []string{optionalString(row.LLMModel), fmt.Sprint(row.Items)}
+		}
+		cols = append(cols,
+			fmt.Sprint(row.Clients),
+			fmt.Sprint(row.RatingTooHigh),
+			fmt.Sprint(row.RatingTooLow),
+			fmt.Sprint(row.TitleWrong),
+			fmt.Sprintf("%.2f", row.AvgRating),
+		)
+		if _, err := fmt.Fprintln(w, strings.Join(cols, "\t")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackCommand(t *testing.T) {
+	mock := &mockFeedbackRepo{}
+	feedbackRepo = mock
+
+	feedID := uuid.New()
+	feedURL := "http://example.com/rss"
+	mock.report = []database.FeedbackReport{
+		{FeedID: &feedID, FeedURL: &feedURL, Items: 12, Clients: 30, RatingTooHigh: 25, RatingTooLow: 2, TitleWrong: 4, AvgRating: 0.71},
+	}
+
+	out := captureOutput(func() {
+		reportFeedback(7, "feed", 10, false)
+	})
+	assert.Equal(t, "feed", mock.lastGroupBy)
+	assert.Contains(t, out, "TooHigh")
+	assert.Regexp(t, feedID.String()+`\s+http://example.com/rss\s+12\s+30\s+25\s+2\s+4\s+0.71`, out)
+
+	out = captureOutput(func() {
+		reportFeedback(7, "model", 10, true)
+	})
+	var rows []database.FeedbackReport
+	assert.NoError(t, json.Unmarshal([]byte(out), &rows))
+	assert.Len(t, rows, 1)
+}
+
+// mockFeedbackRepo implements the report the feedback command reads.
+type mockFeedbackRepo struct {
+	database.FeedbackRepository
+	report      []database.FeedbackReport
+	lastGroupBy string
+}
+
+func (m *mockFeedbackRepo) GetFeedbackReport(since time.Time, groupBy string, limit int) ([]database.FeedbackReport, error) {
+	m.lastGroupBy = groupBy
+	return m.report, nil
+}
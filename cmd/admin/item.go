@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/eval"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reviewScores   = map[string]*float64{}
+	reviewCategory string
+	reviewNote     string
+	reviewer       string
+	reviewJSON     bool
+	reviewFile     string
+	reviewRepo     database.ReviewRepository
+
+	searchOptions database.ItemSearch
+	searchFrom    string
+	searchTo      string
+	searchCursor  string
+	searchJSON    bool
+)
+
+func init() {
+	for _, dim := range eval.Dimensions {
+		reviewScores[dim] = new(float64)
+		itemReviewCmd.Flags().Float64Var(reviewScores[dim], strings.ReplaceAll(dim, "_", "-"), 0, "Reviewed "+dim+" score (0-1)")
+	}
+	itemReviewCmd.Flags().StringVar(&reviewCategory, "category", "", "Reviewed category (english)")
+	itemReviewCmd.Flags().StringVar(&reviewNote, "note", "", "Why the analysis was corrected")
+	itemReviewCmd.Flags().StringVar(&reviewer, "reviewer", "admin", "Name of the reviewer")
+	itemReviewListCmd.Flags().BoolVar(&reviewJSON, "json", false, "Output as JSON")
+	itemReviewExportCmd.Flags().StringVarP(&reviewFile, "file", "f", "", "Output JSONL file (default: stdout)")
+
+	itemSearchCmd.Flags().StringVar(&searchOptions.Language, "lang", "", "Only items of this language")
+	itemSearchCmd.Flags().StringVar(&searchOptions.Domain, "domain", "", "Only items of this root domain")
+	itemSearchCmd.Flags().StringVar(&searchFrom, "from", "", "First publication date (YYYY-MM-DD)")
+	itemSearchCmd.Flags().StringVar(&searchTo, "to", "", "Last publication date (YYYY-MM-DD)")
+	itemSearchCmd.Flags().Float64Var(&searchOptions.MaxScore, "max-score", 0, "Maximum rating to include")
+	itemSearchCmd.Flags().StringVar(&searchOptions.Category, "category", "", "Only items of this category (english)")
+	itemSearchCmd.Flags().IntVar(&searchOptions.Limit, "limit", 20, "Maximum results (1-100)")
+	itemSearchCmd.Flags().StringVar(&searchCursor, "cursor", "", "Continue after a previous page")
+	itemSearchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output as JSON")
+
+	itemReviewCmd.AddCommand(itemReviewClearCmd)
+	itemReviewCmd.AddCommand(itemReviewListCmd)
+	itemReviewCmd.AddCommand(itemReviewExportCmd)
+	itemCmd.AddCommand(itemReviewCmd)
+	itemCmd.AddCommand(itemSearchCmd)
+
+	rootCmd.AddCommand(itemCmd)
+}
+
+var itemCmd = &cobra.Command{
+	Use:   "item",
+	Short: "Manage items",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		store, err := database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+		repo, reviewRepo = store, store
+	},
+}
+
+var itemReviewCmd = &cobra.Command{
+	Use:   "review <item-uuid|url>",
+	Short: "Correct the scores or category of an item",
+	Long: `Stores a reviewer correction next to the analysis of the LLM. Only the given
+scores and category are overridden; the API serves the corrected values and a
+new analysis never replaces them. A url reviews all syndicated copies.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		review := database.ItemReview{Reviewer: reviewer, Note: reviewNote}
+		for _, dim := range eval.Dimensions {
+			if cmd.Flags().Changed(strings.ReplaceAll(dim, "_", "-")) {
+				setReviewScore(&review, dim, *reviewScores[dim])
+			}
+		}
+		if reviewCategory != "" {
+			category := strings.TrimSpace(reviewCategory)
+			review.Category = &category
+		}
+		reviewItem(args[0], review)
+	},
+}
+
+var itemReviewClearCmd = &cobra.Command{
+	Use:   "clear <item-uuid|url>",
+	Short: "Remove the review of an item",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		clearItemReview(args[0])
+	},
+}
+
+var itemReviewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all reviews",
+	Run: func(cmd *cobra.Command, args []string) {
+		listItemReviews(reviewJSON)
+	},
+}
+
+var itemReviewExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export reviewed items as an eval dataset",
+	Long: `Writes one JSONL line per reviewed item in the format of admin eval. The
+expected scores are the reviewed values, or the values of the LLM the reviewer
+left unchanged.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exportItemReviews(reviewFile)
+	},
+}
+
+var itemSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Full-text search over the analyzed items",
+	Long: `Searches the original and corrected titles and descriptions like the
+/api/search endpoint. The query supports "quoted phrases", or and -word.
+Matched words are marked with *. A full page prints the cursor of the next.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		search := searchOptions
+		search.Query = strings.Join(args, " ")
+		search.From = parseSearchDate("--from", searchFrom)
+		if to := parseSearchDate("--to", searchTo); to != nil {
+			next := to.AddDate(0, 0, 1)
+			search.To = &next
+		}
+		if searchCursor != "" {
+			after, err := database.ParseSearchCursor(searchCursor)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid --cursor: %v\n", err)
+				os.Exit(1)
+			}
+			search.After = after
+		}
+		searchItems(search, searchJSON)
+	},
+}
+
+func parseSearchDate(flag string, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %s: %s\n", flag, value)
+		os.Exit(1)
+	}
+	return &date
+}
+
+func searchItems(search database.ItemSearch, asJSON bool) {
+	if search.Limit < 1 || search.Limit > 100 {
+		fmt.Fprintf(os.Stderr, "Invalid --limit: %d\n", search.Limit)
+		os.Exit(1)
+	}
+	hits, err := repo.SearchItems(search)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to search items: %v\n", err)
+		os.Exit(1)
+	}
+	next := ""
+	if len(hits) == search.Limit {
+		next = hits[len(hits)-1].Cursor().Encode()
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			Items      []database.SearchHit `json:"items"`
+			NextCursor string               `json:"next_cursor,omitempty"`
+		}{hits, next}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	marks := strings.NewReplacer("<mark>", "*", "</mark>", "*")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "Rank\tPubDate\tRating\tCategory\tURL\tTitle"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, hit := range hits {
+		category := "-"
+		if hit.Item.ThinkResult != nil && hit.Item.ThinkResult.Category != "" {
+			category = hit.Item.ThinkResult.Category
+		}
+		if _, err := fmt.Fprintf(w, "%.3f\t%s\t%.2f\t%s\t%s\t%s\n", hit.Rank, hit.Item.PubDate.Format("2006-01-02 15:04"), hit.Item.ThinkRating, category, hit.Item.URL, marks.Replace(hit.TitleHighlight)); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	if next != "" {
+		fmt.Printf("Next page: --cursor %s\n", next)
+	}
+}
+
+func setReviewScore(review *database.ItemReview, dim string, value float64) {
+	switch dim {
+	case "framing":
+		review.Framing = &value
+	case "clickbait":
+		review.Clickbait = &value
+	case "persuasive":
+		review.Persuasive = &value
+	case "hyper_stimulus":
+		review.HyperStimulus = &value
+	case "speculative":
+		review.Speculative = &value
+	case "overall":
+		review.Overall = &value
+	}
+}
+
+func reviewItem(identifier string, review database.ItemReview) {
+	if err := review.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid review: %v\n", err)
+		os.Exit(1)
+	}
+	for _, id := range resolveItemIDs(identifier) {
+		itemReview := review
+		itemReview.ItemID = id
+		if err := reviewRepo.UpsertItemReview(&itemReview); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save review: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Reviewed item %s\n", id)
+	}
+}
+
+func clearItemReview(identifier string) {
+	for _, id := range resolveItemIDs(identifier) {
+		if err := reviewRepo.DeleteItemReview(id); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete review: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cleared review of item %s\n", id)
+	}
+}
+
+func listItemReviews(asJSON bool) {
+	reviews, err := reviewRepo.ListItemReviews()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list reviews: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reviews); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ItemID\tUpdatedAt\tReviewer\tOverall\tCategory\tURL\tNote"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, review := range reviews {
+		overall, category := "-", "-"
+		if review.Overall != nil {
+			overall = fmt.Sprintf("%.2f", *review.Overall)
+		}
+		if review.Category != nil {
+			category = *review.Category
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", review.ItemID, review.UpdatedAt.Format("2006-01-02 15:04"), review.Reviewer, overall, category, review.Item.URL, strings.ReplaceAll(review.Note, "\n", " ")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func exportItemReviews(file string) {
+	reviews, err := reviewRepo.ListItemReviews()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list reviews: %v\n", err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create file: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to close file: %v\n", err)
+			}
+		}()
+		out = f
+	}
+
+	encoder := json.NewEncoder(out)
+	exported := 0
+	for _, review := range reviews {
+		c, ok := reviewCase(review)
+		if !ok {
+			continue
+		}
+		if err := encoder.Encode(c); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		exported++
+	}
+	if file != "" {
+		fmt.Printf("Exported %d reviews to %s\n", exported, file)
+	}
+}
+
+// reviewCase turns a review into an eval case. Items that were never
+// analyzed have no original text to evaluate against and are skipped.
+func reviewCase(review database.ItemReview) (eval.Case, bool) {
+	if review.Item.ThinkResult == nil {
+		return eval.Case{}, false
+	}
+	res := review.Apply(review.Item.ThinkResult)
+	language := "en"
+	if review.Item.Language != nil && *review.Item.Language != "" {
+		language = *review.Item.Language
+	}
+	return eval.Case{
+		ID:          review.ItemID.String(),
+		Title:       res.TitleOriginal,
+		Description: res.DescriptionOriginal,
+		Language:    language,
+		Expected: map[string]eval.Range{
+			"framing":        {res.Framing, res.Framing},
+			"clickbait":      {res.Clickbait, res.Clickbait},
+			"persuasive":     {res.Persuasive, res.Persuasive},
+			"hyper_stimulus": {res.HyperStimulus, res.HyperStimulus},
+			"speculative":    {res.Speculative, res.Speculative},
+			"overall":        {res.Overall, res.Overall},
+		},
+		Category: res.Category,
+	}, true
+}
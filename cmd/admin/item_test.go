@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/eval"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemReviewCommands(t *testing.T) {
+	mock := NewMockRepo()
+	repo = mock
+	reviews := &mockReviewRepo{items: &mock.items}
+	reviewRepo = reviews
+
+	itemID := uuid.New()
+	language := "de"
+	mock.items = []database.Item{{
+		ID:       itemID,
+		URL:      "http://example.com/article",
+		Language: &language,
+		ThinkResult: &database.ThinkResult{
+			TitleOriginal: "SKANDAL! Minister tritt zurück",
+			Framing:       0.2,
+			Clickbait:     0.9,
+			Overall:       0.8,
+			Category:      "opinion",
+		},
+	}}
+
+	overall := 0.3
+	category := "politics"
+	out := captureOutput(func() {
+		reviewItem(itemID.String(), database.ItemReview{Reviewer: "alice", Note: "plain resignation news", Overall: &overall, Category: &category})
+	})
+	assert.Contains(t, out, "Reviewed item "+itemID.String())
+	assert.Equal(t, "alice", reviews.reviews[itemID].Reviewer)
+
+	out = captureOutput(func() {
+		listItemReviews(false)
+	})
+	assert.Regexp(t, `alice\s+0.30\s+politics\s+http://example.com/article\s+plain resignation news`, out)
+
+	file := filepath.Join(t.TempDir(), "reviews.jsonl")
+	out = captureOutput(func() {
+		exportItemReviews(file)
+	})
+	assert.Contains(t, out, "Exported 1 reviews")
+	content, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	cases, err := eval.LoadDataset(bytes.NewReader(content))
+	assert.NoError(t, err)
+	if assert.Len(t, cases, 1) {
+		assert.Equal(t, "de", cases[0].Language)
+		assert.Equal(t, "politics", cases[0].Category)
+		assert.Equal(t, eval.Range{0.3, 0.3}, cases[0].Expected["overall"])
+		// unchanged scores of the LLM are part of the expectation
+		assert.Equal(t, eval.Range{0.9, 0.9}, cases[0].Expected["clickbait"])
+	}
+
+	out = captureOutput(func() {
+		clearItemReview(itemID.String())
+	})
+	assert.Contains(t, out, "Cleared review")
+	assert.Empty(t, reviews.reviews)
+}
+
+// mockReviewRepo keeps the reviews in memory and joins them with the items
+// of the MockRepo.
+type mockReviewRepo struct {
+	items   *[]database.Item
+	reviews map[uuid.UUID]database.ItemReview
+}
+
+func (m *mockReviewRepo) UpsertItemReview(review *database.ItemReview) error {
+	if m.reviews == nil {
+		m.reviews = make(map[uuid.UUID]database.ItemReview)
+	}
+	m.reviews[review.ItemID] = *review
+	return nil
+}
+
+func (m *mockReviewRepo) DeleteItemReview(itemID uuid.UUID) error {
+	delete(m.reviews, itemID)
+	return nil
+}
+
+func (m *mockReviewRepo) ListItemReviews() ([]database.ItemReview, error) {
+	var reviews []database.ItemReview
+	for _, review := range m.reviews {
+		for _, item := range *m.items {
+			if item.ID == review.ItemID {
+				review.Item = item
+			}
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
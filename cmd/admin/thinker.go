@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/syncer"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	thinkerJSON          bool
+	rollbackModel        string
+	rollbackSince        string
+	thinkerHistoryReason bool
+	deadFeed             string
+	deadError            string
+	deadAll              bool
+	deadItems            bool
+	deadLimit            int
+	deadJSON             bool
+	deadLLMType          string
+	deadModel            string
+	queueJSON            bool
+)
+
+func init() {
+	thinkerHistoryCmd.Flags().BoolVar(&thinkerJSON, "json", false, "Output as JSON")
+	thinkerHistoryCmd.Flags().BoolVar(&thinkerHistoryReason, "reasons", false, "Show the overall reason of each result")
+	thinkerRollbackCmd.Flags().StringVar(&rollbackModel, "model", "", "LLM model whose results are rolled back")
+	thinkerRollbackCmd.Flags().StringVar(&rollbackSince, "since", "", "Only roll back results created since this date (YYYY-MM-DD or RFC3339)")
+	_ = thinkerRollbackCmd.MarkFlagRequired("model")
+	thinkerDeadCmd.PersistentFlags().StringVar(&deadFeed, "feed", "", "Only items of this feed (uuid)")
+	thinkerDeadCmd.PersistentFlags().StringVar(&deadError, "error", "", "Only items whose error contains this text")
+	thinkerDeadListCmd.Flags().BoolVar(&deadItems, "items", false, "List the items instead of grouping them by feed and error")
+	thinkerDeadListCmd.Flags().IntVar(&deadLimit, "limit", 100, "Maximum items with --items (0 for all)")
+	thinkerDeadListCmd.Flags().BoolVar(&deadJSON, "json", false, "Output as JSON")
+	thinkerDeadRequeueCmd.Flags().BoolVar(&deadAll, "all", false, "Select all dead items")
+	thinkerDeadRequeueCmd.Flags().StringVar(&deadLLMType, "llm-type", "", "Analyze right away with this LLM type instead of requeueing")
+	thinkerDeadRequeueCmd.Flags().StringVar(&deadModel, "model", "", "Analyze right away with this LLM model instead of requeueing")
+	thinkerDeadPurgeCmd.Flags().BoolVar(&deadAll, "all", false, "Select all dead items")
+	thinkerQueueCmd.Flags().BoolVar(&queueJSON, "json", false, "Output as JSON")
+
+	thinkerCmd.AddCommand(thinkerHistoryCmd)
+	thinkerCmd.AddCommand(thinkerRollbackCmd)
+	thinkerDeadCmd.AddCommand(thinkerDeadListCmd)
+	thinkerDeadCmd.AddCommand(thinkerDeadRequeueCmd)
+	thinkerDeadCmd.AddCommand(thinkerDeadPurgeCmd)
+	thinkerCmd.AddCommand(thinkerDeadCmd)
+	thinkerCmd.AddCommand(thinkerQueueCmd)
+
+	rootCmd.AddCommand(thinkerCmd)
+}
+
+var thinkerCmd = &cobra.Command{
+	Use:   "thinker",
+	Short: "Inspect and manage analysis results",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		var err error
+		repo, err = database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var thinkerHistoryCmd = &cobra.Command{
+	Use:   "history <item-uuid|url>",
+	Short: "Show the analysis history of an item",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showThinkHistory(args[0], thinkerJSON, thinkerHistoryReason)
+	},
+}
+
+var thinkerRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore the previous analysis of items analyzed by a model",
+	Long: `Restores the previous analysis of every item whose current result was
+produced by --model. Items without an older result of another model are kept.
+Change LLM_MODEL first, otherwise thinker-update-llm-model analyzes them again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rollbackThinkResults(rollbackModel, rollbackSince)
+	},
+}
+
+var thinkerQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Show the number of items per thinker lane",
+	Run: func(cmd *cobra.Command, args []string) {
+		showThinkerQueue(queueJSON)
+	},
+}
+
+var thinkerDeadCmd = &cobra.Command{
+	Use:   "dead",
+	Short: "Manage items the thinker gave up on",
+	Long: fmt.Sprintf(`Items without analysis that failed %d or more times, or that the LLM refused
+to analyze, are neither retried by the thinker nor by the fixer. Select them
+by uuid, --feed or --error.`, syncer.DeadLetterErrorCount),
+}
+
+var thinkerDeadListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dead items grouped by feed and error",
+	Run: func(cmd *cobra.Command, args []string) {
+		listDeadItems(deadItemFilter(nil), deadItems, deadLimit, deadJSON)
+	},
+}
+
+var thinkerDeadRequeueCmd = &cobra.Command{
+	Use:   "requeue [item-uuid...]",
+	Short: "Put dead items back into the thinker queue",
+	Long: `Resets the error count of the selected items so the thinker analyzes them
+again. With --llm-type or --model the items are analyzed right away by this
+command with that provider instead; items that fail again stay dead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := deadItemFilter(args)
+		requireDeadSelection(args)
+		if deadLLMType != "" || deadModel != "" {
+			rethinkDeadItems(cmd.Context(), filter, deadLLMType, deadModel)
+			return
+		}
+		requeueDeadItems(filter)
+	},
+}
+
+var thinkerDeadPurgeCmd = &cobra.Command{
+	Use:   "purge [item-uuid...]",
+	Short: "Delete dead items",
+	Long: `Deletes the selected items. Items that are still part of their feed are
+ingested again on the next poll.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := deadItemFilter(args)
+		requireDeadSelection(args)
+		purgeDeadItems(filter)
+	},
+}
+
+func resolveItemIDs(identifier string) []uuid.UUID {
+	if id, err := uuid.Parse(identifier); err == nil {
+		return []uuid.UUID{id}
+	}
+
+	u, err := url.Parse(identifier)
+	if err != nil || u.Scheme == "" {
+		fmt.Fprintf(os.Stderr, "Invalid item uuid or url: %s\n", identifier)
+		os.Exit(1)
+	}
+	items, err := repo.FindItemsByUrl(u)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find items: %v\n", err)
+		os.Exit(1)
+	}
+	if len(items) == 0 {
+		fmt.Fprintf(os.Stderr, "No item found for %s\n", identifier)
+		os.Exit(1)
+	}
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func showThinkHistory(identifier string, asJSON bool, reasons bool) {
+	history := map[uuid.UUID][]database.ThinkResultRecord{}
+	ids := resolveItemIDs(identifier)
+	for _, id := range ids {
+		records, err := repo.ListThinkResultHistory(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get history: %v\n", err)
+			os.Exit(1)
+		}
+		history[id] = records
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(history); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := "ItemID\tCreatedAt\tModel\tPromptVersion\tRating\tCategory"
+	if reasons {
+		header += "\tReason"
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, id := range ids {
+		for _, record := range history[id] {
+			category := ""
+			reason := ""
+			if record.ThinkResult != nil {
+				category = record.ThinkResult.Category
+				reason = record.ThinkResult.OverallReason
+			}
+			line := fmt.Sprintf("%s\t%s\t%s\t%s\t%.2f\t%s", id, record.CreatedAt.Format("2006-01-02 15:04"), record.LLMModel, record.PromptVersion, record.ThinkRating, category)
+			if reasons {
+				line += "\t" + strings.ReplaceAll(reason, "\n", " ")
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func rollbackThinkResults(model string, since string) {
+	var sinceTime time.Time
+	if since != "" {
+		var err error
+		if sinceTime, err = time.Parse(time.DateOnly, since); err != nil {
+			if sinceTime, err = time.Parse(time.RFC3339, since); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid --since: %s\n", since)
+				os.Exit(1)
+			}
+		}
+	}
+
+	ids, err := repo.RollbackThinkResults(model, sinceTime)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to roll back: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Rolled back %d items analyzed by %s\n", len(ids), model)
+}
+
+func deadItemFilter(args []string) database.DeadItemFilter {
+	filter := database.DeadItemFilter{
+		MinErrorCount: syncer.DeadLetterErrorCount,
+		ErrorContains: deadError,
+	}
+	for _, arg := range args {
+		id, err := uuid.Parse(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid item uuid: %s\n", arg)
+			os.Exit(1)
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+	if deadFeed != "" {
+		id, err := uuid.Parse(deadFeed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --feed: %s\n", deadFeed)
+			os.Exit(1)
+		}
+		filter.FeedID = &id
+	}
+	return filter
+}
+
+// requireDeadSelection guards requeue and purge against acting on every dead
+// item by accident.
+func requireDeadSelection(args []string) {
+	if len(args) == 0 && deadFeed == "" && deadError == "" && !deadAll {
+		fmt.Fprintln(os.Stderr, "Select items by uuid, --feed or --error, or pass --all")
+		os.Exit(1)
+	}
+}
+
+func showThinkerQueue(asJSON bool) {
+	depth, err := syncer.ThinkerQueueDepth(cfg, repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get queue depth: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(depth); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintf(w, "Lane\tItems\nthinker\t%d\nfixer\t%d\ndead\t%d\nrefused\t%d\nupdate-llm-model\t%d\nupdate-prompt\t%d\n",
+		depth.Thinker, depth.Fixer, depth.Dead, depth.Refused, depth.UpdateLLMModel, depth.UpdatePrompt); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func listDeadItems(filter database.DeadItemFilter, items bool, limit int, asJSON bool) {
+	var rows any
+	var lines []string
+	if items {
+		dead, err := repo.FindDeadItems(filter, limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to find dead items: %v\n", err)
+			os.Exit(1)
+		}
+		rows = dead
+		lines = append(lines, "ItemID\tCreatedAt\tErrors\tKind\tFeedURL\tURL\tError")
+		for _, item := range dead {
+			lines = append(lines, fmt.Sprintf("%s\t%s\t%d\t%s\t%s\t%s\t%s", item.ID, item.CreatedAt.Format("2006-01-02 15:04"), item.ThinkErrorCount, optionalString(item.ThinkErrorKind), item.Feed.URL, item.URL, oneLine(optionalString(item.ThinkError))))
+		}
+	} else {
+		groups, err := repo.GetDeadItemGroups(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to group dead items: %v\n", err)
+			os.Exit(1)
+		}
+		rows = groups
+		lines = append(lines, "Items\tOldest\tNewest\tFeedID\tFeedURL\tKind\tError")
+		for _, group := range groups {
+			lines = append(lines, fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\t%s", group.Items, group.Oldest.Format("2006-01-02"), group.Newest.Format("2006-01-02"), group.FeedID, group.FeedURL, group.Kind, oneLine(group.ThinkError)))
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func requeueDeadItems(filter database.DeadItemFilter) {
+	count, err := repo.RequeueDeadItems(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to requeue dead items: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Requeued %d items\n", count)
+}
+
+func rethinkDeadItems(ctx context.Context, filter database.DeadItemFilter, llmType string, model string) {
+	c := *cfg
+	if llmType != "" {
+		if err := c.LLM_Type.UnmarshalText([]byte(llmType)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --llm-type: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if model != "" {
+		c.LLM_Model = model
+		c.LLM_EnsembleModels = nil
+	}
+	s, err := syncer.New(ctx, &c, repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	items, err := repo.FindDeadItems(filter, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find dead items: %v\n", err)
+		os.Exit(1)
+	}
+	analyzed, err := s.ThinkItems(items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to analyze dead items: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Analyzed %d of %d items with %s\n", analyzed, len(items), c.ThinkModel())
+}
+
+func purgeDeadItems(filter database.DeadItemFilter) {
+	count, err := repo.PurgeDeadItems(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to purge dead items: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Purged %d items\n", count)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
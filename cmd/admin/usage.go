@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageDays    int
+	usageGroupBy []string
+	usageJSON    bool
+)
+
+func init() {
+	usageCmd.Flags().IntVar(&usageDays, "days", 30, "Number of days to report")
+	usageCmd.Flags().StringSliceVar(&usageGroupBy, "group-by", []string{"day", "model"}, "Comma-separated grouping: "+strings.Join(database.LLMUsageGroups, ","))
+	usageCmd.Flags().BoolVar(&usageJSON, "json", false, "Output as JSON")
+
+	rootCmd.AddCommand(usageCmd)
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report LLM token usage and cost",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		var err error
+		repo, err = database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		reportUsage(usageDays, usageGroupBy, usageJSON)
+	},
+}
+
+func reportUsage(days int, groupBy []string, asJSON bool) {
+	if days <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --days: %d\n", days)
+		os.Exit(1)
+	}
+	for _, group := range groupBy {
+		if !slices.Contains(database.LLMUsageGroups, group) {
+			fmt.Fprintf(os.Stderr, "Invalid --group-by: %s (allowed: %s)\n", group, strings.Join(database.LLMUsageGroups, ","))
+			os.Exit(1)
+		}
+	}
+
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -days).Truncate(24 * time.Hour)
+	rows, err := repo.GetLLMUsageReport(since, groupBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get usage report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := []string{}
+	for _, group := range groupBy {
+		switch group {
+		case "day":
+			header = append(header, "Day")
+		case "model":
+			header = append(header, "Model")
+		case "feed":
+			header = append(header, "FeedID", "FeedURL")
+		}
+	}
+	header = append(header, "Calls", "PromptTokens", "CompletionTokens", "ReasoningTokens", "AvgLatencyMs", "Cost")
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, row := range rows {
+		cols := []string{}
+		for _, group := range groupBy {
+			switch group {
+			case "day":
+				day := ""
+				if row.Day != nil {
+					day = row.Day.Format(time.DateOnly)
+				}
+				cols = append(cols, day)
+			case "model":
+				model := ""
+				if row.LLMModel != nil {
+					model = *row.LLMModel
+				}
+				cols = append(cols, model)
+			case "feed":
+				feedID, feedURL := "", ""
+				if row.FeedID != nil {
+					feedID = row.FeedID.String()
+				}
+				if row.FeedURL != nil {
+					feedURL = *row.FeedURL
+				}
+				cols = append(cols, feedID, feedURL)
+			}
+		}
+		cols = append(cols,
+			fmt.Sprint(row.Calls),
+			fmt.Sprint(row.PromptTokens),
+			fmt.Sprint(row.CompletionTokens),
+			fmt.Sprint(row.ReasoningTokens),
+			fmt.Sprintf("%.0f", row.AvgLatencyMs),
+			fmt.Sprintf("%.4f", row.Cost),
+		)
+		if _, err := fmt.Fprintln(w, strings.Join(cols, "\t")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthCost, err := repo.SumLLMCostSince(monthStart)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to sum monthly cost: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg != nil && cfg.LLM_MonthlyBudget > 0 {
+		fmt.Printf("\nMonth to date: %.4f / %.2f USD\n", monthCost, cfg.LLM_MonthlyBudget)
+	} else {
+		fmt.Printf("\nMonth to date: %.4f USD\n", monthCost)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookEvents      []string
+	webhookDomains     []string
+	webhookCategories  []string
+	webhookMinScore    float64
+	webhookMaxScore    float64
+	webhookDescription string
+	webhookSecret      string
+
+	webhookDeliveriesWebhook string
+	webhookDeliveriesFailed  bool
+	webhookDeliveriesLimit   int
+	webhookDeliveriesJSON    bool
+
+	webhookRepo database.WebhookRepository
+)
+
+func init() {
+	webhookAddCmd.Flags().StringSliceVar(&webhookEvents, "events", nil, "Events to deliver: "+strings.Join(database.WebhookEvents, ",")+" (default all)")
+	webhookAddCmd.Flags().StringSliceVar(&webhookDomains, "domain", nil, "Only events of these root domains")
+	webhookAddCmd.Flags().StringSliceVar(&webhookCategories, "category", nil, "Only items of these categories")
+	webhookAddCmd.Flags().Float64Var(&webhookMinScore, "min-score", 0, "Only items rated at least this (0 to disable)")
+	webhookAddCmd.Flags().Float64Var(&webhookMaxScore, "max-score", 0, "Only items rated at most this (0 to disable)")
+	webhookAddCmd.Flags().StringVar(&webhookDescription, "description", "", "Note shown in the list")
+	webhookAddCmd.Flags().StringVar(&webhookSecret, "secret", "", "Signing secret (default random)")
+
+	webhookDeliveriesCmd.Flags().StringVar(&webhookDeliveriesWebhook, "webhook", "", "Only deliveries of this webhook id")
+	webhookDeliveriesCmd.Flags().BoolVar(&webhookDeliveriesFailed, "failed", false, "Only deliveries that ran out of retries")
+	webhookDeliveriesCmd.Flags().IntVar(&webhookDeliveriesLimit, "limit", 50, "Maximum rows, newest first (0 for all)")
+	webhookDeliveriesCmd.Flags().BoolVar(&webhookDeliveriesJSON, "json", false, "Output as JSON")
+
+	webhookCmd.AddCommand(webhookAddCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	webhookCmd.AddCommand(webhookRemoveCmd)
+	webhookCmd.AddCommand(webhookEnableCmd)
+	webhookCmd.AddCommand(webhookDisableCmd)
+	webhookCmd.AddCommand(webhookDeliveriesCmd)
+	webhookCmd.AddCommand(webhookRedeliverCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage outbound webhooks",
+	Long: `Webhooks POST signed JSON to external URLs on item.analyzed, feed.failed
+and trend.burst events. They are delivered by the worker in webhooks mode.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if rootCmd.PersistentPreRun != nil {
+			rootCmd.PersistentPreRun(cmd, args)
+		}
+		var err error
+		webhookRepo, err = database.NewRepository(cmd.Context(), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Add a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		addWebhook(args[0])
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List webhooks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listWebhooks()
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a webhook, its deliveries stop",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		removeWebhook(args[0])
+	},
+}
+
+var webhookEnableCmd = &cobra.Command{
+	Use:   "enable <id>",
+	Short: "Enable a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setWebhookEnabled(args[0], true)
+	},
+}
+
+var webhookDisableCmd = &cobra.Command{
+	Use:   "disable <id>",
+	Short: "Disable a webhook, events are not queued while disabled",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setWebhookEnabled(args[0], false)
+	},
+}
+
+var webhookDeliveriesCmd = &cobra.Command{
+	Use:   "deliveries",
+	Short: "Show the delivery log",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listWebhookDeliveries(webhookDeliveriesWebhook, webhookDeliveriesFailed, webhookDeliveriesLimit, webhookDeliveriesJSON)
+	},
+}
+
+var webhookRedeliverCmd = &cobra.Command{
+	Use:   "redeliver <delivery-id>",
+	Short: "Queue a delivery again with fresh retries",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		redeliverWebhook(args[0])
+	},
+}
+
+func addWebhook(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fmt.Fprintf(os.Stderr, "Invalid webhook URL: %s\n", rawURL)
+		os.Exit(1)
+	}
+	for _, event := range webhookEvents {
+		if !slices.Contains(database.WebhookEvents, event) {
+			fmt.Fprintf(os.Stderr, "Invalid --events: %s (allowed: %s)\n", event, strings.Join(database.WebhookEvents, ","))
+			os.Exit(1)
+		}
+	}
+	if webhookMinScore < 0 || webhookMinScore > 1 || webhookMaxScore < 0 || webhookMaxScore > 1 {
+		fmt.Fprintf(os.Stderr, "Scores must be between 0 and 1\n")
+		os.Exit(1)
+	}
+
+	secret := webhookSecret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate secret: %v\n", err)
+			os.Exit(1)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &database.Webhook{
+		URL:         u.String(),
+		Secret:      secret,
+		Description: webhookDescription,
+		Enabled:     true,
+		Events:      database.StringArray(webhookEvents),
+		Domains:     database.StringArray(webhookDomains),
+		Categories:  database.StringArray(webhookCategories),
+		MinScore:    webhookMinScore,
+		MaxScore:    webhookMaxScore,
+	}
+	if err := webhookRepo.CreateWebhook(webhook); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to add webhook: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Added webhook for url=%s with id=%s\n", webhook.URL, webhook.ID)
+	fmt.Printf("Secret (shown only once): %s\n", secret)
+}
+
+func listWebhooks() {
+	webhooks, err := webhookRepo.ListWebhooks()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list webhooks: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ID\tURL\tEnabled\tEvents\tDomains\tCategories\tScore\tDescription"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, webhook := range webhooks {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n",
+			webhook.ID,
+			webhook.URL,
+			webhook.Enabled,
+			joinOrAll(webhook.Events),
+			joinOrAll(webhook.Domains),
+			joinOrAll(webhook.Categories),
+			scoreRange(webhook.MinScore, webhook.MaxScore),
+			webhook.Description,
+		); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func removeWebhook(input string) {
+	id := parseWebhookID(input)
+	found, err := webhookRepo.DeleteWebhook(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove webhook: %v\n", err)
+		os.Exit(1)
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Webhook not found: %s\n", id)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed webhook with id=%s\n", id)
+}
+
+func setWebhookEnabled(input string, enabled bool) {
+	id := parseWebhookID(input)
+	found, err := webhookRepo.SetWebhookEnabled(id, enabled)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update webhook: %v\n", err)
+		os.Exit(1)
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Webhook not found: %s\n", id)
+		os.Exit(1)
+	}
+	if enabled {
+		fmt.Printf("Enabled webhook with id=%s\n", id)
+	} else {
+		fmt.Printf("Disabled webhook with id=%s\n", id)
+	}
+}
+
+func listWebhookDeliveries(webhookID string, failedOnly bool, limit int, asJSON bool) {
+	filter := database.WebhookDeliveryFilter{FailedOnly: failedOnly, Limit: limit}
+	if webhookID != "" {
+		id := parseWebhookID(webhookID)
+		filter.WebhookID = &id
+	}
+
+	deliveries, err := webhookRepo.ListWebhookDeliveries(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list deliveries: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(deliveries); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ID\tCreated\tEvent\tURL\tState\tAttempts\tStatus\tError"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+		os.Exit(1)
+	}
+	for _, delivery := range deliveries {
+		status := ""
+		if delivery.LastStatus != 0 {
+			status = fmt.Sprint(delivery.LastStatus)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			delivery.ID,
+			delivery.CreatedAt.Format(time.RFC3339),
+			delivery.Event,
+			delivery.Webhook.URL,
+			deliveryState(delivery),
+			delivery.Attempts,
+			status,
+			optionalString(delivery.LastError),
+		); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to stdout: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush to stdout: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func redeliverWebhook(input string) {
+	id, err := uuid.Parse(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid delivery id: %s\n", input)
+		os.Exit(1)
+	}
+	found, err := webhookRepo.RedeliverWebhookDelivery(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to redeliver: %v\n", err)
+		os.Exit(1)
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Delivery not found: %s\n", id)
+		os.Exit(1)
+	}
+	fmt.Printf("Queued delivery with id=%s\n", id)
+}
+
+func parseWebhookID(input string) uuid.UUID {
+	id, err := uuid.Parse(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid webhook id: %s\n", input)
+		os.Exit(1)
+	}
+	return id
+}
+
+func deliveryState(delivery database.WebhookDelivery) string {
+	switch {
+	case delivery.DeliveredAt != nil:
+		return "delivered"
+	case delivery.FailedAt != nil:
+		return "failed"
+	case delivery.Attempts > 0:
+		return "retrying"
+	default:
+		return "pending"
+	}
+}
+
+func joinOrAll(values []string) string {
+	if len(values) == 0 {
+		return "*"
+	}
+	return strings.Join(values, ",")
+}
+
+func scoreRange(minScore, maxScore float64) string {
+	if minScore == 0 && maxScore == 0 {
+		return "*"
+	}
+	upper := "1"
+	if maxScore > 0 {
+		upper = fmt.Sprintf("%.2f", maxScore)
+	}
+	return fmt.Sprintf("%.2f-%s", minScore, upper)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookCommands(t *testing.T) {
+	mock := &mockWebhookRepo{}
+	webhookRepo = mock
+
+	webhookEvents = []string{database.WebhookEventItemAnalyzed}
+	webhookDomains = []string{"example.com"}
+	webhookMaxScore = 0.3
+	defer func() {
+		webhookEvents, webhookDomains, webhookMaxScore = nil, nil, 0
+	}()
+
+	out := captureOutput(func() {
+		addWebhook("https://hooks.example.net/deframer")
+	})
+	if !assert.Len(t, mock.webhooks, 1) {
+		return
+	}
+	webhook := mock.webhooks[0]
+	assert.Len(t, webhook.Secret, 64)
+	assert.Equal(t, database.StringArray{"item.analyzed"}, webhook.Events)
+	assert.Contains(t, out, "Secret (shown only once): "+webhook.Secret)
+
+	out = captureOutput(func() {
+		listWebhooks()
+	})
+	assert.Regexp(t, `https://hooks.example.net/deframer\s+true\s+item.analyzed\s+example.com\s+\*\s+0.00-0.30`, out)
+	assert.NotContains(t, out, webhook.Secret)
+
+	captureOutput(func() {
+		setWebhookEnabled(webhook.ID.String(), false)
+	})
+	assert.False(t, mock.webhooks[0].Enabled)
+
+	failedAt := time.Now()
+	lastError := "unexpected status 500 Internal Server Error"
+	delivery := database.WebhookDelivery{
+		ID:         uuid.New(),
+		Webhook:    webhook,
+		Event:      database.WebhookEventItemAnalyzed,
+		Attempts:   8,
+		FailedAt:   &failedAt,
+		LastStatus: 500,
+		LastError:  &lastError,
+	}
+	mock.deliveries = []database.WebhookDelivery{delivery}
+
+	out = captureOutput(func() {
+		listWebhookDeliveries(webhook.ID.String(), true, 10, false)
+	})
+	if assert.NotNil(t, mock.lastDeliveries.WebhookID) {
+		assert.Equal(t, webhook.ID, *mock.lastDeliveries.WebhookID)
+	}
+	assert.True(t, mock.lastDeliveries.FailedOnly)
+	assert.Regexp(t, `item.analyzed\s+https://hooks.example.net/deframer\s+failed\s+8\s+500\s+unexpected status 500`, out)
+
+	captureOutput(func() {
+		redeliverWebhook(delivery.ID.String())
+	})
+	assert.Equal(t, []uuid.UUID{delivery.ID}, mock.redelivered)
+
+	captureOutput(func() {
+		removeWebhook(webhook.ID.String())
+	})
+	assert.Empty(t, mock.webhooks)
+}
+
+// mockWebhookRepo keeps the webhooks and deliveries the webhook commands manage.
+type mockWebhookRepo struct {
+	database.WebhookRepository
+	webhooks       []database.Webhook
+	deliveries     []database.WebhookDelivery
+	lastDeliveries database.WebhookDeliveryFilter
+	redelivered    []uuid.UUID
+}
+
+func (m *mockWebhookRepo) CreateWebhook(webhook *database.Webhook) error {
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.New()
+	}
+	m.webhooks = append(m.webhooks, *webhook)
+	return nil
+}
+
+func (m *mockWebhookRepo) ListWebhooks() ([]database.Webhook, error) {
+	return m.webhooks, nil
+}
+
+func (m *mockWebhookRepo) SetWebhookEnabled(id uuid.UUID, enabled bool) (bool, error) {
+	for i := range m.webhooks {
+		if m.webhooks[i].ID == id {
+			m.webhooks[i].Enabled = enabled
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockWebhookRepo) DeleteWebhook(id uuid.UUID) (bool, error) {
+	for i := range m.webhooks {
+		if m.webhooks[i].ID == id {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockWebhookRepo) ListWebhookDeliveries(filter database.WebhookDeliveryFilter) ([]database.WebhookDelivery, error) {
+	m.lastDeliveries = filter
+	return m.deliveries, nil
+}
+
+func (m *mockWebhookRepo) RedeliverWebhookDelivery(id uuid.UUID) (bool, error) {
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id {
+			m.redelivered = append(m.redelivered, id)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -5,24 +5,36 @@ import (
 
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/metrics"
+	"github.com/deframer/news-deframer/pkg/tracing"
 	"github.com/joho/godotenv"
 	"goa.design/clue/log"
 )
 
 // bootstrap our own services
-func bootstrap(ctx context.Context, httpPortF *string, outDbgF *bool) (outHttpPortF *string) {
+func bootstrap(ctx context.Context, httpPortF *string, outDbgF *bool) (outHttpPortF *string, cfg *config.Config, shutdownTracing func(context.Context) error) {
 	outHttpPortF = httpPortF
 
 	_ = godotenv.Load() // load .env file - if exist
-	cfg, err := config.Load()
+	var err error
+	cfg, err = config.Load()
 
 	if err != nil {
 		log.Fatalf(ctx, err, "can't initialize config")
 	}
 	cfg.ApplicationName = cfg.ApplicationName + " (Browser Extension Service)"
+	shutdownTracing, err = tracing.Setup(ctx, cfg, "news-deframer-service")
+	if err != nil {
+		log.Fatalf(ctx, err, "can't initialize tracing")
+	}
 	if err := database.Connect(ctx, cfg); err != nil {
 		log.Fatalf(ctx, err, "can't connect to database")
 	}
+	if db, err := database.SQLDB(); err != nil {
+		log.Errorf(ctx, err, "can't register database metrics")
+	} else if err := metrics.RegisterDBStats(db); err != nil {
+		log.Errorf(ctx, err, "can't register database metrics")
+	}
 
 	log.Printf(ctx, "starting: %v", cfg.ApplicationName)
 
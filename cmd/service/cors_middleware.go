@@ -24,6 +24,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS,HEAD")
 		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		// paged listings return the cursor of the next page in a header
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, ETag")
 		w.Header().Set("Vary", "Origin")
 
 		if r.Method == http.MethodOptions {
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/deframer/news-deframer/pkg/config"
+)
+
+// etagMiddleware makes the read endpoints cacheable: successful GET responses
+// get an ETag computed from the body and a Cache-Control max-age of
+// config.ETagTTL; a matching If-None-Match is answered with 304.
+func etagMiddleware(next http.Handler) http.Handler {
+	cacheControl := fmt.Sprintf("max-age=%d", int(config.ETagTTL.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isCacheableRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := newResponseRecorder(w)
+		next.ServeHTTP(rw, r)
+
+		for key, values := range rw.headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		if rw.statusCode != http.StatusOK {
+			w.WriteHeader(rw.statusCode)
+			_, _ = w.Write(rw.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(rw.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		// shared caches must not hand authenticated responses to other clients
+		if r.Header.Get("Authorization") != "" {
+			w.Header().Set("Cache-Control", "private, "+cacheControl)
+		} else {
+			w.Header().Set("Cache-Control", "public, "+cacheControl)
+		}
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(rw.statusCode)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(rw.body.Bytes())
+	})
+}
+
+// isCacheableRequest selects the reads of the web and mobile API; the event
+// stream never ends and cannot be buffered.
+func isCacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/mobile/api/") {
+		return false
+	}
+	return !isEventStreamRequest(r) && !strings.HasSuffix(r.URL.Path, "/stream")
+}
+
+// etagMatches implements the weak comparison of If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
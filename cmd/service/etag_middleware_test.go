@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	calls := 0
+	handler := etagMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("root") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`"not found"`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Next-Cursor", "abc")
+		_, _ = w.Write([]byte(`[{"hash":"1"}]`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/site?root=example.com", nil))
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, "public, max-age=150", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "abc", rec.Header().Get("X-Next-Cursor"))
+	assert.Equal(t, `[{"hash":"1"}]`, rec.Body.String())
+
+	// same content, same tag; a match is answered without the body
+	req := httptest.NewRequest(http.MethodGet, "/mobile/api/site?root=example.com", nil)
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, "private, max-age=150", rec.Header().Get("Cache-Control"))
+	assert.Empty(t, rec.Body.String())
+
+	// errors are passed through without caching headers
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/site?root=missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, `"not found"`, rec.Body.String())
+
+	// writes and the event stream are not buffered
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/feedback", nil),
+		httptest.NewRequest(http.MethodGet, "/api/stream", nil),
+	} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Empty(t, rec.Header().Get("ETag"), req.URL.Path)
+	}
+	assert.Equal(t, 5, calls)
+}
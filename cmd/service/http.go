@@ -16,9 +16,12 @@ import (
 	mobile "github.com/deframer/news-deframer/gen/mobile"
 	openapi "github.com/deframer/news-deframer/gen/openapi"
 	web "github.com/deframer/news-deframer/gen/web"
+	"github.com/deframer/news-deframer/pkg/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"goa.design/clue/debug"
 	"goa.design/clue/log"
 	goahttp "goa.design/goa/v3/http"
+	"goa.design/goa/v3/http/middleware"
 )
 
 // handleHTTPServer starts configures and starts a HTTP server on the given
@@ -70,19 +73,28 @@ func handleHTTPServer(ctx context.Context, u *url.URL, infraEndpoints *infra.End
 	openapisvr.Mount(mux, openapiServer)
 	mobilesvr.Mount(mux, mobileServer)
 	websvr.Mount(mux, webServer)
+	mux.Handle(http.MethodGet, "/metrics", metrics.Handler().ServeHTTP)
 
 	var handler http.Handler = mux
+	// the client address for the feedback rate limit, see TRUSTED_PROXIES
+	handler = middleware.PopulateRequestContext()(handler)
 	if dbg {
 		// Log query and response bodies if debug logs are enabled.
 		handler = debug.HTTP()(handler)
 	}
 	// skip pings
-	var noLogRegexp = regexp.MustCompile(`^/(healthz|livez|metrics|ping)$`)
+	var noLogRegexp = regexp.MustCompile(`^/(healthz|livez|readyz|metrics|ping)$`)
 	handler = log.HTTP(ctx, log.WithPathFilter(noLogRegexp))(handler)
+	// ETag, Cache-Control and 304 for the read endpoints
+	handler = etagMiddleware(handler)
 	// Allow browser and mobile clients to call the API across origins.
 	handler = corsMiddleware(handler)
 	// 404 redirects
 	handler = redirect404Middleware(handler)
+	// a span for every request, named after the Goa method by tracing.Endpoint
+	handler = otelhttp.NewHandler(handler, "http.request", otelhttp.WithFilter(func(r *http.Request) bool {
+		return !noLogRegexp.MatchString(r.URL.Path)
+	}))
 	// handler = log.HTTP(ctx)(handler)
 	// Start HTTP server using default configuration, change the code to
 	// configure the server as required by your service.
@@ -99,6 +111,7 @@ func handleHTTPServer(ctx context.Context, u *url.URL, infraEndpoints *infra.End
 	for _, m := range webServer.Mounts {
 		log.Printf(ctx, "HTTP %q mounted on %s %s", m.Method, m.Verb, m.Pattern)
 	}
+	log.Printf(ctx, "HTTP %q mounted on %s %s", "Metrics", http.MethodGet, "/metrics")
 
 	(*wg).Add(1)
 	go func() {
@@ -15,7 +15,10 @@ import (
 	mobile "github.com/deframer/news-deframer/gen/mobile"
 	openapi "github.com/deframer/news-deframer/gen/openapi"
 	web "github.com/deframer/news-deframer/gen/web"
+	"github.com/deframer/news-deframer/pkg/facade"
+	"github.com/deframer/news-deframer/pkg/metrics"
 	service "github.com/deframer/news-deframer/pkg/service"
+	"github.com/deframer/news-deframer/pkg/tracing"
 	"goa.design/clue/debug"
 	"goa.design/clue/log"
 )
@@ -38,13 +41,16 @@ func main() {
 		format = log.FormatTerminal
 	}
 	ctx := log.Context(context.Background(), log.WithFormat(format))
-	httpPortF = bootstrap(ctx, httpPortF, dbgF)
+	httpPortF, cfg, shutdownTracing := bootstrap(ctx, httpPortF, dbgF)
 	if *dbgF {
 		ctx = log.Context(ctx, log.WithDebug())
 		log.Debugf(ctx, "debug logs enabled")
 	}
 	log.Print(ctx, log.KV{K: "http-port", V: *httpPortF})
 
+	// One LISTEN connection for the live streams of both APIs.
+	events := facade.NewNotifier(ctx, cfg)
+
 	// Initialize the services.
 	var (
 		infraSvc   infra.Service
@@ -53,10 +59,10 @@ func main() {
 		webSvc     web.Service
 	)
 	{
-		infraSvc = service.NewInfra()
+		infraSvc = service.NewInfra(ctx)
 		openapiSvc = service.NewOpenapi()
-		mobileSvc = service.NewMobile(ctx)
-		webSvc = service.NewWeb(ctx)
+		mobileSvc = service.NewMobile(ctx, events)
+		webSvc = service.NewWeb(ctx, events)
 	}
 
 	// Wrap the services in endpoints that can be invoked from other services
@@ -71,15 +77,21 @@ func main() {
 		infraEndpoints = infra.NewEndpoints(infraSvc)
 		infraEndpoints.Use(debug.LogPayloads())
 		infraEndpoints.Use(log.Endpoint)
+		infraEndpoints.Use(tracing.Endpoint)
 		openapiEndpoints = openapi.NewEndpoints(openapiSvc)
 		openapiEndpoints.Use(debug.LogPayloads())
 		openapiEndpoints.Use(log.Endpoint)
+		openapiEndpoints.Use(tracing.Endpoint)
 		mobileEndpoints = mobile.NewEndpoints(mobileSvc)
 		mobileEndpoints.Use(debug.LogPayloads())
 		mobileEndpoints.Use(log.Endpoint)
+		mobileEndpoints.Use(tracing.Endpoint)
+		mobileEndpoints.Use(metrics.Endpoint)
 		webEndpoints = web.NewEndpoints(webSvc)
 		webEndpoints.Use(debug.LogPayloads())
 		webEndpoints.Use(log.Endpoint)
+		webEndpoints.Use(tracing.Endpoint)
+		webEndpoints.Use(metrics.Endpoint)
 	}
 
 	// Create channel used by both the signal handler and server goroutines
@@ -135,5 +147,8 @@ func main() {
 	cancel()
 
 	wg.Wait()
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Errorf(ctx, err, "failed to flush traces")
+	}
 	log.Printf(ctx, "exited")
 }
@@ -40,6 +40,11 @@ func isJSONRequest(r *http.Request) bool {
 	return strings.Contains(accept, "application/json") || strings.Contains(accept, "application/*+json")
 }
 
+// isEventStreamRequest reports an SSE client; its response must not be buffered.
+func isEventStreamRequest(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "text/event-stream")
+}
+
 func redirect404Middleware(next http.Handler) http.Handler {
 	cfg, err := config.Load()
 	if err != nil || strings.TrimSpace(cfg.RedirectWebRequest404URL) == "" {
@@ -47,7 +52,7 @@ func redirect404Middleware(next http.Handler) http.Handler {
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if isJSONRequest(r) {
+		if isJSONRequest(r) || isEventStreamRequest(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
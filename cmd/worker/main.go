@@ -12,12 +12,16 @@ import (
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
 	applog "github.com/deframer/news-deframer/pkg/logger"
+	"github.com/deframer/news-deframer/pkg/metrics"
 	"github.com/deframer/news-deframer/pkg/syncer"
+	"github.com/deframer/news-deframer/pkg/think"
+	"github.com/deframer/news-deframer/pkg/tracing"
+	"github.com/deframer/news-deframer/pkg/webhook"
 	"goa.design/clue/log"
 )
 
 func main() {
-	mode := flag.String("mode", string(syncer.ModeIngester), "Run mode: ingester, thinker, thinker-fixer, or thinker-update-llm-model")
+	mode := flag.String("mode", string(syncer.ModeIngester), "Run mode: ingester, thinker, thinker-fixer, thinker-update-llm-model, thinker-update-prompt, or webhooks")
 	flag.Usage = func() {
 		// #nosec G705: usage string is escaped before printing
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", html.EscapeString(os.Args[0]))
@@ -27,9 +31,9 @@ func main() {
 
 	selectedMode := syncer.Mode(*mode)
 	switch selectedMode {
-	case syncer.ModeIngester, syncer.ModeThinker, syncer.ModeThinkerFixer, syncer.ModeThinkerUpdateLLMModel:
+	case syncer.ModeIngester, syncer.ModeThinker, syncer.ModeThinkerFixer, syncer.ModeThinkerUpdateLLMModel, syncer.ModeThinkerUpdatePrompt, syncer.ModeWebhooks:
 	default:
-		fmt.Fprintf(os.Stderr, "Invalid mode: %s (expected %s, %s, %s, or %s)\n", *mode, syncer.ModeIngester, syncer.ModeThinker, syncer.ModeThinkerFixer, syncer.ModeThinkerUpdateLLMModel)
+		fmt.Fprintf(os.Stderr, "Invalid mode: %s (expected %s, %s, %s, %s, %s, or %s)\n", *mode, syncer.ModeIngester, syncer.ModeThinker, syncer.ModeThinkerFixer, syncer.ModeThinkerUpdateLLMModel, syncer.ModeThinkerUpdatePrompt, syncer.ModeWebhooks)
 		os.Exit(2)
 	}
 
@@ -45,6 +49,17 @@ func main() {
 	hostname, _ := os.Hostname()
 	log.Print(logCtx, log.KV{K: "component", V: "worker"}, log.KV{K: "hostname", V: hostname})
 
+	shutdownTracing, err := tracing.Setup(logCtx, cfg, "news-deframer-"+string(selectedMode))
+	if err != nil {
+		log.Fatalf(logCtx, err, "Failed to initialize tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Errorf(logCtx, err, "Failed to flush traces")
+		}
+	}()
+
 	repo, err := database.NewRepository(logCtx, cfg)
 	if err != nil {
 		log.Fatalf(logCtx, err, "Failed to connect to database")
@@ -60,7 +75,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	// start syncer poll
-	s.Poll(selectedMode)
+	if cfg.MetricsAddr != "" {
+		if err := metrics.RegisterQueues(ctx, func() (*database.ThinkerQueueDepth, error) {
+			return syncer.ThinkerQueueDepth(cfg, repo)
+		}, repo.CountLockWaits); err != nil {
+			log.Errorf(logCtx, err, "Failed to register queue metrics")
+		}
+		if db, err := database.SQLDB(); err != nil {
+			log.Errorf(logCtx, err, "Failed to register database metrics")
+		} else if err := metrics.RegisterDBStats(db); err != nil {
+			log.Errorf(logCtx, err, "Failed to register database metrics")
+		}
+		go func() {
+			log.Printf(logCtx, "Metrics listening on %s", cfg.MetricsAddr)
+			if err := metrics.Serve(ctx, cfg.MetricsAddr); err != nil {
+				log.Errorf(logCtx, err, "Metrics listener failed")
+			}
+		}()
+	}
+
+	// changes in PROMPT_DIR are picked up by polling, SIGHUP reloads it at once
+	if cfg.PromptDir != "" {
+		go think.WatchPrompts(ctx)
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			if err := think.ReloadPrompts(); err != nil {
+				log.Errorf(logCtx, err, "Failed to reload prompts, keeping previous prompts")
+				continue
+			}
+			log.Print(logCtx, log.KV{K: "message", V: "Reloaded prompts"})
+		}
+	}()
+
+	// start syncer poll, webhooks are delivered by their own dispatcher
+	if selectedMode == syncer.ModeWebhooks {
+		webhook.New(ctx, repo).Poll()
+	} else {
+		s.Poll(selectedMode)
+	}
 	log.Print(logCtx, log.KV{K: "message", V: "Shutting down..."})
 }
@@ -14,6 +14,10 @@ var localizedCategories = map[string][]string{
 	"es": {"política", "mundo", "negocios", "deporte", "cultura", "tecnología", "salud", "finanzas", "ciencia", "medio ambiente", "viajes", "estilo de vida", "videojuegos", "historia", "opinión", "otro"},
 	"fr": {"politique", "monde", "affaires", "sport", "culture", "technologie", "santé", "finance", "science", "environnement", "voyages", "art de vivre", "jeux vidéo", "histoire", "opinion", "autre"},
 	"nl": {"politiek", "wereld", "bedrijfsleven", "sport", "cultuur", "technologie", "gezondheid", "financiën", "wetenschap", "milieu", "reizen", "levensstijl", "videogames", "geschiedenis", "opinie", "overig"},
+	"it": {"politica", "mondo", "economia", "sport", "cultura", "tecnologia", "salute", "finanza", "scienza", "ambiente", "viaggi", "stile di vita", "videogiochi", "storia", "opinione", "altro"},
+	"pt": {"política", "mundo", "negócios", "desporto", "cultura", "tecnologia", "saúde", "finanças", "ciência", "ambiente", "viagens", "estilo de vida", "videojogos", "história", "opinião", "outro"},
+	"pl": {"polityka", "świat", "biznes", "sport", "kultura", "technologia", "zdrowie", "finanse", "nauka", "środowisko", "podróże", "styl życia", "gry", "historia", "opinie", "inne"},
+	"sv": {"politik", "världen", "näringsliv", "sport", "kultur", "teknik", "hälsa", "ekonomi", "vetenskap", "miljö", "resor", "livsstil", "spel", "historia", "åsikt", "övrigt"},
 }
 
 var canonicalCategories = localizedCategories["en"]
@@ -71,6 +75,16 @@ func FirstLocalizedCategory(language string) (string, error) {
 	return allowed[0], nil
 }
 
+// Languages returns the sorted language codes that have localized categories.
+func Languages() []string {
+	languages := make([]string, 0, len(localizedCategories))
+	for language := range localizedCategories {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
 func LocalizedCategoriesFor(language string) ([]string, error) {
 	allowed, ok := localizedCategories[language]
 	if !ok || len(allowed) == 0 {
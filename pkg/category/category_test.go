@@ -21,3 +21,13 @@ func TestGetUnknowCategory(t *testing.T) {
 		t.Fatalf("expected other, got %q", got)
 	}
 }
+
+func TestLanguages(t *testing.T) {
+	languages := Languages()
+	if len(languages) != len(localizedCategories) {
+		t.Fatalf("expected %d languages, got %d", len(localizedCategories), len(languages))
+	}
+	if languages[0] != "da" {
+		t.Fatalf("expected sorted languages, got %v", languages)
+	}
+}
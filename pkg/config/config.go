@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +16,8 @@ import (
 
 type LLMType int
 
+type EnsembleAggregation int
+
 const (
 	// PollingInterval defines how often a single feed is re-synced.
 	// If a feed was synced at T, it will be eligible again at T + PollingInterval.
@@ -52,6 +57,146 @@ func (t *LLMType) UnmarshalText(text []byte) error {
 	return nil
 }
 
+const (
+	Median EnsembleAggregation = iota
+	Mean
+)
+
+func (a *EnsembleAggregation) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "median":
+		*a = Median
+	case "mean":
+		*a = Mean
+	default:
+		return fmt.Errorf("unknown ensemble aggregation: %s", string(text))
+	}
+	return nil
+}
+
+func (a EnsembleAggregation) String() string {
+	if a == Mean {
+		return "mean"
+	}
+	return "median"
+}
+
+// LLMPrice is the price in USD per one million tokens.
+type LLMPrice struct {
+	Input  float64
+	Output float64
+}
+
+// LLMPrices maps a model name to its price. The text form is
+// "model=input/output,..." e.g. "gpt-4o-mini=0.15/0.60".
+type LLMPrices map[string]LLMPrice
+
+func (p *LLMPrices) UnmarshalText(text []byte) error {
+	prices := LLMPrices{}
+	for _, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, price, ok := strings.Cut(entry, "=")
+		input, output, ok2 := strings.Cut(price, "/")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return fmt.Errorf("invalid LLM price %q (expected model=input/output)", entry)
+		}
+		in, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil {
+			return fmt.Errorf("invalid LLM input price %q: %w", entry, err)
+		}
+		out, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil {
+			return fmt.Errorf("invalid LLM output price %q: %w", entry, err)
+		}
+		prices[strings.TrimSpace(model)] = LLMPrice{Input: in, Output: out}
+	}
+	*p = prices
+	return nil
+}
+
+// Cost returns the USD cost of a call, or 0 for models without a price.
+func (p LLMPrices) Cost(model string, promptTokens, completionTokens int64) float64 {
+	price, ok := p[model]
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000
+}
+
+// TrustedProxies are the reverse proxies whose X-Forwarded-For and X-Real-IP
+// headers are believed. The text form is a comma separated list of addresses
+// and CIDRs, e.g. "10.0.0.0/8,192.168.1.10".
+type TrustedProxies []netip.Prefix
+
+func (p *TrustedProxies) UnmarshalText(text []byte) error {
+	proxies := TrustedProxies{}
+	for _, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, err2 := netip.ParseAddr(entry)
+			if err2 != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	*p = proxies
+	return nil
+}
+
+// Contains reports whether addr belongs to a trusted proxy.
+func (p TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the address of the client behind remoteAddr (host:port
+// or host). The forwarding headers are only read when remoteAddr is a trusted
+// proxy; X-Forwarded-For is walked from the right and the first address that
+// is not a trusted proxy wins, X-Real-IP is used without it.
+func (p TrustedProxies) ClientAddr(remoteAddr, forwardedFor, realIP string) string {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !p.Contains(addr) {
+		return host
+	}
+
+	if strings.TrimSpace(forwardedFor) == "" {
+		if client, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+			return client.Unmap().String()
+		}
+		return host
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !p.Contains(addr) {
+			return addr.String()
+		}
+	}
+	return addr.String()
+}
+
 type Config struct {
 	ApplicationName string `env:"APPLICATION_NAME" envDefault:"News Deframer"`
 
@@ -61,16 +206,62 @@ type Config struct {
 	// Gorm DNS
 	DSN string `env:"DSN" envDefault:"host=postgres user=deframer password=deframer dbname=deframer port=5432 sslmode=disable"`
 
-	BasicAuthUser            string `env:"BASIC_AUTH_USER" envDefault:""`
-	BasicAuthPassword        string `env:"BASIC_AUTH_PASSWORD" envDefault:""`
+	BasicAuthUser     string `env:"BASIC_AUTH_USER" envDefault:""`
+	BasicAuthPassword string `env:"BASIC_AUTH_PASSWORD" envDefault:""`
+	// ReviewerUser and ReviewerPassword guard the review endpoints; they are disabled while unset.
+	ReviewerUser     string `env:"REVIEWER_USER" envDefault:""`
+	ReviewerPassword string `env:"REVIEWER_PASSWORD" envDefault:""`
+	// FeedbackRateLimit is the feedback a client may submit per hour and
+	// FeedbackSourceRateLimit the feedback per API key or remote address and
+	// hour, which made-up client ids cannot dodge; 0 removes a limit.
+	FeedbackRateLimit        int    `env:"FEEDBACK_RATE_LIMIT" envDefault:"30"`
+	FeedbackSourceRateLimit  int    `env:"FEEDBACK_SOURCE_RATE_LIMIT" envDefault:"300"`
 	CORSAllowedOrigins       string `env:"CORS_ALLOWED_ORIGINS"`
 	RedirectWebRequest404URL string `env:"REDIRECT_WEBREQUEST_404_URL" envDefault:""`
 
+	// TrustedProxies may name the client address with X-Forwarded-For or
+	// X-Real-IP; other requests are told apart by their remote address.
+	TrustedProxies TrustedProxies `env:"TRUSTED_PROXIES"`
+
 	LLM_Type    LLMType `env:"LLM_TYPE" envDefault:"dummy"`
 	LLM_Model   string  `env:"LLM_MODEL" required:"true"`
 	LLM_APIKey  string  `env:"LLM_API_KEY" envDefault:""`
 	LLM_BaseURL string  `env:"LLM_BASE_URL" envDefault:""`
 
+	// Optional ensemble: every model is queried with the same provider settings
+	// and the scores are aggregated. LLM_Model is ignored when this is set.
+	LLM_EnsembleModels      []string            `env:"LLM_ENSEMBLE_MODELS" envSeparator:","`
+	LLM_EnsembleAggregation EnsembleAggregation `env:"LLM_ENSEMBLE_AGGREGATION" envDefault:"median"`
+
+	// LLM_RepairFollowUp sends one "fix your JSON" message when an answer can
+	// neither be decoded nor repaired locally. The second call is billed too.
+	LLM_RepairFollowUp bool `env:"LLM_REPAIR_FOLLOWUP" envDefault:"false"`
+
+	// LLM_Prices is used to compute the cost of every recorded LLM call.
+	LLM_Prices LLMPrices `env:"LLM_PRICES"`
+	// LLM_MonthlyBudget pauses the thinker workers once the recorded cost of the
+	// current calendar month (UTC) reaches it. 0 disables the cap.
+	LLM_MonthlyBudget float64 `env:"LLM_MONTHLY_BUDGET" envDefault:"0"`
+
+	// ThinkCache answers identical requests (same prompt version, model, language and text) from the database.
+	ThinkCache bool `env:"THINK_CACHE" envDefault:"true"`
+
+	// PromptDir overlays the embedded prompts with <name>-prompt-<language>.md files; changes are picked up at runtime.
+	PromptDir string `env:"PROMPT_DIR" envDefault:""`
+
+	// LanguageDetection detects the language of every new item from its title and description.
+	// Items fall back to the feed language below LanguageMinConfidence.
+	LanguageDetection     bool    `env:"LANGUAGE_DETECTION" envDefault:"true"`
+	LanguageMinConfidence float64 `env:"LANGUAGE_MIN_CONFIDENCE" envDefault:"0.95"`
+
+	// MetricsAddr is where workers serve Prometheus metrics; empty disables it.
+	// The service serves them on its own port under /metrics.
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9090"`
+
+	// TracesExporter sends OpenTelemetry spans to "otlp" (see OTEL_EXPORTER_OTLP_ENDPOINT),
+	// to "stdout" for local debugging or nowhere ("none").
+	TracesExporter string `env:"OTEL_TRACES_EXPORTER" envDefault:"none"`
+
 	DebugLog        bool `env:"DEBUG_LOG" envDefault:"false"`
 	DatabaseLogging bool `env:"DATABASE_LOGGING" envDefault:"false"`
 }
@@ -93,5 +284,22 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	models := cfg.LLM_EnsembleModels[:0]
+	for _, model := range cfg.LLM_EnsembleModels {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	cfg.LLM_EnsembleModels = models
+
 	return cfg, nil
 }
+
+// ThinkModel returns the model label stored in ThinkResult.LLMModel for the
+// configured provider, so the update-llm-model queue can detect stale results.
+func (c *Config) ThinkModel() string {
+	if len(c.LLM_EnsembleModels) == 0 {
+		return c.LLM_Model
+	}
+	return "ensemble:" + c.LLM_EnsembleAggregation.String() + ":" + strings.Join(c.LLM_EnsembleModels, "+")
+}
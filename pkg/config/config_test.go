@@ -36,4 +36,47 @@ func TestLoad(t *testing.T) {
 
 		assert.Equal(t, "9090", cfg.Port)
 	})
+
+	t.Run("LLM Settings", func(t *testing.T) {
+		t.Setenv("LLM_ENSEMBLE_MODELS", "a, b,,c")
+		t.Setenv("LLM_ENSEMBLE_AGGREGATION", "mean")
+		t.Setenv("LLM_PRICES", "gpt-4o-mini=0.15/0.60, gemini-2.5-flash=0.10/0.40")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"a", "b", "c"}, cfg.LLM_EnsembleModels)
+		assert.Equal(t, Mean, cfg.LLM_EnsembleAggregation)
+		assert.Equal(t, "ensemble:mean:a+b+c", cfg.ThinkModel())
+		assert.Equal(t, LLMPrice{Input: 0.15, Output: 0.60}, cfg.LLM_Prices["gpt-4o-mini"])
+		assert.InDelta(t, 0.75, cfg.LLM_Prices.Cost("gpt-4o-mini", 1_000_000, 1_000_000), 1e-9)
+		assert.Zero(t, cfg.LLM_Prices.Cost("unknown", 1_000_000, 1_000_000))
+	})
+
+	t.Run("Trusted Proxies", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+
+		proxies := cfg.TrustedProxies
+		assert.Len(t, proxies, 2)
+		// untrusted peers cannot pick their address
+		assert.Equal(t, "203.0.113.9", proxies.ClientAddr("203.0.113.9:4711", "198.51.100.1", "198.51.100.2"))
+		// the first hop right of the trusted proxies is the client
+		assert.Equal(t, "198.51.100.1", proxies.ClientAddr("10.1.2.3:4711", "192.0.2.66, 198.51.100.1, 10.9.9.9", ""))
+		assert.Equal(t, "198.51.100.2", proxies.ClientAddr("192.168.1.10:4711", "", "198.51.100.2"))
+		assert.Equal(t, "10.1.2.3", proxies.ClientAddr("10.1.2.3:4711", "", ""))
+
+		t.Setenv("TRUSTED_PROXIES", "proxy.local")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid LLM Prices", func(t *testing.T) {
+		t.Setenv("LLM_PRICES", "gpt-4o-mini=0.15")
+
+		_, err := Load()
+		assert.Error(t, err)
+	})
 }
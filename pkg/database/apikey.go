@@ -0,0 +1,35 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateAPIKey returns a new key with a random key id and secret. The secret
+// is only returned here, the key stores its hash.
+func GenerateAPIKey(name string, scopes []string, rateLimit int) (*APIKey, string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	plain := hex.EncodeToString(secret)
+	return &APIKey{
+		KeyID:      APIKeyPrefix + hex.EncodeToString(id),
+		SecretHash: HashAPIKeySecret(plain),
+		Name:       name,
+		Scopes:     StringArray(scopes),
+		RateLimit:  rateLimit,
+	}, plain, nil
+}
+
+// HashAPIKeySecret hashes a secret for storage and comparison. The secrets
+// are random, so a fast hash is enough.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
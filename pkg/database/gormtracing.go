@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/deframer/news-deframer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// tracingPlugin starts a span for every query run within a traced operation,
+// as a child of the span in the context of the statement (see
+// Repository.WithContext). Queries without a parent span, e.g. of the worker
+// loops, stay untraced. The SQL is recorded with placeholders, never with the
+// bound values.
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "tracing"
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	return errors.Join(
+		db.Callback().Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		db.Callback().Create().After("gorm:create").Register("tracing:after_create", p.after),
+		db.Callback().Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		db.Callback().Query().After("gorm:query").Register("tracing:after_query", p.after),
+		db.Callback().Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		db.Callback().Update().After("gorm:update").Register("tracing:after_update", p.after),
+		db.Callback().Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		db.Callback().Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		db.Callback().Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		db.Callback().Row().After("gorm:row").Register("tracing:after_row", p.after),
+		db.Callback().Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		db.Callback().Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+func (tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !trace.SpanContextFromContext(db.Statement.Context).IsValid() {
+			return
+		}
+		ctx, span := tracing.Start(db.Statement.Context, "db."+operation,
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+	}
+	span.SetAttributes(
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.RowsAffected),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestTracingPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	// DryRun builds the statements without a database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=none"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Use(tracingPlugin{}))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	repo := NewFromDB(db).WithContext(ctx)
+	u, err := url.Parse("https://example.com/feed")
+	assert.NoError(t, err)
+	_, _ = repo.FindFeedByUrlAndAvailability(u, true)
+	parent.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		query := spans[0]
+		assert.Equal(t, "db.query", query.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+		attrs := map[string]string{}
+		for _, attr := range query.Attributes() {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		assert.Equal(t, "postgresql", attrs["db.system.name"])
+		assert.Equal(t, "feeds", attrs["db.collection.name"])
+		assert.Contains(t, attrs["db.query.text"], `WHERE url = $1`)
+		assert.NotContains(t, attrs["db.query.text"], "example.com", "bound values stay out of the span")
+	}
+
+	// without a parent span no root spans are started
+	recorder.Reset()
+	_, _ = NewFromDB(db).WithContext(context.Background()).FindFeedByUrlAndAvailability(u, true)
+	_, _ = NewFromDB(db).FindFeedByUrlAndAvailability(u, true)
+	assert.Empty(t, recorder.Ended())
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/jackc/pgx/v5"
+	"goa.design/clue/log"
+)
+
+// ItemsAnalyzedChannel is the NOTIFY channel UpsertItemWithTrendInvalidation
+// signals with the event id of every new analysis.
+const ItemsAnalyzedChannel = "items_analyzed"
+
+// listenRetryDelay is the pause before a lost LISTEN connection is reopened.
+const listenRetryDelay = 5 * time.Second
+
+// ListenItemsAnalyzed wakes the returned channel whenever an analysis was
+// stored, until ctx ends. Wake-ups are coalesced, so readers must look up what
+// is new themselves. After a lost connection the channel is woken once, as
+// notifications may have been missed in between.
+func ListenItemsAnalyzed(ctx context.Context, cfg *config.Config) <-chan struct{} {
+	wake := make(chan struct{}, 1)
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		defer close(wake)
+		for {
+			if err := listen(ctx, cfg, signal); err != nil && ctx.Err() == nil {
+				log.Errorf(ctx, err, "LISTEN %s failed, retrying in %s", ItemsAnalyzedChannel, listenRetryDelay)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenRetryDelay):
+				signal()
+			}
+		}
+	}()
+	return wake
+}
+
+func listen(ctx context.Context, cfg *config.Config, signal func()) error {
+	conn, err := pgx.Connect(ctx, postgresDSNWithApplicationName(cfg.DSN, cfg.ApplicationName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+ItemsAnalyzedChannel); err != nil {
+		return err
+	}
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		signal()
+	}
+}
@@ -5,15 +5,25 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/deframer/news-deframer/pkg/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:embed sql
 var migrationFS embed.FS
 
+// SchemaVersion is recorded by every successful migration; raise it whenever
+// the models or the embedded SQL change, so readiness reports pending
+// migrations until the migrator ran.
+const SchemaVersion = 4
+
+// RequiredViews are the views the trend queries read from.
+var RequiredViews = []string{"view_trend_metrics", "view_trend_metrics_by_domain"}
+
 // Connects to the database and runs the migration
 func RunMigrations(cfg *config.Config, forced bool) error {
 	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
@@ -49,12 +59,12 @@ func Migrate(db *gorm.DB, forced bool) error {
 		return fmt.Errorf("failed to create extension pg_duckdb: %w. Are you using the pgduckdb/pgduckdb docker image or a postgres build with pg_duckdb? Check https://github.com/duckdb/pg_duckdb and https://pgxman.com/x/pg_duckdb", err)
 	}
 
-	// Drop views that reference columns we may need to alter (recreated by migrateViews below)
+	// Drop views that reference columns we may need to alter (recreated from sql/views below)
 	_ = db.Exec("DROP VIEW IF EXISTS view_trend_metrics CASCADE")
 	_ = db.Exec("DROP VIEW IF EXISTS view_trend_metrics_by_domain CASCADE")
 
 	// AutoMigrate the schema
-	if err := db.AutoMigrate(&Feed{}, &Item{}, &FeedSchedule{}, &Trend{}, &StopWords{}); err != nil {
+	if err := db.AutoMigrate(&Feed{}, &Item{}, &FeedSchedule{}, &Trend{}, &StopWords{}, &FeedLanguageStat{}, &ThinkResultRecord{}, &ItemReview{}, &ItemFeedback{}, &ThinkCache{}, &LLMUsage{}, &Webhook{}, &WebhookDelivery{}, &APIKey{}, &APIKeyUsage{}, &SchemaMigration{}); err != nil {
 		return err
 	}
 	_ = db.Exec(`DO $$ BEGIN
@@ -67,15 +77,24 @@ func Migrate(db *gorm.DB, forced bool) error {
 	END $$;`)
 
 	// Run embedded SQL files
-	if err := migrateViews(db); err != nil {
+	if err := migrateSQLDir(db, "sql/schema"); err != nil {
+		return err
+	}
+	if err := migrateSQLDir(db, "sql/views"); err != nil {
 		return err
 	}
 
+	migration := SchemaMigration{ID: 1, Version: SchemaVersion, MigratedAt: time.Now().UTC()}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&migration).Error; err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
 	return nil
 }
 
-func migrateViews(db *gorm.DB) error {
-	entries, err := migrationFS.ReadDir("sql/views")
+// migrateSQLDir executes the .sql files of an embedded directory in name order.
+func migrateSQLDir(db *gorm.DB, dir string) error {
+	entries, err := migrationFS.ReadDir(dir)
 	if err != nil {
 		return fmt.Errorf("failed to read migration directory: %w", err)
 	}
@@ -91,7 +110,7 @@ func migrateViews(db *gorm.DB) error {
 		if !strings.HasSuffix(entry.Name(), ".sql") {
 			continue
 		}
-		content, err := migrationFS.ReadFile("sql/views/" + entry.Name())
+		content, err := migrationFS.ReadFile(dir + "/" + entry.Name())
 		if err != nil {
 			return fmt.Errorf("failed to read migration file %s: %w", entry.Name(), err)
 		}
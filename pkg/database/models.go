@@ -4,8 +4,10 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	categorypkg "github.com/deframer/news-deframer/pkg/category"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
@@ -55,6 +57,16 @@ func (f *Feed) HasTag(tag string) bool {
 	return false
 }
 
+// FeedLanguageStat counts the new items of a feed per detected language, so
+// feeds whose declared language does not match their content stand out.
+type FeedLanguageStat struct {
+	FeedID    uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Feed      Feed      `gorm:"foreignKey:FeedID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Language  string    `gorm:"primaryKey;type:varchar(3)"` // ISO 639-1 code or "und" when the detection was not confident
+	Items     int64     `gorm:"not null;default:0"`
+	UpdatedAt time.Time `gorm:"not null;default:now()"`
+}
+
 type FeedError struct {
 	RootDomain   *string    `gorm:"column:root_domain" json:"root_domain,omitempty"`
 	URL          string     `gorm:"column:url" json:"url"`
@@ -102,6 +114,7 @@ type FeedSchedule struct {
 // ThinkResult we make omitempty to not serialize default e.g. 0.0 or ""
 type ThinkResult struct {
 	LLMModel                    string  `json:"llm_model,omitempty"`
+	PromptVersion               string  `json:"prompt_version,omitempty"`
 	TitleOriginal               string  `json:"title_original,omitempty"`
 	DescriptionOriginal         string  `json:"description_original,omitempty"`
 	TitleCorrected              string  `json:"title_corrected,omitempty"`
@@ -121,6 +134,26 @@ type ThinkResult struct {
 	Overall                     float64 `json:"overall,omitempty"`
 	OverallReason               string  `json:"overall_reason,omitempty"`
 	Category                    string  `json:"category,omitempty"`
+	// Evidence points at the words of the original text behind the scores.
+	Evidence []EvidenceSpan `json:"evidence,omitempty"`
+	// Disagreement and EnsembleModels are only set by ensemble scoring.
+	Disagreement   float64  `json:"disagreement,omitempty"`
+	EnsembleModels []string `json:"ensemble_models,omitempty"`
+	// Repairs lists what was fixed in the LLM answer before it passed validation.
+	Repairs []string `json:"repairs,omitempty"`
+	// Usage of the LLM calls behind this result; recorded in llm_usages, never persisted with the result.
+	Usage []LLMUsage `json:"-"`
+}
+
+// EvidenceSpan is a quoted substring of the original title or description
+// that supports the score of a dimension. Start and End are rune offsets into
+// the analyzed text, which is the original with HTML stripped.
+type EvidenceSpan struct {
+	Dimension string `json:"dimension"`
+	Field     string `json:"field"`
+	Quote     string `json:"quote"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
 }
 
 func (j ThinkResult) Value() (driver.Value, error) {
@@ -184,20 +217,22 @@ func (j *MediaContent) Scan(value interface{}) error {
 }
 
 type Item struct {
-	ID              uuid.UUID     `gorm:"primaryKey;type:uuid"`
+	ID              uuid.UUID     `gorm:"primaryKey;type:uuid;index:idx_items_feed_pub_date,priority:3,sort:desc"`
 	CreatedAt       time.Time     `gorm:"not null;default:now()"`
 	UpdatedAt       time.Time     `gorm:"not null;default:now()"`
 	Hash            string        `gorm:"type:char(64);uniqueIndex:idx_hash_feed_url;uniqueIndex:idx_hash_feed;not null"`
-	FeedID          uuid.UUID     `gorm:"type:uuid;index;uniqueIndex:idx_feed_url;uniqueIndex:idx_hash_feed_url;uniqueIndex:idx_hash_feed;not null"`
+	FeedID          uuid.UUID     `gorm:"type:uuid;index;uniqueIndex:idx_feed_url;uniqueIndex:idx_hash_feed_url;uniqueIndex:idx_hash_feed;index:idx_items_feed_pub_date,priority:1;not null"`
 	Feed            Feed          `gorm:"foreignKey:FeedID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	URL             string        `gorm:"index;uniqueIndex:idx_feed_url;uniqueIndex:idx_hash_feed_url;not null"`
 	Language        *string       `gorm:"type:char(2)"` // ISO 639-1 language code
 	Content         string        `gorm:"type:text;not null"`
-	PubDate         time.Time     `gorm:"not null;index;default:now()"`
+	PubDate         time.Time     `gorm:"not null;index;index:idx_items_feed_pub_date,priority:2,sort:desc;default:now()"`
 	MediaContent    *MediaContent `gorm:"type:jsonb"`
 	ThinkResult     *ThinkResult  `gorm:"type:jsonb"`
+	ThinkResultID   *uuid.UUID    `gorm:"type:uuid"` // current entry of the think_results history; nil marks a result that still has to be recorded
 	ThinkError      *string       `gorm:"type:text;null"`
 	ThinkErrorCount int           `gorm:"not null;default:0"`
+	ThinkErrorKind  *string       `gorm:"type:varchar(32)"` // think.ErrorKind of the last failure
 	ThinkRating     float64       `gorm:"not null;default:0.0"`
 	Categories      StringArray   `gorm:"type:text[];not null;default:'{}'"`
 	Authors         StringArray   `gorm:"type:text[];not null;default:'{}'"`
@@ -211,6 +246,232 @@ func (item *Item) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// ThinkResultRecord is one entry of the append-only analysis history of an
+// item. Items point to their current entry with ThinkResultID.
+type ThinkResultRecord struct {
+	ID            uuid.UUID    `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	CreatedAt     time.Time    `gorm:"not null;default:now();index:idx_think_results_item_created,priority:2"`
+	ItemID        uuid.UUID    `gorm:"type:uuid;not null;index:idx_think_results_item_created,priority:1"`
+	Item          Item         `gorm:"foreignKey:ItemID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	LLMModel      string       `gorm:"not null;index"`
+	PromptVersion string       `gorm:"not null;default:''"`
+	ThinkResult   *ThinkResult `gorm:"type:jsonb;not null"`
+	ThinkRating   float64      `gorm:"not null;default:0.0"`
+	Categories    StringArray  `gorm:"type:text[];not null;default:'{}'"`
+}
+
+func (ThinkResultRecord) TableName() string {
+	return "think_results"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (r *ThinkResultRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ItemReview is a reviewer's correction of the analysis of an item. It is
+// kept apart from ThinkResult so a new analysis never overwrites it. Nil
+// scores and category keep the value of the LLM.
+type ItemReview struct {
+	ItemID        uuid.UUID `gorm:"primaryKey;type:uuid" json:"item_id"`
+	Item          Item      `gorm:"foreignKey:ItemID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	CreatedAt     time.Time `gorm:"not null;default:now()" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"not null;default:now()" json:"updated_at"`
+	Reviewer      string    `gorm:"not null;default:''" json:"reviewer"`
+	Note          string    `gorm:"type:text;not null;default:''" json:"note,omitempty"`
+	Framing       *float64  `json:"framing,omitempty"`
+	Clickbait     *float64  `json:"clickbait,omitempty"`
+	Persuasive    *float64  `json:"persuasive,omitempty"`
+	HyperStimulus *float64  `json:"hyper_stimulus,omitempty"`
+	Speculative   *float64  `json:"speculative,omitempty"`
+	Overall       *float64  `json:"overall,omitempty"`
+	Category      *string   `json:"category,omitempty"` // canonical english category
+}
+
+// Validate checks that the scores are in [0, 1] and the category is canonical.
+func (r *ItemReview) Validate() error {
+	scores := []struct {
+		name  string
+		value *float64
+	}{
+		{"framing", r.Framing},
+		{"clickbait", r.Clickbait},
+		{"persuasive", r.Persuasive},
+		{"hyper_stimulus", r.HyperStimulus},
+		{"speculative", r.Speculative},
+		{"overall", r.Overall},
+	}
+	for _, score := range scores {
+		if score.value != nil && (*score.value < 0 || *score.value > 1) {
+			return fmt.Errorf("%s must be between 0 and 1, got %v", score.name, *score.value)
+		}
+	}
+	if r.Category != nil {
+		if err := categorypkg.ValidateEnglishCategory(*r.Category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply returns a copy of res with the reviewed scores and category.
+func (r *ItemReview) Apply(res *ThinkResult) *ThinkResult {
+	if res == nil {
+		return nil
+	}
+	reviewed := *res
+	override := func(dst *float64, src *float64) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	override(&reviewed.Framing, r.Framing)
+	override(&reviewed.Clickbait, r.Clickbait)
+	override(&reviewed.Persuasive, r.Persuasive)
+	override(&reviewed.HyperStimulus, r.HyperStimulus)
+	override(&reviewed.Speculative, r.Speculative)
+	override(&reviewed.Overall, r.Overall)
+	if r.Category != nil {
+		reviewed.Category = *r.Category
+	}
+	return &reviewed
+}
+
+// Feedback kinds readers can submit on an analyzed item.
+const (
+	FeedbackRatingTooHigh = "rating_too_high"
+	FeedbackRatingTooLow  = "rating_too_low"
+	FeedbackTitleWrong    = "title_wrong"
+)
+
+// FeedbackKinds are all feedback kinds, in report order.
+var FeedbackKinds = []string{FeedbackRatingTooHigh, FeedbackRatingTooLow, FeedbackTitleWrong}
+
+// ItemFeedback is a reader's flag on the analysis of an item. A client can
+// submit each kind once per item. LLMModel and ThinkRating are copied from the
+// item, so feedback stays attributable after the item is analyzed again.
+// Source is the API key id of the sender or, with the shared credentials, its
+// remote address; unlike the client id it is not chosen by the client.
+type ItemFeedback struct {
+	ID          uuid.UUID `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	CreatedAt   time.Time `gorm:"not null;default:now();index"`
+	ItemID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_item_feedback_client_kind,priority:1"`
+	Item        Item      `gorm:"foreignKey:ItemID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	FeedID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	ClientID    string    `gorm:"type:varchar(64);not null;index;uniqueIndex:idx_item_feedback_client_kind,priority:2"`
+	Kind        string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_item_feedback_client_kind,priority:3"`
+	Comment     string    `gorm:"type:text;not null;default:''"`
+	LLMModel    string    `gorm:"not null;default:''"`
+	ThinkRating float64   `gorm:"not null;default:0.0"`
+	Source      string    `gorm:"type:varchar(64);not null;default:'';index"`
+}
+
+func (ItemFeedback) TableName() string {
+	return "item_feedback"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (f *ItemFeedback) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// Webhook event types.
+const (
+	WebhookEventItemAnalyzed = "item.analyzed"
+	WebhookEventFeedFailed   = "feed.failed"
+	WebhookEventTrendBurst   = "trend.burst"
+)
+
+// WebhookEvents are all event types a webhook can subscribe to.
+var WebhookEvents = []string{WebhookEventItemAnalyzed, WebhookEventFeedFailed, WebhookEventTrendBurst}
+
+// Webhook subscribes an external URL to events. Empty filters match every
+// event; a filter only applies to events with that property, e.g. the score
+// filters only to item.analyzed.
+type Webhook struct {
+	Base
+	URL         string      `gorm:"type:text;not null" json:"url"`
+	Secret      string      `gorm:"type:text;not null" json:"-"` // HMAC key of the signature header
+	Description string      `gorm:"type:text;not null;default:''" json:"description,omitempty"`
+	Enabled     bool        `gorm:"not null;default:true" json:"enabled"`
+	Events      StringArray `gorm:"type:text[];not null;default:'{}'" json:"events"`
+	Domains     StringArray `gorm:"type:text[];not null;default:'{}'" json:"domains"`
+	Categories  StringArray `gorm:"type:text[];not null;default:'{}'" json:"categories"`
+	MinScore    float64     `gorm:"not null;default:0" json:"min_score,omitempty"`
+	MaxScore    float64     `gorm:"not null;default:0" json:"max_score,omitempty"` // 0 does not filter
+}
+
+// WebhookDelivery is one event for one webhook. It stays as the delivery log
+// after it was delivered or given up.
+type WebhookDelivery struct {
+	ID            uuid.UUID  `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()" json:"id"`
+	CreatedAt     time.Time  `gorm:"not null;default:now();index" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null;default:now()" json:"updated_at"`
+	WebhookID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_key,priority:1" json:"webhook_id"`
+	Webhook       Webhook    `gorm:"foreignKey:WebhookID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Event         string     `gorm:"type:varchar(32);not null" json:"event"`
+	EventKey      string     `gorm:"type:text;not null;uniqueIndex:idx_webhook_deliveries_key,priority:2" json:"event_key"` // an event is delivered once per webhook
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;default:now();index" json:"next_attempt_at"` // pushed ahead while a worker delivers
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	FailedAt      *time.Time `json:"failed_at,omitempty"` // retries exhausted
+	LastStatus    int        `gorm:"not null;default:0" json:"last_status,omitempty"`
+	LastError     *string    `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// ThinkCache stores successful analyses by request content, so the same
+// title and description are only sent to the LLM once per model and prompt.
+type ThinkCache struct {
+	Key           string       `gorm:"primaryKey;type:char(64)"` // sha256 over prompt version, model, language and normalized request
+	CreatedAt     time.Time    `gorm:"not null;default:now()"`
+	LLMModel      string       `gorm:"not null"`
+	PromptVersion string       `gorm:"not null"`
+	Language      string       `gorm:"type:char(2);not null"`
+	ThinkResult   *ThinkResult `gorm:"type:jsonb;not null"`
+	HitCount      int64        `gorm:"not null;default:0"`
+	LastHitAt     *time.Time
+}
+
+// LLMUsage is one LLM call. CompletionTokens are all billed output tokens;
+// ReasoningTokens is the part of them the model spent on thinking.
+type LLMUsage struct {
+	ID               uuid.UUID  `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	CreatedAt        time.Time  `gorm:"not null;default:now();index"`
+	ItemID           *uuid.UUID `gorm:"type:uuid;index"`
+	FeedID           *uuid.UUID `gorm:"type:uuid;index"`
+	Provider         string     `gorm:"not null"`
+	LLMModel         string     `gorm:"not null;index"`
+	PromptTokens     int64      `gorm:"not null;default:0"`
+	CompletionTokens int64      `gorm:"not null;default:0"`
+	ReasoningTokens  int64      `gorm:"not null;default:0"`
+	TotalTokens      int64      `gorm:"not null;default:0"`
+	LatencyMs        int64      `gorm:"not null;default:0"`
+	Cost             float64    `gorm:"not null;default:0"` // USD, priced when recorded
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (u *LLMUsage) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
 // Sentiment represents psychological language analysis scores.
 //
 // It combines two complementary emotion models:
@@ -273,3 +534,48 @@ type Trend struct {
 	Sentiments         *Sentiment  `gorm:"type:jsonb;not null;default:'{}'"`
 	SentimentsDeframed *Sentiment  `gorm:"type:jsonb;not null;default:'{}'"`
 }
+
+// API key scopes, checked against the scopes the API methods require.
+const (
+	ScopeItemsRead     = "items:read"
+	ScopeTrendsRead    = "trends:read"
+	ScopeFeedbackWrite = "feedback:write"
+)
+
+// APIKeyScopes are all scopes a key can be granted.
+var APIKeyScopes = []string{ScopeItemsRead, ScopeTrendsRead, ScopeFeedbackWrite}
+
+// APIKeyPrefix starts every key id, so the service can tell keys from the
+// shared basic auth user.
+const APIKeyPrefix = "dfk_"
+
+// APIKey lets one client group use the API. Clients send the key id as the
+// basic auth user and the secret as the password; only a hash of the secret
+// is stored.
+type APIKey struct {
+	Base
+	KeyID      string      `gorm:"type:varchar(32);not null;uniqueIndex" json:"key_id"`
+	SecretHash string      `gorm:"type:varchar(64);not null" json:"-"` // hex SHA-256, the secret is random
+	Name       string      `gorm:"type:text;not null" json:"name"`
+	Scopes     StringArray `gorm:"type:text[];not null;default:'{}'" json:"scopes"`
+	RateLimit  int         `gorm:"not null;default:0" json:"rate_limit"` // requests per hour, 0 = no limit
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+}
+
+// APIKeyUsage counts the requests of a key per hour. The current hour is
+// also the window of the rate limit.
+type APIKeyUsage struct {
+	APIKeyID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	APIKey   APIKey    `gorm:"foreignKey:APIKeyID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Hour     time.Time `gorm:"primaryKey"`
+	Requests int64     `gorm:"not null;default:0"`
+}
+
+// SchemaMigration holds the single row (ID 1) with the SchemaVersion of the
+// last successful migration.
+type SchemaMigration struct {
+	ID         int       `gorm:"primaryKey;autoIncrement:false"`
+	Version    int       `gorm:"not null"`
+	MigratedAt time.Time `gorm:"not null"`
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -40,6 +42,9 @@ var articlesByTrendQuery string
 //go:embed sql/statement/sentiments_by_trend.sql
 var sentimentsByTrendQuery string
 
+//go:embed sql/statement/trend_bursts.sql
+var trendBurstsQuery string
+
 const DomainComparisonUtilityThreshold = 1.0
 const DomainComparisonOutlierRatioThreshold = 1.5
 const DomainComparisonLimit = 10
@@ -124,6 +129,7 @@ type SentimentItem struct {
 }
 
 type AnalyzedItem struct {
+	ID                 uuid.UUID        `json:"-"`
 	Hash               string           `json:"hash"`
 	Tags               StringArray      `gorm:"type:text[]" json:"tags,omitempty"`
 	URL                string           `json:"url"`
@@ -135,6 +141,8 @@ type AnalyzedItem struct {
 	ThinkRating        float64          `json:"rating"`
 	Authors            StringArray      `gorm:"type:text[]" json:"authors,omitempty"`
 	PubDate            time.Time        `json:"pubDate"`
+	// Review is the reviewer correction already applied to ThinkResult and ThinkRating.
+	Review *ItemReview `gorm:"-" json:"-"`
 }
 
 // MarshalJSON implements json.Marshaler to conditionally include ThinkResult fields based on ThinkRating.
@@ -183,11 +191,284 @@ func (a AnalyzedItem) MarshalJSON() ([]byte, error) {
 			result[k] = v
 		}
 	}
+	if a.Review != nil {
+		result["reviewed"] = true
+		if a.Review.Note != "" {
+			result["review_note"] = a.Review.Note
+		}
+	}
 
 	return json.Marshal(result)
 }
 
+// SiteFilter narrows FindAnalyzedItemsByRootDomain. Empty fields do not
+// filter; scores and category are matched as served, i.e. after reviewer
+// corrections.
+type SiteFilter struct {
+	Category  string
+	Author    string             // case-insensitive, one of the authors
+	MinScores map[string]float64 // keyed by dimension: framing, clickbait, persuasive, hyper_stimulus, speculative, overall
+	MaxScores map[string]float64
+	From      *time.Time // inclusive
+	To        *time.Time // exclusive
+	After     *SiteCursor
+}
+
+// SiteCursor is the position of an item in the newest first site listing.
+type SiteCursor struct {
+	PubDate time.Time `json:"p"`
+	ID      uuid.UUID `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c SiteCursor) Encode() string {
+	return encodeCursor(c)
+}
+
+// ParseSiteCursor reads a token returned by Encode.
+func ParseSiteCursor(token string) (*SiteCursor, error) {
+	var c SiteCursor
+	if err := decodeCursor(token, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ItemEvent is a finished analysis. EventID orders the events and is the SSE
+// event id of /api/stream.
+type ItemEvent struct {
+	EventID    int64        `gorm:"column:event_id"`
+	RootDomain string       `gorm:"column:root_domain"`
+	Item       AnalyzedItem `gorm:"embedded"`
+}
+
+// ItemEventFilter narrows FindItemEventsAfter. Empty fields do not filter.
+type ItemEventFilter struct {
+	RootDomain string
+	MaxScore   float64
+}
+
+// WebhookEvent is something that happened, to be delivered to the matching
+// webhooks. Empty properties are not filtered on.
+type WebhookEvent struct {
+	Type       string
+	Key        string // identifies the event, it is delivered once per webhook
+	RootDomain string
+	Category   string
+	Score      *float64
+	Data       any
+}
+
+// WebhookItemData is the data of an item.analyzed event.
+type WebhookItemData struct {
+	Hash       string    `json:"hash"`
+	URL        string    `json:"url"`
+	RootDomain string    `json:"root_domain"`
+	Language   string    `json:"language,omitempty"`
+	Title      string    `json:"title"`
+	Category   string    `json:"category"`
+	Rating     float64   `json:"rating"`
+	LLMModel   string    `json:"llm_model"`
+	PubDate    time.Time `json:"pub_date"`
+}
+
+// WebhookFeedData is the data of a feed.failed event.
+type WebhookFeedData struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	URL        string    `json:"url"`
+	RootDomain string    `json:"root_domain"`
+	Error      string    `json:"error"`
+}
+
+// TrendBurst is a topic that appears far more often on a domain than in the
+// days before, the data of a trend.burst event.
+type TrendBurst struct {
+	TrendTopic   string    `gorm:"column:trend_topic" json:"trend_topic"`
+	Language     string    `gorm:"column:language" json:"language"`
+	RootDomain   string    `gorm:"column:root_domain" json:"root_domain"`
+	Frequency    int64     `gorm:"column:frequency" json:"frequency"`
+	Utility      int64     `gorm:"column:utility" json:"utility"`
+	OutlierRatio float64   `gorm:"column:outlier_ratio" json:"outlier_ratio"`
+	TimeSlice    time.Time `gorm:"column:time_slice" json:"time_slice"`
+}
+
+// WebhookDeliveryFilter selects the delivery log of ListWebhookDeliveries.
+type WebhookDeliveryFilter struct {
+	WebhookID  *uuid.UUID
+	FailedOnly bool
+	Limit      int
+}
+
+// ItemSearch is a full-text query over the analyzed items. Empty fields do
+// not filter.
+type ItemSearch struct {
+	Query    string
+	Language string
+	Domain   string
+	From     *time.Time // inclusive
+	To       *time.Time // exclusive
+	MaxScore float64
+	Category string // english category
+	Limit    int
+	After    *SearchCursor
+}
+
+// SearchHit is an item found by SearchItems. The highlights mark the
+// matched words of the displayed title and description with <mark>.
+type SearchHit struct {
+	Item                 AnalyzedItem `gorm:"embedded" json:"item"`
+	Rank                 float32      `gorm:"column:rank" json:"rank"`
+	TitleHighlight       string       `gorm:"column:title_highlight" json:"title_highlight"`
+	DescriptionHighlight string       `gorm:"column:description_highlight" json:"description_highlight"`
+}
+
+// Cursor returns the position after the hit.
+func (h SearchHit) Cursor() SearchCursor {
+	return SearchCursor{Rank: h.Rank, PubDate: h.Item.PubDate, ID: h.Item.ID}
+}
+
+// SearchCursor is the position of a hit in the ranking of a search.
+type SearchCursor struct {
+	Rank    float32   `json:"r"`
+	PubDate time.Time `json:"p"`
+	ID      uuid.UUID `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c SearchCursor) Encode() string {
+	return encodeCursor(c)
+}
+
+// ParseSearchCursor reads a token returned by Encode.
+func ParseSearchCursor(token string) (*SearchCursor, error) {
+	var c SearchCursor
+	if err := decodeCursor(token, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func encodeCursor(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid cursor: %w", err)
+	}
+	return nil
+}
+
+// LLMUsageReport is one row of the usage report. Columns that are not part
+// of the grouping are nil.
+type LLMUsageReport struct {
+	Day              *time.Time `json:"day,omitempty"`
+	LLMModel         *string    `gorm:"column:llm_model" json:"llm_model,omitempty"`
+	FeedID           *uuid.UUID `json:"feed_id,omitempty"`
+	FeedURL          *string    `json:"feed_url,omitempty"`
+	Calls            int64      `json:"calls"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	ReasoningTokens  int64      `json:"reasoning_tokens"`
+	AvgLatencyMs     float64    `json:"avg_latency_ms"`
+	Cost             float64    `json:"cost"`
+}
+
+// FeedLanguageStatsResult is the item count of one detected language of a feed.
+type FeedLanguageStatsResult struct {
+	FeedID           uuid.UUID `json:"feed_id"`
+	URL              string    `json:"url"`
+	DeclaredLanguage *string   `json:"declared_language,omitempty"`
+	Language         string    `json:"language"`
+	Items            int64     `json:"items"`
+}
+
+// LLMUsageGroups are the supported groupings for GetLLMUsageReport.
+var LLMUsageGroups = []string{"day", "model", "feed"}
+
+// ThinkErrorKindRefused marks items the LLM refused to analyze (think.ErrorRefused).
+// Retrying them does not help, so they leave the thinker queue right away.
+const ThinkErrorKindRefused = "refused"
+
+// ThinkerQueueDepth counts the items waiting per lane of the thinker queue.
+type ThinkerQueueDepth struct {
+	Thinker int64 `json:"thinker"`
+	Fixer   int64 `json:"fixer"`
+	Dead    int64 `json:"dead"`
+	Refused int64 `json:"refused"`
+	// UpdateLLMModel and UpdatePrompt are the analyzed items the update lanes
+	// have not analyzed with the current model or prompt yet.
+	UpdateLLMModel int64 `json:"update_llm_model"`
+	UpdatePrompt   int64 `json:"update_prompt"`
+}
+
+// SchemaStatus describes the schema a service runs against.
+type SchemaStatus struct {
+	// Version is the SchemaVersion of the last migration, 0 if none was recorded.
+	Version       int
+	MissingViews  []string
+	DuckDBVersion string // empty without pg_duckdb
+}
+
+// DeadItemFilter selects dead-lettered items: items without analysis whose
+// error count reached MinErrorCount or that were refused. The other fields
+// narrow the selection.
+type DeadItemFilter struct {
+	MinErrorCount int
+	IDs           []uuid.UUID
+	FeedID        *uuid.UUID
+	ErrorContains string
+}
+
+// DeadItemGroup counts the dead-lettered items of a feed failing with the same error.
+type DeadItemGroup struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedURL    string    `json:"feed_url"`
+	Kind       string    `json:"kind"`
+	ThinkError string    `json:"think_error"`
+	Items      int64     `json:"items"`
+	Oldest     time.Time `json:"oldest"`
+	Newest     time.Time `json:"newest"`
+}
+
+// FeedbackReport is one row of the reader feedback report. Columns that are
+// not part of the grouping are nil.
+type FeedbackReport struct {
+	ItemID        *uuid.UUID `json:"item_id,omitempty"`
+	ItemURL       *string    `json:"item_url,omitempty"`
+	FeedID        *uuid.UUID `json:"feed_id,omitempty"`
+	FeedURL       *string    `json:"feed_url,omitempty"`
+	LLMModel      *string    `gorm:"column:llm_model" json:"llm_model,omitempty"`
+	Items         int64      `json:"items"`
+	Clients       int64      `json:"clients"`
+	RatingTooHigh int64      `json:"rating_too_high"`
+	RatingTooLow  int64      `json:"rating_too_low"`
+	TitleWrong    int64      `json:"title_wrong"`
+	AvgRating     float64    `json:"avg_rating"`
+}
+
+// APIKeyUsageReport is the usage of one key, revoked keys included.
+type APIKeyUsageReport struct {
+	ID         uuid.UUID  `json:"id"`
+	KeyID      string     `json:"key_id"`
+	Name       string     `json:"name"`
+	Requests   int64      `json:"requests"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// FeedbackGroups are the supported groupings for GetFeedbackReport.
+var FeedbackGroups = []string{"item", "feed", "model"}
+
 type Repository interface {
+	// WithContext returns the repository running its queries with ctx, which
+	// cancels them and makes them children of the span in ctx.
+	WithContext(ctx context.Context) Repository
 	FindFeedByUrl(u *url.URL) (*Feed, error)
 	FindFeedByUrlAndAvailability(u *url.URL, onlyEnabled bool) (*Feed, error)
 	FindFeedById(feedID uuid.UUID) (*Feed, error)
@@ -219,6 +500,39 @@ type Repository interface {
 	BeginThinkerBatch(limit int, since time.Time, minErrorCount int, maxErrorCount int, lockDuration time.Duration) ([]Item, error)
 	BeginThinkerFixerBatch(limit int, since time.Time, minErrorCount int, maxErrorCount int, lockDuration time.Duration) ([]Item, error)
 	BeginThinkerUpdateLLMModelBatch(limit int, llmModel string, lockDuration time.Duration) ([]Item, error)
+	// BeginThinkerUpdatePromptVersionBatch claims analyzed items whose prompt_version differs
+	// from promptVersions[language]. Items in languages missing from the map are never claimed.
+	BeginThinkerUpdatePromptVersionBatch(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]Item, error)
+	// AddFeedLanguageStats adds item counts per detected language to the stats of a feed.
+	AddFeedLanguageStats(feedID uuid.UUID, counts map[string]int64) error
+	// GetFeedLanguageStats returns the detected language counts of all feeds that are not deleted.
+	GetFeedLanguageStats() ([]FeedLanguageStatsResult, error)
+	// ListThinkResultHistory returns the analysis history of an item, newest first.
+	ListThinkResultHistory(itemID uuid.UUID) ([]ThinkResultRecord, error)
+	// RollbackThinkResults restores the previous analysis of every item whose current
+	// result was produced by llmModel since the given time and returns the restored item ids.
+	RollbackThinkResults(llmModel string, since time.Time) ([]uuid.UUID, error)
+	// GetThinkerQueueDepth counts the items without analysis in the thinker lane (up to
+	// maxRetries errors), the fixer lane (up to maxFixerErrorCount errors) and beyond.
+	GetThinkerQueueDepth(maxRetries int, maxFixerErrorCount int) (*ThinkerQueueDepth, error)
+	// CountThinkerUpdateCandidates counts the analyzed items the update lanes
+	// still have to analyze again with llmModel and with the prompt versions.
+	CountThinkerUpdateCandidates(llmModel string, promptVersions map[string]string) (int64, int64, error)
+	// CountLockWaits counts the queries of this database waiting for a lock.
+	CountLockWaits() (int64, error)
+	// Ping runs a trivial query to check the connection.
+	Ping(timeout time.Duration) error
+	// GetSchemaStatus reports the schema version, the missing RequiredViews and pg_duckdb.
+	GetSchemaStatus(timeout time.Duration) (*SchemaStatus, error)
+	// GetDeadItemGroups groups the dead-lettered items by feed and error, largest groups first.
+	GetDeadItemGroups(filter DeadItemFilter) ([]DeadItemGroup, error)
+	// FindDeadItems returns the dead-lettered items with their feeds, oldest first.
+	FindDeadItems(filter DeadItemFilter, limit int) ([]Item, error)
+	// RequeueDeadItems resets the error count of the dead-lettered items, so the thinker
+	// picks them up again, and returns how many were requeued.
+	RequeueDeadItems(filter DeadItemFilter) (int64, error)
+	// PurgeDeadItems deletes the dead-lettered items and returns how many were deleted.
+	PurgeDeadItems(filter DeadItemFilter) (int64, error)
 	UpsertItem(item *Item) error
 	UpsertItemWithTrendInvalidation(item *Item) error
 	FindFeedScheduleById(feedID uuid.UUID) (*FeedSchedule, error)
@@ -229,8 +543,115 @@ type Repository interface {
 	GetDomainComparison(domainA string, domainB string, language string, date *time.Time, days int, utilityThreshold float64, outlierRatioThreshold float64, limit int) ([]DomainComparison, error)
 	GetArticlesByTrend(term string, domain string, date *time.Time, days int, offset int, limit int) ([]AnalyzedArticle, error)
 	GetSentimentsByTrend(term string, domain string, date *time.Time, days int) (*SentimentItem, error)
-	FindAnalyzedItemsByRootDomain(rootDomain string, limit int) ([]AnalyzedItem, error)
+	// FindAnalyzedItemsByRootDomain returns the newest analyzed items of a root
+	// domain, one per URL, starting after filter.After.
+	FindAnalyzedItemsByRootDomain(rootDomain string, filter SiteFilter, limit int) ([]AnalyzedItem, error)
 	FindFirstAnalyzedItemByUrl(u *url.URL) (*AnalyzedItem, error)
+	// FindFirstAnalyzedItemsByUrls resolves many URLs in one query; URLs
+	// without an item are missing from the map.
+	FindFirstAnalyzedItemsByUrls(urls []string) (map[string]AnalyzedItem, error)
+	// SearchItems ranks the analyzed items matching the full-text query,
+	// best first, and returns at most search.Limit hits after search.After.
+	SearchItems(search ItemSearch) ([]SearchHit, error)
+	// FindItemEventsAfter returns the analyses finished after the event id, in
+	// event order. Only the current analysis of an item is returned. Event ids
+	// are assigned in commit order, so no analysis committed later can appear
+	// below the last id returned.
+	FindItemEventsAfter(after int64, filter ItemEventFilter, limit int) ([]ItemEvent, error)
+	// LatestItemEventID returns the id of the newest event, 0 without any.
+	LatestItemEventID() (int64, error)
+}
+
+// ThinkCacheRepository stores the content-addressed analysis cache.
+type ThinkCacheRepository interface {
+	// FindThinkCache returns the cached analysis for key and counts the hit, or nil when there is none.
+	FindThinkCache(key string) (*ThinkResult, error)
+	UpsertThinkCache(entry *ThinkCache) error
+}
+
+// LLMUsageRepository records the token usage and cost of the LLM calls.
+type LLMUsageRepository interface {
+	CreateLLMUsages(usages []LLMUsage) error
+	// SumLLMCostSince returns the recorded cost of all LLM calls since the given time.
+	SumLLMCostSince(since time.Time) (float64, error)
+	// GetLLMUsageReport aggregates the LLM calls since the given time by any of LLMUsageGroups.
+	GetLLMUsageReport(since time.Time, groupBy []string) ([]LLMUsageReport, error)
+}
+
+// ReviewRepository stores the corrections of reviewers.
+type ReviewRepository interface {
+	// UpsertItemReview stores the review of an item, replacing an earlier one.
+	UpsertItemReview(review *ItemReview) error
+	// DeleteItemReview removes the review of an item, so the analysis of the LLM applies again.
+	DeleteItemReview(itemID uuid.UUID) error
+	// ListItemReviews returns all reviews with their items, most recently updated first.
+	ListItemReviews() ([]ItemReview, error)
+}
+
+// FeedbackRepository stores the feedback of readers on the analyses.
+type FeedbackRepository interface {
+	// CreateItemFeedback stores reader feedback on the newest analyzed item with the hash
+	// and reports whether there is such an item. Repeated feedback of a client is ignored.
+	CreateItemFeedback(hash string, feedback *ItemFeedback) (bool, error)
+	// CountItemFeedbackSince returns how much feedback a client submitted since the given time.
+	CountItemFeedbackSince(clientID string, since time.Time) (int64, error)
+	// CountItemFeedbackFromSourceSince returns how much feedback was submitted
+	// from an API key or remote address since the given time.
+	CountItemFeedbackFromSourceSince(source string, since time.Time) (int64, error)
+	// GetFeedbackReport aggregates the feedback since the given time by one of FeedbackGroups.
+	GetFeedbackReport(since time.Time, groupBy string, limit int) ([]FeedbackReport, error)
+}
+
+// WebhookRepository stores the webhooks, their delivery log and the trend
+// bursts the dispatcher announces.
+type WebhookRepository interface {
+	CreateWebhook(webhook *Webhook) error
+	ListWebhooks() ([]Webhook, error)
+	// SetWebhookEnabled reports whether the webhook exists.
+	SetWebhookEnabled(id uuid.UUID, enabled bool) (bool, error)
+	// DeleteWebhook reports whether the webhook existed. Its delivery log is kept.
+	DeleteWebhook(id uuid.UUID) (bool, error)
+	// EnqueueWebhookEvent queues a delivery of the event for every matching
+	// webhook that did not get it yet.
+	EnqueueWebhookEvent(event WebhookEvent) error
+	// BeginWebhookDeliveries claims due deliveries of enabled webhooks for
+	// lockDuration, the oldest first.
+	BeginWebhookDeliveries(limit int, lockDuration time.Duration) ([]WebhookDelivery, error)
+	UpdateWebhookDelivery(delivery *WebhookDelivery) error
+	ListWebhookDeliveries(filter WebhookDeliveryFilter) ([]WebhookDelivery, error)
+	// RedeliverWebhookDelivery queues a delivery again with fresh retries and
+	// reports whether it exists.
+	RedeliverWebhookDelivery(id uuid.UUID) (bool, error)
+	// FindTrendBursts returns the topics whose outlier ratio on a domain
+	// reached minRatio in a day since the given time.
+	FindTrendBursts(since time.Time, minRatio float64, minFrequency int) ([]TrendBurst, error)
+}
+
+// APIKeyRepository stores the API keys of external clients and their usage.
+type APIKeyRepository interface {
+	CreateAPIKey(key *APIKey) error
+	// ListAPIKeys returns all keys, revoked ones included.
+	ListAPIKeys() ([]APIKey, error)
+	// FindAPIKey returns nil if no key has the key id.
+	FindAPIKey(keyID string) (*APIKey, error)
+	// RevokeAPIKey reports whether an active key was revoked.
+	RevokeAPIKey(id uuid.UUID) (bool, error)
+	// RecordAPIKeyRequest counts a request of the key and returns the requests
+	// of the current hour, this one included.
+	RecordAPIKeyRequest(id uuid.UUID) (int64, error)
+	GetAPIKeyUsage(since time.Time) ([]APIKeyUsageReport, error)
+}
+
+// Store is everything the database offers. Consumers take only the
+// interfaces they use, so their tests only stub those.
+type Store interface {
+	Repository
+	ThinkCacheRepository
+	LLMUsageRepository
+	ReviewRepository
+	FeedbackRepository
+	WebhookRepository
+	APIKeyRepository
 }
 
 type repository struct {
@@ -255,6 +676,15 @@ func Connect(ctx context.Context, cfg *config.Config) error {
 	return err
 }
 
+// SQLDB returns the connection pool of the database singleton; Connect or
+// NewRepository must have succeeded before.
+func SQLDB() (*sql.DB, error) {
+	if dbInstance == nil {
+		return nil, fmt.Errorf("database is not connected")
+	}
+	return dbInstance.DB()
+}
+
 func connect(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
 	if dbInstance != nil {
 		return dbInstance, nil
@@ -276,6 +706,10 @@ func connect(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
 			dbErr = fmt.Errorf("failed to connect to database: %w", err)
 			return
 		}
+		if err := db.Use(tracingPlugin{}); err != nil {
+			dbErr = fmt.Errorf("failed to register tracing: %w", err)
+			return
+		}
 
 		if err := Migrate(db, false); err != nil {
 			dbErr = fmt.Errorf("failed to migrate database: %w", err)
@@ -289,7 +723,7 @@ func connect(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
 }
 
 // NewRepository initializes a new repository with a database connection from config.
-func NewRepository(ctx context.Context, cfg *config.Config) (Repository, error) {
+func NewRepository(ctx context.Context, cfg *config.Config) (Store, error) {
 	db, err := connect(ctx, cfg)
 	if err != nil {
 		return nil, err
@@ -299,10 +733,14 @@ func NewRepository(ctx context.Context, cfg *config.Config) (Repository, error)
 }
 
 // NewFromDB creates a repository from an existing GORM connection.
-func NewFromDB(db *gorm.DB) Repository {
+func NewFromDB(db *gorm.DB) Store {
 	return &repository{ctx: context.Background(), db: db}
 }
 
+func (r *repository) WithContext(ctx context.Context) Repository {
+	return &repository{ctx: ctx, db: r.db.WithContext(ctx)}
+}
+
 func (r *repository) FindFeedByUrl(u *url.URL) (*Feed, error) {
 	return r.FindFeedByUrlAndAvailability(u, true)
 }
@@ -441,7 +879,52 @@ func (r *repository) UpsertItem(item *Item) error {
 
 func (r *repository) UpsertItemWithTrendInvalidation(item *Item) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		return r.upsertItemInternal(tx, item, true)
+		analyzed := item.ThinkResult != nil && item.ThinkResultID == nil
+		if err := r.upsertItemInternal(tx, item, true); err != nil {
+			return err
+		}
+		if !analyzed {
+			return nil
+		}
+		if err := enqueueItemAnalyzed(tx, item); err != nil {
+			return err
+		}
+		// Postgres delivers the notification on commit
+		return tx.Exec("SELECT pg_notify(?, event_id::text) FROM think_results WHERE id = ?", ItemsAnalyzedChannel, item.ThinkResultID).Error
+	})
+}
+
+func enqueueItemAnalyzed(tx *gorm.DB, item *Item) error {
+	var feed Feed
+	if err := tx.Unscoped().Select("id", "root_domain").First(&feed, "id = ?", item.FeedID).Error; err != nil {
+		return err
+	}
+	data := WebhookItemData{
+		Hash:     item.Hash,
+		URL:      item.URL,
+		Title:    item.ThinkResult.TitleCorrected,
+		Category: item.ThinkResult.Category,
+		Rating:   item.ThinkRating,
+		LLMModel: item.ThinkResult.LLMModel,
+		PubDate:  item.PubDate,
+	}
+	if data.Title == "" {
+		data.Title = item.ThinkResult.TitleOriginal
+	}
+	if feed.RootDomain != nil {
+		data.RootDomain = *feed.RootDomain
+	}
+	if item.Language != nil {
+		data.Language = *item.Language
+	}
+	rating := item.ThinkRating
+	return enqueueWebhookEvent(tx, WebhookEvent{
+		Type:       WebhookEventItemAnalyzed,
+		Key:        item.ThinkResultID.String(),
+		RootDomain: data.RootDomain,
+		Category:   data.Category,
+		Score:      &rating,
+		Data:       data,
 	})
 }
 
@@ -477,6 +960,12 @@ func (r *repository) upsertItemInternal(tx *gorm.DB, item *Item, invalidateTrend
 		return err
 	}
 
+	if item.ThinkResult != nil && item.ThinkResultID == nil {
+		if err := recordThinkResult(tx, item); err != nil {
+			return err
+		}
+	}
+
 	if invalidateTrend {
 		if err := tx.Where("item_id = ?", item.ID).Delete(&Trend{}).Error; err != nil {
 			return err
@@ -486,6 +975,161 @@ func (r *repository) upsertItemInternal(tx *gorm.DB, item *Item, invalidateTrend
 	return nil
 }
 
+func (r *repository) AddFeedLanguageStats(feedID uuid.UUID, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	stats := make([]FeedLanguageStat, 0, len(counts))
+	for language, items := range counts {
+		stats = append(stats, FeedLanguageStat{FeedID: feedID, Language: language, Items: items, UpdatedAt: time.Now()})
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "feed_id"}, {Name: "language"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"items":      gorm.Expr("feed_language_stats.items + excluded.items"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&stats).Error
+}
+
+func (r *repository) GetFeedLanguageStats() ([]FeedLanguageStatsResult, error) {
+	var results []FeedLanguageStatsResult
+	err := r.db.Table("feed_language_stats").
+		Select("feed_language_stats.feed_id, feeds.url, NULLIF(TRIM(feeds.language), '') AS declared_language, feed_language_stats.language, feed_language_stats.items").
+		Joins("JOIN feeds ON feeds.id = feed_language_stats.feed_id").
+		Where("feeds.deleted_at IS NULL").
+		Order("feeds.url, feed_language_stats.items DESC, feed_language_stats.language").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// recordThinkResult appends the item's result to the history and makes it the current one.
+func recordThinkResult(tx *gorm.DB, item *Item) error {
+	record := ThinkResultRecord{
+		ItemID:        item.ID,
+		LLMModel:      item.ThinkResult.LLMModel,
+		PromptVersion: item.ThinkResult.PromptVersion,
+		ThinkResult:   item.ThinkResult,
+		ThinkRating:   item.ThinkRating,
+		Categories:    item.Categories,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&Item{}).Where("id = ?", item.ID).UpdateColumn("think_result_id", record.ID).Error; err != nil {
+		return err
+	}
+	item.ThinkResultID = &record.ID
+	return nil
+}
+
+func (r *repository) ListThinkResultHistory(itemID uuid.UUID) ([]ThinkResultRecord, error) {
+	var records []ThinkResultRecord
+	if err := r.db.Where("item_id = ?", itemID).Order("created_at DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *repository) RollbackThinkResults(llmModel string, since time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// the previous entry is the newest older one that was not produced by the rolled back model
+		if err := tx.Raw(`
+			WITH targets AS (
+				SELECT items.id AS item_id, prev.id AS record_id
+				FROM items
+				JOIN think_results cur ON cur.id = items.think_result_id
+				CROSS JOIN LATERAL (
+					SELECT p.id FROM think_results p
+					WHERE p.item_id = items.id AND p.llm_model <> cur.llm_model AND p.created_at < cur.created_at
+					ORDER BY p.created_at DESC
+					LIMIT 1
+				) prev
+				WHERE cur.llm_model = ? AND cur.created_at >= ?
+			)
+			UPDATE items SET
+				think_result = p.think_result,
+				think_rating = p.think_rating,
+				categories = p.categories,
+				think_result_id = p.id,
+				think_error = NULL,
+				think_error_count = 0,
+				think_error_kind = NULL,
+				updated_at = now()
+			FROM targets
+			JOIN think_results p ON p.id = targets.record_id
+			WHERE items.id = targets.item_id
+			RETURNING items.id`, llmModel, since).Scan(&ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		// let the trend miner recreate the trends from the restored results
+		return tx.Where("item_id IN ?", ids).Delete(&Trend{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) UpsertItemReview(review *ItemReview) error {
+	review.UpdatedAt = time.Now()
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "reviewer", "note", "framing", "clickbait", "persuasive", "hyper_stimulus", "speculative", "overall", "category"}),
+	}).Omit("Item").Create(review).Error
+}
+
+func (r *repository) DeleteItemReview(itemID uuid.UUID) error {
+	return r.db.Where("item_id = ?", itemID).Delete(&ItemReview{}).Error
+}
+
+func (r *repository) ListItemReviews() ([]ItemReview, error) {
+	var reviews []ItemReview
+	if err := r.db.Preload("Item").Order("updated_at DESC").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// applyItemReviews replaces the analysis of reviewed items with the reviewer
+// corrections, so every serialization of an AnalyzedItem prefers them.
+func (r *repository) applyItemReviews(items []AnalyzedItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(items))
+	for i := range items {
+		ids = append(ids, items[i].ID)
+	}
+	var reviews []ItemReview
+	if err := r.db.Where("item_id IN ?", ids).Find(&reviews).Error; err != nil {
+		return err
+	}
+	byItem := make(map[uuid.UUID]*ItemReview, len(reviews))
+	for i := range reviews {
+		byItem[reviews[i].ItemID] = &reviews[i]
+	}
+	for i := range items {
+		review, ok := byItem[items[i].ID]
+		if !ok || items[i].ThinkResult == nil {
+			continue
+		}
+		items[i].Review = review
+		items[i].ThinkResult = review.Apply(items[i].ThinkResult)
+		if review.Overall != nil {
+			items[i].ThinkRating = *review.Overall
+		}
+	}
+	return nil
+}
+
 func (r *repository) EnqueueSync(id uuid.UUID, pollingInterval time.Duration) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		return r.enqueueSyncTx(tx, id, pollingInterval)
@@ -684,6 +1328,22 @@ func (r *repository) EndFeedUpdate(id uuid.UUID, jobErr error, pollingInterval t
 			return err
 		}
 
+		// only the first failure is an event, not every failed poll after it
+		if jobErr != nil && feed.LastError == nil {
+			data := WebhookFeedData{FeedID: feed.ID, URL: feed.URL, Error: jobErr.Error()}
+			if feed.RootDomain != nil {
+				data.RootDomain = *feed.RootDomain
+			}
+			if err := enqueueWebhookEvent(tx, WebhookEvent{
+				Type:       WebhookEventFeedFailed,
+				Key:        fmt.Sprintf("%s@%d", feed.ID, time.Now().Unix()),
+				RootDomain: data.RootDomain,
+				Data:       data,
+			}); err != nil {
+				return err
+			}
+		}
+
 		if feed.Enabled && feed.Polling {
 			return r.enqueueSyncTx(tx, id, pollingInterval)
 		}
@@ -713,11 +1373,12 @@ func (r *repository) GetPendingItems(feedID uuid.UUID, hashes []string, maxRetri
 				Hash            string
 				ThinkResult     *ThinkResult
 				ThinkErrorCount int
+				ThinkErrorKind  *string
 			}
 
 			// We fetch items that exist to check their status
 			if err := tx.Model(&Item{}).
-				Select("hash, think_result, think_error_count").
+				Select("hash, think_result, think_error_count, think_error_kind").
 				Where("feed_id = ?", feedID).
 				Where("hash IN ?", batch).
 				Scan(&foundItems).Error; err != nil {
@@ -725,8 +1386,9 @@ func (r *repository) GetPendingItems(feedID uuid.UUID, hashes []string, maxRetri
 			}
 
 			for _, item := range foundItems {
-				// If processed (ThinkResult not nil) OR failed too many times, remove from pending
-				if item.ThinkResult != nil || item.ThinkErrorCount > maxRetries {
+				// If processed (ThinkResult not nil), failed too many times or refused, remove from pending
+				refused := item.ThinkErrorKind != nil && *item.ThinkErrorKind == ThinkErrorKindRefused
+				if item.ThinkResult != nil || item.ThinkErrorCount > maxRetries || refused {
 					delete(pendingItems, item.Hash)
 				} else {
 					// It exists but needs retry (or initial processing if error count is small)
@@ -800,7 +1462,8 @@ func (r *repository) BeginThinkerBatch(limit int, since time.Time, minErrorCount
 			Where("items.think_result IS NULL").
 			Where("items.updated_at <= ?", now).
 			Where("items.think_error_count >= ?", minErrorCount).
-			Where("items.think_error_count <= ?", maxErrorCount)
+			Where("items.think_error_count <= ?", maxErrorCount).
+			Where("items.think_error_kind IS DISTINCT FROM ?", ThinkErrorKindRefused)
 		if !since.IsZero() {
 			query = query.Where("items.created_at >= ?", since)
 		}
@@ -857,6 +1520,7 @@ func (r *repository) BeginThinkerFixerBatch(limit int, since time.Time, minError
 			Where("items.think_result IS NULL").
 			Where("items.think_error_count >= ?", minErrorCount).
 			Where("items.think_error_count <= ?", maxErrorCount).
+			Where("items.think_error_kind IS DISTINCT FROM ?", ThinkErrorKindRefused).
 			Where("items.updated_at <= ?", lockBefore)
 		if !since.IsZero() {
 			query = query.Where("items.created_at >= ?", since)
@@ -892,6 +1556,38 @@ func (r *repository) BeginThinkerFixerBatch(limit int, since time.Time, minError
 	return items, nil
 }
 
+// analyzedCandidates are the analyzed items of polled feeds, which the update
+// lanes analyze again.
+func analyzedCandidates(db *gorm.DB) *gorm.DB {
+	return db.Model(&Item{}).
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Where("feeds.deleted_at IS NULL").
+		Where("feeds.enabled = ?", true).
+		Where("feeds.polling = ?", true).
+		Where("items.think_result IS NOT NULL")
+}
+
+func updateLLMModelCandidates(db *gorm.DB, llmModel string) *gorm.DB {
+	return analyzedCandidates(db).
+		Where("NULLIF(items.think_result->>'llm_model', '') IS DISTINCT FROM ?", llmModel)
+}
+
+func updatePromptCandidates(db *gorm.DB, promptVersions map[string]string) *gorm.DB {
+	languages := make([]string, 0, len(promptVersions))
+	for language := range promptVersions {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	conditions := make([]string, 0, len(languages))
+	args := make([]interface{}, 0, len(languages)*2)
+	for _, language := range languages {
+		conditions = append(conditions, "(COALESCE(NULLIF(items.language, ''), 'en') = ? AND NULLIF(items.think_result->>'prompt_version', '') IS DISTINCT FROM ?)")
+		args = append(args, language, promptVersions[language])
+	}
+	return analyzedCandidates(db).Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
 func (r *repository) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string, lockDuration time.Duration) ([]Item, error) {
 	var items []Item
 
@@ -902,14 +1598,8 @@ func (r *repository) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string,
 	lockBefore := time.Now().Add(-lockDuration)
 
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		query := tx.Model(&Item{}).
+		query := updateLLMModelCandidates(tx, llmModel).
 			Select("items.*").
-			Joins("JOIN feeds ON feeds.id = items.feed_id").
-			Where("feeds.deleted_at IS NULL").
-			Where("feeds.enabled = ?", true).
-			Where("feeds.polling = ?", true).
-			Where("items.think_result IS NOT NULL").
-			Where("NULLIF(items.think_result->>'llm_model', '') IS DISTINCT FROM ?", llmModel).
 			Where("items.updated_at <= ?", lockBefore)
 
 		if err := query.
@@ -942,6 +1632,378 @@ func (r *repository) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string,
 	return items, nil
 }
 
+func (r *repository) BeginThinkerUpdatePromptVersionBatch(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]Item, error) {
+	var items []Item
+
+	if limit <= 0 || len(promptVersions) == 0 {
+		return items, nil
+	}
+
+	lockBefore := time.Now().Add(-lockDuration)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := updatePromptCandidates(tx, promptVersions).
+			Select("items.*").
+			Where("items.updated_at <= ?", lockBefore)
+
+		if err := query.
+			Order("items.updated_at ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Feed").
+			Find(&items).Error; err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+
+		return tx.Model(&Item{}).
+			Where("id IN ?", ids).
+			Update("updated_at", gorm.Expr("NOW()")).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *repository) FindThinkCache(key string) (*ThinkResult, error) {
+	var entry ThinkCache
+	if err := r.db.Where("key = ?", key).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := r.db.Model(&ThinkCache{}).
+		Where("key = ?", key).
+		Updates(map[string]interface{}{
+			"hit_count":   gorm.Expr("hit_count + 1"),
+			"last_hit_at": gorm.Expr("NOW()"),
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	return entry.ThinkResult, nil
+}
+
+func (r *repository) UpsertThinkCache(entry *ThinkCache) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"think_result"}),
+	}).Create(entry).Error
+}
+
+func (r *repository) CreateLLMUsages(usages []LLMUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	return r.db.Create(&usages).Error
+}
+
+func (r *repository) SumLLMCostSince(since time.Time) (float64, error) {
+	var cost float64
+	err := r.db.Model(&LLMUsage{}).
+		Select("COALESCE(SUM(cost), 0)").
+		Where("created_at >= ?", since).
+		Scan(&cost).Error
+	return cost, err
+}
+
+func (r *repository) GetLLMUsageReport(since time.Time, groupBy []string) ([]LLMUsageReport, error) {
+	columns := map[string][]string{
+		"day":   {"date_trunc('day', llm_usages.created_at) AS day"},
+		"model": {"llm_usages.llm_model AS llm_model"},
+		"feed":  {"llm_usages.feed_id AS feed_id", "MAX(feeds.url) AS feed_url"},
+	}
+	groups := map[string][]string{
+		"day":   {"date_trunc('day', llm_usages.created_at)"},
+		"model": {"llm_usages.llm_model"},
+		"feed":  {"llm_usages.feed_id"},
+	}
+
+	selects := []string{}
+	grouping := []string{}
+	seen := map[string]bool{}
+	for _, group := range groupBy {
+		if _, ok := columns[group]; !ok {
+			return nil, fmt.Errorf("unknown usage grouping %q (expected one of %s)", group, strings.Join(LLMUsageGroups, ", "))
+		}
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+		selects = append(selects, columns[group]...)
+		grouping = append(grouping, groups[group]...)
+	}
+	selects = append(selects,
+		"COUNT(*) AS calls",
+		"SUM(llm_usages.prompt_tokens) AS prompt_tokens",
+		"SUM(llm_usages.completion_tokens) AS completion_tokens",
+		"SUM(llm_usages.reasoning_tokens) AS reasoning_tokens",
+		"AVG(llm_usages.latency_ms) AS avg_latency_ms",
+		"SUM(llm_usages.cost) AS cost",
+	)
+
+	query := r.db.Table("llm_usages").
+		Select(strings.Join(selects, ", ")).
+		Joins("LEFT JOIN feeds ON feeds.id = llm_usages.feed_id").
+		Where("llm_usages.created_at >= ?", since)
+	if len(grouping) > 0 {
+		query = query.Group(strings.Join(grouping, ", ")).Order(strings.Join(grouping, ", "))
+	}
+
+	var rows []LLMUsageReport
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *repository) CreateItemFeedback(hash string, feedback *ItemFeedback) (bool, error) {
+	var item Item
+	if err := r.db.Select("id", "feed_id", "think_result", "think_rating").
+		Where("hash = ? AND think_result IS NOT NULL", hash).
+		Order("pub_date DESC").
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	feedback.ItemID = item.ID
+	feedback.FeedID = item.FeedID
+	feedback.LLMModel = item.ThinkResult.LLMModel
+	feedback.ThinkRating = item.ThinkRating
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Omit("Item").Create(feedback).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *repository) CountItemFeedbackSince(clientID string, since time.Time) (int64, error) {
+	var count int64
+	if err := r.db.Model(&ItemFeedback{}).Where("client_id = ? AND created_at >= ?", clientID, since).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *repository) CountItemFeedbackFromSourceSince(source string, since time.Time) (int64, error) {
+	var count int64
+	if err := r.db.Model(&ItemFeedback{}).Where("source = ? AND created_at >= ?", source, since).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *repository) GetFeedbackReport(since time.Time, groupBy string, limit int) ([]FeedbackReport, error) {
+	columns := map[string][]string{
+		"item":  {"item_feedback.item_id AS item_id", "MAX(items.url) AS item_url", "MAX(feeds.url) AS feed_url"},
+		"feed":  {"item_feedback.feed_id AS feed_id", "MAX(feeds.url) AS feed_url"},
+		"model": {"item_feedback.llm_model AS llm_model"},
+	}
+	groups := map[string]string{
+		"item":  "item_feedback.item_id",
+		"feed":  "item_feedback.feed_id",
+		"model": "item_feedback.llm_model",
+	}
+	if _, ok := columns[groupBy]; !ok {
+		return nil, fmt.Errorf("unknown feedback grouping %q (expected one of %s)", groupBy, strings.Join(FeedbackGroups, ", "))
+	}
+
+	selects := append(columns[groupBy],
+		"COUNT(DISTINCT item_feedback.item_id) AS items",
+		"COUNT(DISTINCT item_feedback.client_id) AS clients",
+		"COUNT(*) FILTER (WHERE item_feedback.kind = '"+FeedbackRatingTooHigh+"') AS rating_too_high",
+		"COUNT(*) FILTER (WHERE item_feedback.kind = '"+FeedbackRatingTooLow+"') AS rating_too_low",
+		"COUNT(*) FILTER (WHERE item_feedback.kind = '"+FeedbackTitleWrong+"') AS title_wrong",
+		"AVG(item_feedback.think_rating) AS avg_rating",
+	)
+
+	query := r.db.Table("item_feedback").
+		Select(strings.Join(selects, ", ")).
+		Joins("JOIN items ON items.id = item_feedback.item_id").
+		Joins("JOIN feeds ON feeds.id = item_feedback.feed_id").
+		Where("item_feedback.created_at >= ?", since).
+		Group(groups[groupBy]).
+		Order("COUNT(*) DESC, " + groups[groupBy])
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []FeedbackReport
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *repository) CountLockWaits() (int64, error) {
+	var waits int64
+	err := r.db.Raw(`SELECT COUNT(*) FROM pg_locks
+		JOIN pg_stat_activity ON pg_stat_activity.pid = pg_locks.pid
+		WHERE NOT pg_locks.granted AND pg_stat_activity.datname = current_database()`).Scan(&waits).Error
+	return waits, err
+}
+
+func (r *repository) Ping(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
+	defer cancel()
+	return r.db.WithContext(ctx).Exec("SELECT 1").Error
+}
+
+func (r *repository) GetSchemaStatus(timeout time.Duration) (*SchemaStatus, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
+	defer cancel()
+	db := r.db.WithContext(ctx)
+
+	status := SchemaStatus{MissingViews: []string{}}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		var migration SchemaMigration
+		if err := db.Where("id = ?", 1).Limit(1).Find(&migration).Error; err != nil {
+			return nil, err
+		}
+		status.Version = migration.Version
+	}
+
+	if err := db.Raw("SELECT name FROM unnest(?::text[]) AS name WHERE to_regclass(name) IS NULL",
+		StringArray(RequiredViews)).Scan(&status.MissingViews).Error; err != nil {
+		return nil, err
+	}
+
+	var duckDBVersion *string
+	if err := db.Raw("SELECT extversion FROM pg_extension WHERE extname = ?", "pg_duckdb").Scan(&duckDBVersion).Error; err != nil {
+		return nil, err
+	}
+	if duckDBVersion != nil {
+		status.DuckDBVersion = *duckDBVersion
+	}
+	return &status, nil
+}
+
+func (r *repository) GetThinkerQueueDepth(maxRetries int, maxFixerErrorCount int) (*ThinkerQueueDepth, error) {
+	var depth ThinkerQueueDepth
+	if err := r.db.
+		Select(
+			"COUNT(*) FILTER (WHERE NOT refused AND think_error_count <= ?) AS thinker, "+
+				"COUNT(*) FILTER (WHERE NOT refused AND think_error_count > ? AND think_error_count <= ?) AS fixer, "+
+				"COUNT(*) FILTER (WHERE NOT refused AND think_error_count > ?) AS dead, "+
+				"COUNT(*) FILTER (WHERE refused) AS refused",
+			maxRetries, maxRetries, maxFixerErrorCount, maxFixerErrorCount,
+		).
+		Table("(?) AS queue", r.db.Model(&Item{}).
+			Select("think_error_count, think_error_kind IS NOT DISTINCT FROM ? AS refused", ThinkErrorKindRefused).
+			Where("think_result IS NULL")).
+		Scan(&depth).Error; err != nil {
+		return nil, err
+	}
+	return &depth, nil
+}
+
+func (r *repository) CountThinkerUpdateCandidates(llmModel string, promptVersions map[string]string) (int64, int64, error) {
+	var llmModelCount, promptCount int64
+	if err := updateLLMModelCandidates(r.db, llmModel).Count(&llmModelCount).Error; err != nil {
+		return 0, 0, err
+	}
+	if len(promptVersions) > 0 {
+		if err := updatePromptCandidates(r.db, promptVersions).Count(&promptCount).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	return llmModelCount, promptCount, nil
+}
+
+func deadItemsQuery(db *gorm.DB, filter DeadItemFilter) *gorm.DB {
+	query := db.Model(&Item{}).
+		Where("items.think_result IS NULL").
+		Where("(items.think_error_count >= ? OR items.think_error_kind = ?)", filter.MinErrorCount, ThinkErrorKindRefused)
+	if len(filter.IDs) > 0 {
+		query = query.Where("items.id IN ?", filter.IDs)
+	}
+	if filter.FeedID != nil {
+		query = query.Where("items.feed_id = ?", *filter.FeedID)
+	}
+	if filter.ErrorContains != "" {
+		query = query.Where("STRPOS(LOWER(items.think_error), LOWER(?)) > 0", filter.ErrorContains)
+	}
+	return query
+}
+
+func (r *repository) GetDeadItemGroups(filter DeadItemFilter) ([]DeadItemGroup, error) {
+	var groups []DeadItemGroup
+	if err := deadItemsQuery(r.db, filter).
+		Select("items.feed_id AS feed_id, feeds.url AS feed_url, COALESCE(items.think_error_kind, '') AS kind, " +
+			"COALESCE(items.think_error, '') AS think_error, " +
+			"COUNT(*) AS items, MIN(items.created_at) AS oldest, MAX(items.created_at) AS newest").
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Group("items.feed_id, feeds.url, COALESCE(items.think_error_kind, ''), COALESCE(items.think_error, '')").
+		Order("items DESC, feeds.url ASC").
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *repository) FindDeadItems(filter DeadItemFilter, limit int) ([]Item, error) {
+	var items []Item
+	query := deadItemsQuery(r.db, filter).
+		Preload("Feed", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("items.created_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *repository) RequeueDeadItems(filter DeadItemFilter) (int64, error) {
+	res := deadItemsQuery(r.db, filter).
+		Updates(map[string]interface{}{
+			"think_error":       nil,
+			"think_error_count": 0,
+			"think_error_kind":  nil,
+			"updated_at":        gorm.Expr("NOW()"),
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (r *repository) PurgeDeadItems(filter DeadItemFilter) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		if err := deadItemsQuery(tx, filter).Pluck("items.id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Where("item_id IN ?", ids).Delete(&Trend{}).Error; err != nil {
+			return fmt.Errorf("failed to delete trends: %w", err)
+		}
+		res := tx.Where("id IN ?", ids).Delete(&Item{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to delete items: %w", res.Error)
+		}
+		deleted = res.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
+
 func (r *repository) FindFeedScheduleById(feedID uuid.UUID) (*FeedSchedule, error) {
 	var schedule FeedSchedule
 	if err := r.db.Where("id = ?", feedID).First(&schedule).Error; err != nil {
@@ -1149,58 +2211,139 @@ func (r *repository) FindItemsByRootDomain(rootDomain string, limit int) ([]Item
 	return items, nil
 }
 
-func (r *repository) FindAnalyzedItemsByRootDomain(rootDomain string, limit int) ([]AnalyzedItem, error) {
+// siteScoreColumns are the scores as served, the reviewer's value first.
+var siteScoreColumns = siteScoreColumnsFor("items", "item_reviews")
+
+// siteScoreColumnsFor returns siteScoreColumns for other aliases of the items
+// and item_reviews tables.
+func siteScoreColumnsFor(items, reviews string) map[string]string {
+	return map[string]string{
+		"framing":        fmt.Sprintf("COALESCE(%[2]s.framing, (%[1]s.think_result->>'framing')::float8)", items, reviews),
+		"clickbait":      fmt.Sprintf("COALESCE(%[2]s.clickbait, (%[1]s.think_result->>'clickbait')::float8)", items, reviews),
+		"persuasive":     fmt.Sprintf("COALESCE(%[2]s.persuasive, (%[1]s.think_result->>'persuasive')::float8)", items, reviews),
+		"hyper_stimulus": fmt.Sprintf("COALESCE(%[2]s.hyper_stimulus, (%[1]s.think_result->>'hyper_stimulus')::float8)", items, reviews),
+		"speculative":    fmt.Sprintf("COALESCE(%[2]s.speculative, (%[1]s.think_result->>'speculative')::float8)", items, reviews),
+		"overall":        fmt.Sprintf("COALESCE(%[2]s.overall, %[1]s.think_rating)", items, reviews),
+	}
+}
+
+// siteListingCondition returns the WHERE condition of the site listing for
+// the given aliases of the items, feeds and item_reviews tables.
+func siteListingCondition(rootDomain string, filter SiteFilter, items, feeds, reviews string) (string, []any, error) {
+	conditions := []string{
+		feeds + ".root_domain = ? AND " + feeds + ".enabled = ? AND " + feeds + ".deleted_at IS NULL",
+		items + ".think_result IS NOT NULL AND " + items + ".think_error IS NULL AND " + items + ".think_error_count = 0",
+	}
+	args := []any{rootDomain, true}
+
+	columns := siteScoreColumnsFor(items, reviews)
+	for dim, value := range filter.MinScores {
+		column, ok := columns[dim]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown score dimension %q", dim)
+		}
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, value)
+	}
+	for dim, value := range filter.MaxScores {
+		column, ok := columns[dim]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown score dimension %q", dim)
+		}
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, value)
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "COALESCE("+reviews+".category, "+items+".think_result->>'category') = ?")
+		args = append(args, filter.Category)
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM unnest("+items+".authors) AS author WHERE lower(author) = lower(?))")
+		args = append(args, filter.Author)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, items+".pub_date >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, items+".pub_date < ?")
+		args = append(args, *filter.To)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// FindAnalyzedItemsByRootDomain lists the newest matching copy of every URL.
+// Instead of de-duplicating the whole domain per page, a row is skipped when a
+// newer matching copy of its URL exists, so the keyset cursor walks
+// idx_items_feed_pub_date and every page costs the same.
+func (r *repository) FindAnalyzedItemsByRootDomain(rootDomain string, filter SiteFilter, limit int) ([]AnalyzedItem, error) {
 	var items []AnalyzedItem
-	subQuery := r.db.Model(&Item{}).
-		Select("DISTINCT ON (items.url) items.*, NULLIF(items.think_result->>'llm_model', '') AS llm_model, feeds.tags AS tags").
-		Joins("JOIN feeds ON feeds.id = items.feed_id").
-		Where("feeds.root_domain = ? AND feeds.enabled = ? AND feeds.deleted_at IS NULL", rootDomain, true).
-		Where("items.think_result IS NOT NULL AND items.think_error IS NULL AND items.think_error_count = 0").
-		Order("items.url, items.pub_date DESC")
+	condition, args, err := siteListingCondition(rootDomain, filter, "items", "feeds", "item_reviews")
+	if err != nil {
+		return nil, err
+	}
+	newerCondition, newerArgs, err := siteListingCondition(rootDomain, filter, "newer", "newer_feeds", "newer_reviews")
+	if err != nil {
+		return nil, err
+	}
 
-	if err := r.db.Model(&Item{}).
-		Table("(?) as unique_items", subQuery).
-		Select("unique_items.*").
-		Order("unique_items.pub_date DESC").
+	query := r.db.Model(&Item{}).
+		Select("items.*, NULLIF(items.think_result->>'llm_model', '') AS llm_model, feeds.tags AS tags").
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Joins("LEFT JOIN item_reviews ON item_reviews.item_id = items.id").
+		Where(condition, args...)
+	if filter.After != nil {
+		query = query.Where("(items.pub_date, items.id) < (?, ?)", filter.After.PubDate, filter.After.ID)
+	}
+	query = query.Where("NOT EXISTS (SELECT 1 FROM items AS newer"+
+		" JOIN feeds AS newer_feeds ON newer_feeds.id = newer.feed_id"+
+		" LEFT JOIN item_reviews AS newer_reviews ON newer_reviews.item_id = newer.id"+
+		" WHERE newer.url = items.url AND (newer.pub_date, newer.id) > (items.pub_date, items.id) AND "+newerCondition+")", newerArgs...)
+	if err := query.
+		Order("items.pub_date DESC, items.id DESC").
 		Limit(limit).
 		Find(&items).Error; err != nil {
 		return nil, err
 	}
+	if err := r.applyItemReviews(items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
+// analyzedItemColumns selects an item with its trend sentiments as AnalyzedItem.
+const analyzedItemColumns = `items.*,
+	NULLIF(items.think_result->>'llm_model', '') AS llm_model,
+	CASE WHEN COALESCE(feeds.tags, '{}'::text[]) && supported_tags.supported_tags THEN supported_tags.supported_tags ELSE '{}'::text[] END AS tags,
+	CASE WHEN trends.sentiments IS NOT NULL AND trends.sentiments <> '{}'::jsonb THEN
+		jsonb_build_object(
+			'valence', (trends.sentiments->>'v')::double precision,
+			'arousal', (trends.sentiments->>'a')::double precision,
+			'dominance', (trends.sentiments->>'d')::double precision,
+			'joy', (trends.sentiments->>'j')::double precision,
+			'anger', (trends.sentiments->>'a_n')::double precision,
+			'sadness', (trends.sentiments->>'s')::double precision,
+			'fear', (trends.sentiments->>'f')::double precision,
+			'disgust', (trends.sentiments->>'d_g')::double precision
+		)
+	ELSE NULL END as sentiments,
+	CASE WHEN trends.sentiments_deframed IS NOT NULL AND trends.sentiments_deframed <> '{}'::jsonb THEN
+		jsonb_build_object(
+			'valence', (trends.sentiments_deframed->>'v')::double precision,
+			'arousal', (trends.sentiments_deframed->>'a')::double precision,
+			'dominance', (trends.sentiments_deframed->>'d')::double precision,
+			'joy', (trends.sentiments_deframed->>'j')::double precision,
+			'anger', (trends.sentiments_deframed->>'a_n')::double precision,
+			'sadness', (trends.sentiments_deframed->>'s')::double precision,
+			'fear', (trends.sentiments_deframed->>'f')::double precision,
+			'disgust', (trends.sentiments_deframed->>'d_g')::double precision
+		)
+	ELSE NULL END as sentiments_deframed`
+
 func (r *repository) FindFirstAnalyzedItemByUrl(u *url.URL) (*AnalyzedItem, error) {
 	var item AnalyzedItem
-	query := `items.*, 
-		NULLIF(items.think_result->>'llm_model', '') AS llm_model,
-		CASE WHEN COALESCE(feeds.tags, '{}'::text[]) && supported_tags.supported_tags THEN supported_tags.supported_tags ELSE '{}'::text[] END AS tags,
-		CASE WHEN trends.sentiments IS NOT NULL AND trends.sentiments <> '{}'::jsonb THEN
-			jsonb_build_object(
-				'valence', (trends.sentiments->>'v')::double precision,
-				'arousal', (trends.sentiments->>'a')::double precision,
-				'dominance', (trends.sentiments->>'d')::double precision,
-				'joy', (trends.sentiments->>'j')::double precision,
-				'anger', (trends.sentiments->>'a_n')::double precision,
-				'sadness', (trends.sentiments->>'s')::double precision,
-				'fear', (trends.sentiments->>'f')::double precision,
-				'disgust', (trends.sentiments->>'d_g')::double precision
-			)
-		ELSE NULL END as sentiments,
-		CASE WHEN trends.sentiments_deframed IS NOT NULL AND trends.sentiments_deframed <> '{}'::jsonb THEN
-			jsonb_build_object(
-				'valence', (trends.sentiments_deframed->>'v')::double precision,
-				'arousal', (trends.sentiments_deframed->>'a')::double precision,
-				'dominance', (trends.sentiments_deframed->>'d')::double precision,
-				'joy', (trends.sentiments_deframed->>'j')::double precision,
-				'anger', (trends.sentiments_deframed->>'a_n')::double precision,
-				'sadness', (trends.sentiments_deframed->>'s')::double precision,
-				'fear', (trends.sentiments_deframed->>'f')::double precision,
-				'disgust', (trends.sentiments_deframed->>'d_g')::double precision
-			)
-		ELSE NULL END as sentiments_deframed`
-
 	if err := r.db.Model(&Item{}).
-		Select(query).
+		Select(analyzedItemColumns).
 		Joins("JOIN feeds ON feeds.id = items.feed_id").
 		Joins("LEFT JOIN trends ON trends.item_id = items.id").
 		Joins("CROSS JOIN (SELECT ?::text[] AS supported_tags) AS supported_tags", SupportedUserTags).
@@ -1212,7 +2355,381 @@ func (r *repository) FindFirstAnalyzedItemByUrl(u *url.URL) (*AnalyzedItem, erro
 		}
 		return nil, err
 	}
-	return &item, nil
+	items := []AnalyzedItem{item}
+	if err := r.applyItemReviews(items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+func (r *repository) FindFirstAnalyzedItemsByUrls(urls []string) (map[string]AnalyzedItem, error) {
+	result := make(map[string]AnalyzedItem)
+	if len(urls) == 0 {
+		return result, nil
+	}
+	var items []AnalyzedItem
+	// DISTINCT ON keeps the newest item per URL, like FindFirstAnalyzedItemByUrl
+	if err := r.db.Model(&Item{}).
+		Select("DISTINCT ON (items.url) "+analyzedItemColumns).
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Joins("LEFT JOIN trends ON trends.item_id = items.id").
+		Joins("CROSS JOIN (SELECT ?::text[] AS supported_tags) AS supported_tags", SupportedUserTags).
+		Where("items.url IN ? AND feeds.enabled = ? AND feeds.deleted_at IS NULL", urls, true).
+		Order("items.url, items.pub_date DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if err := r.applyItemReviews(items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		result[item.URL] = item
+	}
+	return result, nil
+}
+
+func (r *repository) SearchItems(search ItemSearch) ([]SearchHit, error) {
+	limit := search.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	// with a language the query is a constant and the GIN index applies;
+	// without one every item is matched in the configuration of its language
+	tsquery := "websearch_to_tsquery(deframer_search_config(items.language), ?)"
+	queryArgs := []any{search.Query}
+	if search.Language != "" {
+		tsquery = "websearch_to_tsquery(deframer_search_config(?), ?)"
+		queryArgs = []any{search.Language, search.Query}
+	}
+
+	matches := r.db.Model(&Item{}).
+		Select("items.id, items.pub_date, ts_rank_cd(items.search_vector, "+tsquery+") AS rank", queryArgs...).
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Joins("LEFT JOIN item_reviews ON item_reviews.item_id = items.id").
+		Where("feeds.enabled = ? AND feeds.deleted_at IS NULL", true).
+		Where("items.think_result IS NOT NULL").
+		Where("items.search_vector @@ "+tsquery, queryArgs...)
+	if search.Language != "" {
+		matches = matches.Where("items.language = ?", search.Language)
+	}
+	if search.Domain != "" {
+		matches = matches.Where("feeds.root_domain = ?", search.Domain)
+	}
+	if search.From != nil {
+		matches = matches.Where("items.pub_date >= ?", *search.From)
+	}
+	if search.To != nil {
+		matches = matches.Where("items.pub_date < ?", *search.To)
+	}
+	// filter on the values as served, the reviewer's first
+	if search.MaxScore > 0 {
+		matches = matches.Where(siteScoreColumns["overall"]+" <= ?", search.MaxScore)
+	}
+	if search.Category != "" {
+		matches = matches.Where("COALESCE(item_reviews.category, items.think_result->>'category') = ?", search.Category)
+	}
+
+	page := r.db.Table("(?) AS matches", matches).Select("matches.*")
+	if search.After != nil {
+		page = page.Where("(matches.rank, matches.pub_date, matches.id) < (?::real, ?, ?)", search.After.Rank, search.After.PubDate, search.After.ID)
+	}
+	page = page.Order("matches.rank DESC, matches.pub_date DESC, matches.id DESC").Limit(limit)
+
+	headline := "ts_headline(deframer_search_config(items.language), COALESCE(NULLIF(items.think_result->>'%s', ''), items.think_result->>'%s', ''), " +
+		"websearch_to_tsquery(deframer_search_config(items.language), ?), '%s') AS %s"
+	var hits []SearchHit
+	if err := r.db.Table("(?) AS hits", page).
+		Select(analyzedItemColumns+", hits.rank, "+
+			fmt.Sprintf(headline, "title_corrected", "title_original", "StartSel=<mark>, StopSel=</mark>, HighlightAll=true", "title_highlight")+", "+
+			fmt.Sprintf(headline, "description_corrected", "description_original", "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10", "description_highlight"),
+			search.Query, search.Query).
+		Joins("JOIN items ON items.id = hits.id").
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Joins("LEFT JOIN trends ON trends.item_id = items.id").
+		Joins("CROSS JOIN (SELECT ?::text[] AS supported_tags) AS supported_tags", SupportedUserTags).
+		Order("hits.rank DESC, hits.pub_date DESC, hits.id DESC").
+		Find(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]AnalyzedItem, len(hits))
+	for i := range hits {
+		items[i] = hits[i].Item
+	}
+	if err := r.applyItemReviews(items); err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Item = items[i]
+	}
+	return hits, nil
+}
+
+func (r *repository) FindItemEventsAfter(after int64, filter ItemEventFilter, limit int) ([]ItemEvent, error) {
+	var events []ItemEvent
+	query := r.db.Table("think_results").
+		Select("think_results.event_id, feeds.root_domain, "+analyzedItemColumns).
+		Joins("JOIN items ON items.think_result_id = think_results.id").
+		Joins("JOIN feeds ON feeds.id = items.feed_id").
+		Joins("LEFT JOIN trends ON trends.item_id = items.id").
+		Joins("LEFT JOIN item_reviews ON item_reviews.item_id = items.id").
+		Joins("CROSS JOIN (SELECT ?::text[] AS supported_tags) AS supported_tags", SupportedUserTags).
+		Where("think_results.event_id > ?", after).
+		Where("feeds.enabled = ? AND feeds.deleted_at IS NULL", true)
+	if filter.RootDomain != "" {
+		query = query.Where("feeds.root_domain = ?", filter.RootDomain)
+	}
+	if filter.MaxScore > 0 {
+		query = query.Where(siteScoreColumns["overall"]+" <= ?", filter.MaxScore)
+	}
+	if err := query.Order("think_results.event_id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]AnalyzedItem, len(events))
+	for i := range events {
+		items[i] = events[i].Item
+	}
+	if err := r.applyItemReviews(items); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Item = items[i]
+	}
+	return events, nil
+}
+
+func (r *repository) LatestItemEventID() (int64, error) {
+	var id int64
+	if err := r.db.Table("think_results").Select("COALESCE(MAX(event_id), 0)").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *repository) CreateWebhook(webhook *Webhook) error {
+	if webhook.Events == nil {
+		webhook.Events = StringArray{}
+	}
+	if webhook.Domains == nil {
+		webhook.Domains = StringArray{}
+	}
+	if webhook.Categories == nil {
+		webhook.Categories = StringArray{}
+	}
+	return r.db.Create(webhook).Error
+}
+
+func (r *repository) ListWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := r.db.Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *repository) SetWebhookEnabled(id uuid.UUID, enabled bool) (bool, error) {
+	result := r.db.Model(&Webhook{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"enabled":    enabled,
+		"updated_at": gorm.Expr("NOW()"),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) DeleteWebhook(id uuid.UUID) (bool, error) {
+	result := r.db.Delete(&Webhook{}, "id = ?", id)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) EnqueueWebhookEvent(event WebhookEvent) error {
+	return enqueueWebhookEvent(r.db, event)
+}
+
+// enqueueWebhookEvent runs inside the transaction that caused the event, so an
+// event is queued if and only if it happened.
+func enqueueWebhookEvent(tx *gorm.DB, event WebhookEvent) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	return tx.Exec(`INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, event, event_key, payload, next_attempt_at)
+		SELECT uuid_generate_v4(), NOW(), NOW(), webhooks.id, @type, @key, CAST(@payload AS jsonb), NOW()
+		FROM webhooks
+		WHERE webhooks.enabled AND webhooks.deleted_at IS NULL
+			AND (cardinality(webhooks.events) = 0 OR @type = ANY(webhooks.events))
+			AND (cardinality(webhooks.domains) = 0 OR @domain = '' OR @domain = ANY(webhooks.domains))
+			AND (cardinality(webhooks.categories) = 0 OR @category = '' OR @category = ANY(webhooks.categories))
+			AND (webhooks.min_score = 0 OR CAST(@score AS float8) IS NULL OR CAST(@score AS float8) >= webhooks.min_score)
+			AND (webhooks.max_score = 0 OR CAST(@score AS float8) IS NULL OR CAST(@score AS float8) <= webhooks.max_score)
+		ON CONFLICT DO NOTHING`,
+		sql.Named("type", event.Type),
+		sql.Named("key", event.Key),
+		sql.Named("payload", string(payload)),
+		sql.Named("domain", event.RootDomain),
+		sql.Named("category", event.Category),
+		sql.Named("score", event.Score),
+	).Error
+}
+
+func (r *repository) BeginWebhookDeliveries(limit int, lockDuration time.Duration) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	if limit <= 0 {
+		return deliveries, nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&WebhookDelivery{}).
+			Select("webhook_deliveries.*").
+			Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+			Where("webhooks.enabled AND webhooks.deleted_at IS NULL").
+			Where("webhook_deliveries.delivered_at IS NULL AND webhook_deliveries.failed_at IS NULL").
+			Where("webhook_deliveries.next_attempt_at <= NOW()").
+			Order("webhook_deliveries.next_attempt_at ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Preload("Webhook").
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		// a crashed worker's deliveries are due again after the lock
+		return tx.Model(&WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lockDuration)).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *repository) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	return r.db.Model(&WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+		"failed_at":       delivery.FailedAt,
+		"last_status":     delivery.LastStatus,
+		"last_error":      delivery.LastError,
+		"updated_at":      gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *repository) ListWebhookDeliveries(filter WebhookDeliveryFilter) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	query := r.db.Preload("Webhook", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("created_at DESC")
+	if filter.WebhookID != nil {
+		query = query.Where("webhook_id = ?", *filter.WebhookID)
+	}
+	if filter.FailedOnly {
+		query = query.Where("failed_at IS NOT NULL")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *repository) RedeliverWebhookDelivery(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        0,
+		"next_attempt_at": gorm.Expr("NOW()"),
+		"delivered_at":    nil,
+		"failed_at":       nil,
+		"updated_at":      gorm.Expr("NOW()"),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) FindTrendBursts(since time.Time, minRatio float64, minFrequency int) ([]TrendBurst, error) {
+	var bursts []TrendBurst
+	if err := r.db.Raw(trendBurstsQuery,
+		sql.Named("since", since),
+		sql.Named("min_ratio", minRatio),
+		sql.Named("min_frequency", minFrequency),
+	).Scan(&bursts).Error; err != nil {
+		return nil, err
+	}
+	return bursts, nil
+}
+
+func (r *repository) CreateAPIKey(key *APIKey) error {
+	if key.Scopes == nil {
+		key.Scopes = StringArray{}
+	}
+	return r.db.Create(key).Error
+}
+
+func (r *repository) ListAPIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	if err := r.db.Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *repository) FindAPIKey(keyID string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.Where("key_id = ?", keyID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) RevokeAPIKey(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Updates(map[string]interface{}{
+		"revoked_at": gorm.Expr("NOW()"),
+		"updated_at": gorm.Expr("NOW()"),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) RecordAPIKeyRequest(id uuid.UUID) (int64, error) {
+	var requests int64
+	// counted in the database, so the rate limit holds across service replicas
+	err := r.db.Raw(`WITH touched AS (
+			UPDATE api_keys SET last_used_at = NOW() WHERE id = @id
+		)
+		INSERT INTO api_key_usages (api_key_id, hour, requests)
+		VALUES (@id, date_trunc('hour', NOW()), 1)
+		ON CONFLICT (api_key_id, hour) DO UPDATE SET requests = api_key_usages.requests + 1
+		RETURNING requests`, sql.Named("id", id)).Scan(&requests).Error
+	return requests, err
+}
+
+func (r *repository) GetAPIKeyUsage(since time.Time) ([]APIKeyUsageReport, error) {
+	var rows []APIKeyUsageReport
+	err := r.db.Table("api_keys").
+		Select("api_keys.id, api_keys.key_id, api_keys.name, COALESCE(SUM(api_key_usages.requests), 0) AS requests, api_keys.revoked_at, api_keys.last_used_at").
+		Joins("LEFT JOIN api_key_usages ON api_key_usages.api_key_id = api_keys.id AND api_key_usages.hour >= ?", since).
+		Where("api_keys.deleted_at IS NULL").
+		Group("api_keys.id").
+		Order("requests DESC, api_keys.created_at").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
 }
 
 func (r *repository) GetArticlesByTrend(term string, domain string, date *time.Time, days int, offset int, limit int) ([]AnalyzedArticle, error) {
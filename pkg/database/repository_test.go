@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
 	"strings"
 	"testing"
@@ -798,6 +799,466 @@ func TestFindItemsByUrl(t *testing.T) {
 	})
 }
 
+func TestFindFirstAnalyzedItemsByUrls(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	feed := Feed{URL: "http://items-lookup.test/rss-" + uid, Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	feed2 := Feed{URL: "http://items-lookup.test/rss2-" + uid, Enabled: true}
+	assert.NoError(t, tx.Create(&feed2).Error)
+
+	// the newest copy of a syndicated article wins
+	articleURL := "http://items-lookup.test/article-" + uid
+	for _, item := range []Item{
+		{Hash: "lookup-older", FeedID: feed.ID, URL: articleURL, ThinkResult: &ThinkResult{TitleCorrected: "Older"}, PubDate: time.Now().Add(-time.Hour)},
+		{Hash: "lookup-latest", FeedID: feed2.ID, URL: articleURL, ThinkResult: &ThinkResult{TitleCorrected: "Latest"}, PubDate: time.Now()},
+		{Hash: "lookup-other", FeedID: feed.ID, URL: articleURL + "?v=2", ThinkResult: &ThinkResult{TitleCorrected: "Other"}, PubDate: time.Now()},
+	} {
+		assert.NoError(t, tx.Create(&item).Error)
+	}
+
+	items, err := repo.FindFirstAnalyzedItemsByUrls([]string{articleURL, articleURL + "?v=2", "http://items-lookup.test/missing"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Latest", items[articleURL].ThinkResult.TitleCorrected)
+	assert.Equal(t, "Other", items[articleURL+"?v=2"].ThinkResult.TitleCorrected)
+
+	items, err = repo.FindFirstAnalyzedItemsByUrls(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestFindAnalyzedItemsByRootDomain(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	domain := "site-" + uid + ".test"
+	feed := Feed{URL: "http://" + domain + "/rss", RootDomain: &domain, Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	items := []Item{
+		{Hash: "site-1-" + uid, URL: "http://" + domain + "/1", PubDate: now, ThinkRating: 0.2, Authors: StringArray{"Jane Doe"},
+			ThinkResult: &ThinkResult{Framing: 0.1, Overall: 0.2, Category: "politics"}},
+		{Hash: "site-2-" + uid, URL: "http://" + domain + "/2", PubDate: now.Add(-time.Hour), ThinkRating: 0.7,
+			ThinkResult: &ThinkResult{Framing: 0.8, Overall: 0.7, Category: "politics"}},
+		{Hash: "site-3-" + uid, URL: "http://" + domain + "/3", PubDate: now.AddDate(0, 0, -3), ThinkRating: 0.4,
+			ThinkResult: &ThinkResult{Framing: 0.3, Overall: 0.4, Category: "sports"}},
+	}
+	for i := range items {
+		items[i].FeedID = feed.ID
+		assert.NoError(t, tx.Create(&items[i]).Error)
+	}
+	// the reviewer lowered the framing of the second item
+	framing := 0.2
+	assert.NoError(t, repo.UpsertItemReview(&ItemReview{ItemID: items[1].ID, Framing: &framing}))
+
+	hashes := func(items []AnalyzedItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Hash)
+		}
+		return result
+	}
+
+	got, err := repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-1-" + uid, "site-2-" + uid, "site-3-" + uid}, hashes(got))
+
+	got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{Category: "politics", MaxScores: map[string]float64{"framing": 0.5}}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-1-" + uid, "site-2-" + uid}, hashes(got))
+
+	got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{MinScores: map[string]float64{"overall": 0.3}}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-2-" + uid, "site-3-" + uid}, hashes(got))
+
+	got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{Author: "jane doe"}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"site-1-" + uid}, hashes(got))
+
+	from := now.AddDate(0, 0, -1)
+	got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{From: &from}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	// the cursor continues after the last item of a page
+	got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		after := SiteCursor{PubDate: got[1].PubDate, ID: got[1].ID}
+		got, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{After: &after}, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"site-3-" + uid}, hashes(got))
+	}
+
+	_, err = repo.FindAnalyzedItemsByRootDomain(domain, SiteFilter{MinScores: map[string]float64{"nonsense": 1}}, 10)
+	assert.Error(t, err)
+}
+
+func TestFindAnalyzedItemsByRootDomainPagesAcrossDuplicates(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	domain := "site-dup-" + uid + ".test"
+	feedA := Feed{URL: "http://" + domain + "/a.rss", RootDomain: &domain, Enabled: true}
+	feedB := Feed{URL: "http://" + domain + "/b.rss", RootDomain: &domain, Enabled: true}
+	assert.NoError(t, tx.Create(&feedA).Error)
+	assert.NoError(t, tx.Create(&feedB).Error)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	item := func(feed Feed, name string, path string, age time.Duration, category string) Item {
+		return Item{Hash: name + "-" + uid, FeedID: feed.ID, URL: "http://" + domain + path, PubDate: now.Add(-age),
+			ThinkResult: &ThinkResult{Category: category}}
+	}
+	items := []Item{
+		item(feedA, "a1", "/1", 0, "politics"),
+		item(feedA, "a2", "/2", time.Hour, "politics"),
+		item(feedA, "a3", "/3", 2*time.Hour, "politics"),
+		item(feedB, "b1", "/1", 3*time.Hour, "politics"),  // older copy of /1
+		item(feedB, "b2", "/2", 30*time.Minute, "sports"), // newer copy of /2
+		item(feedB, "b4", "/4", 4*time.Hour, "politics"),
+	}
+	for i := range items {
+		assert.NoError(t, tx.Create(&items[i]).Error)
+	}
+
+	pages := func(filter SiteFilter) []string {
+		var result []string
+		for range items {
+			got, err := repo.FindAnalyzedItemsByRootDomain(domain, filter, 1)
+			assert.NoError(t, err)
+			if len(got) == 0 {
+				break
+			}
+			result = append(result, strings.TrimSuffix(got[0].Hash, "-"+uid))
+			filter.After = &SiteCursor{PubDate: got[0].PubDate, ID: got[0].ID}
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"a1", "b2", "a3", "b4"}, pages(SiteFilter{}))
+	// the newest copy is picked among the matching ones
+	assert.Equal(t, []string{"a1", "a2", "a3", "b4"}, pages(SiteFilter{Category: "politics"}))
+}
+
+func TestFindItemEventsAfter(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	domain := "events-" + uid + ".test"
+	feed := Feed{URL: "http://" + domain + "/rss", RootDomain: &domain, Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	before, err := repo.LatestItemEventID()
+	assert.NoError(t, err)
+
+	calm := Item{Hash: "events-calm-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/1", PubDate: time.Now(), ThinkRating: 0.2,
+		ThinkResult: &ThinkResult{TitleCorrected: "Calm", Overall: 0.2}}
+	loud := Item{Hash: "events-loud-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/2", PubDate: time.Now(), ThinkRating: 0.9,
+		ThinkResult: &ThinkResult{TitleCorrected: "Loud", Overall: 0.9}}
+	pending := Item{Hash: "events-pending-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/3", PubDate: time.Now()}
+	for _, item := range []*Item{&calm, &loud, &pending} {
+		assert.NoError(t, repo.UpsertItemWithTrendInvalidation(item))
+	}
+
+	events, err := repo.FindItemEventsAfter(before, ItemEventFilter{RootDomain: domain}, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "events-calm-"+uid, events[0].Item.Hash)
+		assert.Equal(t, "events-loud-"+uid, events[1].Item.Hash)
+		assert.Less(t, events[0].EventID, events[1].EventID)
+		assert.Equal(t, domain, events[1].RootDomain)
+
+		latest, err := repo.LatestItemEventID()
+		assert.NoError(t, err)
+		assert.Equal(t, events[1].EventID, latest)
+
+		// resuming after the first event skips it
+		resumed, err := repo.FindItemEventsAfter(events[0].EventID, ItemEventFilter{RootDomain: domain}, 10)
+		assert.NoError(t, err)
+		assert.Len(t, resumed, 1)
+	}
+
+	events, err = repo.FindItemEventsAfter(before, ItemEventFilter{RootDomain: domain, MaxScore: 0.5}, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "events-calm-"+uid, events[0].Item.Hash)
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	domain := "webhooks-" + uid + ".test"
+	feed := Feed{URL: "http://" + domain + "/rss", RootDomain: &domain, Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	all := Webhook{URL: "http://hooks.test/all", Secret: "a", Enabled: true}
+	calm := Webhook{URL: "http://hooks.test/calm", Secret: "b", Enabled: true,
+		Events: StringArray{WebhookEventItemAnalyzed}, Domains: StringArray{domain}, MaxScore: 0.5}
+	other := Webhook{URL: "http://hooks.test/other", Secret: "c", Enabled: true, Domains: StringArray{"other-" + domain}}
+	for _, webhook := range []*Webhook{&all, &calm, &other} {
+		assert.NoError(t, repo.CreateWebhook(webhook))
+	}
+
+	for _, item := range []*Item{
+		{Hash: "webhooks-calm-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/1", PubDate: time.Now(), ThinkRating: 0.2,
+			ThinkResult: &ThinkResult{TitleCorrected: "Calm", Category: "politics"}},
+		{Hash: "webhooks-loud-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/2", PubDate: time.Now(), ThinkRating: 0.9,
+			ThinkResult: &ThinkResult{TitleCorrected: "Loud", Category: "politics"}},
+	} {
+		assert.NoError(t, repo.UpsertItemWithTrendInvalidation(item))
+	}
+	// the same event is queued once per webhook
+	assert.NoError(t, repo.EnqueueWebhookEvent(WebhookEvent{Type: WebhookEventFeedFailed, Key: "failed-" + uid, RootDomain: domain, Data: map[string]string{"error": "timeout"}}))
+	assert.NoError(t, repo.EnqueueWebhookEvent(WebhookEvent{Type: WebhookEventFeedFailed, Key: "failed-" + uid, RootDomain: domain, Data: map[string]string{"error": "timeout"}}))
+
+	count := func(webhook Webhook) int {
+		deliveries, err := repo.ListWebhookDeliveries(WebhookDeliveryFilter{WebhookID: &webhook.ID})
+		assert.NoError(t, err)
+		return len(deliveries)
+	}
+	assert.Equal(t, 3, count(all))
+	assert.Equal(t, 1, count(calm))
+	assert.Equal(t, 0, count(other))
+
+	deliveries, err := repo.ListWebhookDeliveries(WebhookDeliveryFilter{WebhookID: &calm.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Contains(t, deliveries[0].Payload, "webhooks-calm-"+uid)
+		assert.Contains(t, deliveries[0].Payload, domain)
+	}
+
+	// disabled webhooks are neither queued nor delivered
+	found, err := repo.SetWebhookEnabled(all.ID, false)
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	claimed, err := repo.BeginWebhookDeliveries(100, time.Minute)
+	assert.NoError(t, err)
+	var mine []WebhookDelivery
+	for _, delivery := range claimed {
+		if delivery.WebhookID == calm.ID {
+			mine = append(mine, delivery)
+		}
+		assert.NotEqual(t, all.ID, delivery.WebhookID)
+	}
+	if assert.Len(t, mine, 1) {
+		assert.Equal(t, calm.URL, mine[0].Webhook.URL)
+
+		// claimed deliveries are not due again until the lock expires
+		again, err := repo.BeginWebhookDeliveries(100, time.Minute)
+		assert.NoError(t, err)
+		for _, delivery := range again {
+			assert.NotEqual(t, mine[0].ID, delivery.ID)
+		}
+
+		now := time.Now()
+		mine[0].Attempts = 8
+		mine[0].FailedAt = &now
+		assert.NoError(t, repo.UpdateWebhookDelivery(&mine[0]))
+
+		failed, err := repo.ListWebhookDeliveries(WebhookDeliveryFilter{WebhookID: &calm.ID, FailedOnly: true})
+		assert.NoError(t, err)
+		assert.Len(t, failed, 1)
+
+		found, err := repo.RedeliverWebhookDelivery(mine[0].ID)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		failed, err = repo.ListWebhookDeliveries(WebhookDeliveryFilter{WebhookID: &calm.ID, FailedOnly: true})
+		assert.NoError(t, err)
+		assert.Empty(t, failed)
+	}
+
+	found, err = repo.DeleteWebhook(other.ID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	webhooks, err := repo.ListWebhooks()
+	assert.NoError(t, err)
+	for _, webhook := range webhooks {
+		assert.NotEqual(t, other.ID, webhook.ID)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	key, secret, err := GenerateAPIKey("test "+uuid.New().String(), []string{ScopeItemsRead}, 10)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.CreateAPIKey(key))
+
+	found, err := repo.FindAPIKey(key.KeyID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) {
+		assert.Equal(t, HashAPIKeySecret(secret), found.SecretHash)
+		assert.Equal(t, StringArray{ScopeItemsRead}, found.Scopes)
+	}
+	missing, err := repo.FindAPIKey(APIKeyPrefix + "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	for i := int64(1); i <= 3; i++ {
+		requests, err := repo.RecordAPIKeyRequest(key.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, i, requests)
+	}
+
+	usage, err := repo.GetAPIKeyUsage(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	var row *APIKeyUsageReport
+	for i := range usage {
+		if usage[i].ID == key.ID {
+			row = &usage[i]
+		}
+	}
+	if assert.NotNil(t, row) {
+		assert.Equal(t, int64(3), row.Requests)
+		assert.NotNil(t, row.LastUsedAt)
+	}
+
+	revoked, err := repo.RevokeAPIKey(key.ID)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.RevokeAPIKey(key.ID)
+	assert.NoError(t, err)
+	assert.False(t, revoked, "already revoked")
+}
+
+func TestSchemaStatus(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	assert.NoError(t, repo.Ping(time.Second))
+
+	assert.NoError(t, tx.AutoMigrate(&SchemaMigration{}))
+	assert.NoError(t, tx.Save(&SchemaMigration{ID: 1, Version: SchemaVersion, MigratedAt: time.Now()}).Error)
+	status, err := repo.GetSchemaStatus(time.Second)
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) {
+		assert.Equal(t, SchemaVersion, status.Version)
+		assert.Empty(t, status.MissingViews)
+		assert.NotEmpty(t, status.DuckDBVersion)
+	}
+
+	assert.NoError(t, tx.Exec("DROP VIEW view_trend_metrics_by_domain").Error)
+	status, err = repo.GetSchemaStatus(time.Second)
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) {
+		assert.Equal(t, []string{"view_trend_metrics_by_domain"}, status.MissingViews)
+	}
+}
+
+func TestSearchItems(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	uid := uuid.New().String()
+	domain := "search-" + uid + ".test"
+	feed := Feed{URL: "http://" + domain + "/rss", RootDomain: &domain, Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	en, de := "en", "de"
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, item := range []Item{
+		{Hash: "search-title-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/1", Language: &en, PubDate: now, ThinkRating: 0.2,
+			ThinkResult: &ThinkResult{TitleOriginal: "Floods hit the coast", DescriptionOriginal: "Heavy rain caused flooding.", Category: "environment"}},
+		{Hash: "search-desc-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/2", Language: &en, PubDate: now.Add(-time.Hour), ThinkRating: 0.8,
+			ThinkResult: &ThinkResult{TitleOriginal: "Storm season", DescriptionOriginal: "More floods are expected.", Category: "environment"}},
+		{Hash: "search-de-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/3", Language: &de, PubDate: now.AddDate(0, 0, -3), ThinkRating: 0.1,
+			ThinkResult: &ThinkResult{TitleOriginal: "Überschwemmungen an der Küste", Category: "environment"}},
+		{Hash: "search-unanalyzed-" + uid, FeedID: feed.ID, URL: "http://" + domain + "/4", Language: &en, PubDate: now},
+	} {
+		assert.NoError(t, tx.Create(&item).Error)
+	}
+
+	// a title match outranks a description match; stemming finds "flooding"
+	hits, err := repo.SearchItems(ItemSearch{Query: "flood", Language: "en", Domain: domain})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 2) {
+		assert.Equal(t, "search-title-"+uid, hits[0].Item.Hash)
+		assert.Contains(t, hits[0].TitleHighlight, "<mark>Floods</mark>")
+		assert.Contains(t, hits[1].DescriptionHighlight, "<mark>floods</mark>")
+	}
+
+	hits, err = repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, MaxScore: 0.5})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	hits, err = repo.SearchItems(ItemSearch{Query: "Küste", Language: "de", Domain: domain})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	from := now.AddDate(0, 0, -1)
+	hits, err = repo.SearchItems(ItemSearch{Query: "flood or küste", Domain: domain, From: &from})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	// paging continues after the cursor of the last hit
+	page, err := repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, page, 1) {
+		after := page[0].Cursor()
+		page, err = repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, Limit: 1, After: &after})
+		assert.NoError(t, err)
+		if assert.Len(t, page, 1) {
+			assert.Equal(t, "search-desc-"+uid, page[0].Item.Hash)
+		}
+	}
+
+	// the reviewer's score and category filter like on the site
+	var reviewed Item
+	assert.NoError(t, tx.First(&reviewed, "hash = ?", "search-desc-"+uid).Error)
+	overall, category := 0.3, "politics"
+	assert.NoError(t, repo.UpsertItemReview(&ItemReview{ItemID: reviewed.ID, Overall: &overall, Category: &category}))
+
+	hits, err = repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, MaxScore: 0.5})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	hits, err = repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, Category: "politics"})
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, "search-desc-"+uid, hits[0].Item.Hash)
+	}
+	hits, err = repo.SearchItems(ItemSearch{Query: "flood", Domain: domain, Category: "environment"})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
 func TestFindFirstAnalyzedItemByUrl_FiltersTags(t *testing.T) {
 	_, baseDB := mustOpenTestRepo(t)
 
@@ -1621,6 +2082,76 @@ func TestBeginThinkerUpdateLLMModelBatch(t *testing.T) {
 	})
 }
 
+func TestBeginThinkerUpdatePromptVersionBatch(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	t.Run("SelectsOutdatedPromptVersionsPerLanguage", func(t *testing.T) {
+		tx := baseDB.Begin()
+		defer tx.Rollback()
+		repo := NewFromDB(tx)
+
+		feed := Feed{URL: "http://thinker-update-prompt.test/" + uuid.New().String(), Enabled: true, Polling: true}
+		assert.NoError(t, tx.Create(&feed).Error)
+
+		older := time.Unix(0, 0)
+		en := "en"
+		de := "de"
+		it := "it"
+
+		item1 := Item{FeedID: feed.ID, Hash: "p1", URL: "http://itemp1/" + uuid.New().String(), Content: "c1", Language: &en, ThinkResult: &ThinkResult{PromptVersion: "old"}}
+		item2 := Item{FeedID: feed.ID, Hash: "p2", URL: "http://itemp2/" + uuid.New().String(), Content: "c2", Language: &de, ThinkResult: &ThinkResult{}}
+		item3 := Item{FeedID: feed.ID, Hash: "p3", URL: "http://itemp3/" + uuid.New().String(), Content: "c3", Language: &en, ThinkResult: &ThinkResult{PromptVersion: "en-v2"}}
+		item4 := Item{FeedID: feed.ID, Hash: "p4", URL: "http://itemp4/" + uuid.New().String(), Content: "c4", Language: &it, ThinkResult: &ThinkResult{PromptVersion: "old"}}
+		item5 := Item{FeedID: feed.ID, Hash: "p5", URL: "http://itemp5/" + uuid.New().String(), Content: "c5", Language: &de}
+		for _, item := range []*Item{&item1, &item2, &item3, &item4, &item5} {
+			assert.NoError(t, tx.Create(item).Error)
+			assert.NoError(t, tx.Model(&Item{}).Where("id = ?", item.ID).UpdateColumn("updated_at", older).Error)
+		}
+
+		items, err := repo.BeginThinkerUpdatePromptVersionBatch(10, map[string]string{"en": "en-v2", "de": "de-v1"}, time.Minute)
+		assert.NoError(t, err)
+		hashes := make(map[string]struct{}, len(items))
+		for _, item := range items {
+			hashes[strings.TrimSpace(item.Hash)] = struct{}{}
+		}
+		assert.Len(t, hashes, 2)
+		_, ok := hashes["p1"]
+		assert.True(t, ok)
+		_, ok = hashes["p2"]
+		assert.True(t, ok)
+	})
+}
+
+func TestCountThinkerUpdateCandidates(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	model := "count-model-" + uuid.New().String()
+	versions := map[string]string{"en": "en-" + uuid.New().String()}
+	beforeModel, beforePrompt, err := repo.CountThinkerUpdateCandidates(model, versions)
+	assert.NoError(t, err)
+
+	feed := Feed{URL: "http://thinker-update-count.test/" + uuid.New().String(), Enabled: true, Polling: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+	en := "en"
+	for _, item := range []Item{
+		{FeedID: feed.ID, Hash: "c1", URL: "http://itemc1/" + uuid.New().String(), Content: "c1", Language: &en, ThinkResult: &ThinkResult{LLMModel: model, PromptVersion: versions["en"]}},
+		{FeedID: feed.ID, Hash: "c2", URL: "http://itemc2/" + uuid.New().String(), Content: "c2", Language: &en, ThinkResult: &ThinkResult{LLMModel: "old-model", PromptVersion: "old"}},
+		{FeedID: feed.ID, Hash: "c3", URL: "http://itemc3/" + uuid.New().String(), Content: "c3", Language: &en},
+	} {
+		assert.NoError(t, tx.Create(&item).Error)
+	}
+
+	// locked items count as well, the backlog is what is left to do
+	afterModel, afterPrompt, err := repo.CountThinkerUpdateCandidates(model, versions)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), afterModel-beforeModel)
+	assert.Equal(t, int64(1), afterPrompt-beforePrompt)
+}
+
 func TestFindFeedScheduleById(t *testing.T) {
 	baseRepo, baseDB := mustOpenTestRepo(t)
 
@@ -1923,3 +2454,416 @@ func TestCreateFeedSchedule(t *testing.T) {
 		}
 	})
 }
+
+func TestThinkCache(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	key := strings.Repeat("a", 63) + "b"
+
+	found, err := repo.FindThinkCache(key)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	entry := &ThinkCache{Key: key, LLMModel: "m", PromptVersion: "v1", Language: "en", ThinkResult: &ThinkResult{Overall: 0.4}}
+	assert.NoError(t, repo.UpsertThinkCache(entry))
+	entry.ThinkResult = &ThinkResult{Overall: 0.6}
+	assert.NoError(t, repo.UpsertThinkCache(entry))
+
+	found, err = repo.FindThinkCache(key)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) {
+		assert.Equal(t, 0.6, found.Overall)
+	}
+
+	var stored ThinkCache
+	assert.NoError(t, tx.Where("key = ?", key).First(&stored).Error)
+	assert.Equal(t, int64(1), stored.HitCount)
+	assert.NotNil(t, stored.LastHitAt)
+}
+
+func TestLLMUsage(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	feed := Feed{URL: "http://usage.example.com/rss"}
+	assert.NoError(t, repo.UpsertFeed(&feed))
+
+	now := time.Now().UTC()
+	old := now.AddDate(0, -2, 0)
+	assert.NoError(t, repo.CreateLLMUsages([]LLMUsage{
+		{CreatedAt: now, FeedID: &feed.ID, Provider: "openai", LLMModel: "a", PromptTokens: 100, CompletionTokens: 10, LatencyMs: 100, Cost: 0.5},
+		{CreatedAt: now, FeedID: &feed.ID, Provider: "openai", LLMModel: "a", PromptTokens: 200, CompletionTokens: 20, LatencyMs: 300, Cost: 0.25},
+		{CreatedAt: now, Provider: "gemini", LLMModel: "b", PromptTokens: 50, CompletionTokens: 5, LatencyMs: 50, Cost: 0.125},
+		{CreatedAt: old, Provider: "openai", LLMModel: "a", PromptTokens: 1, CompletionTokens: 1, Cost: 100},
+	}))
+
+	since := now.Add(-time.Hour)
+	cost, err := repo.SumLLMCostSince(since)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.875, cost, 1e-9)
+
+	rows, err := repo.GetLLMUsageReport(since, []string{"model"})
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		byModel := map[string]LLMUsageReport{}
+		for _, row := range rows {
+			byModel[*row.LLMModel] = row
+		}
+		assert.Equal(t, int64(2), byModel["a"].Calls)
+		assert.Equal(t, int64(300), byModel["a"].PromptTokens)
+		assert.InDelta(t, 200, byModel["a"].AvgLatencyMs, 1e-9)
+		assert.InDelta(t, 0.75, byModel["a"].Cost, 1e-9)
+		assert.Nil(t, byModel["a"].Day)
+	}
+
+	rows, err = repo.GetLLMUsageReport(since, []string{"feed"})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	_, err = repo.GetLLMUsageReport(since, []string{"bogus"})
+	assert.Error(t, err)
+}
+
+func TestThinkResultHistory(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	feed := Feed{URL: "http://think-history.test/" + uuid.New().String(), Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	item := &Item{FeedID: feed.ID, Hash: "h1", URL: "http://think-history.test/item", Content: "c"}
+	assert.NoError(t, repo.UpsertItem(item))
+	assert.Nil(t, item.ThinkResultID)
+
+	analyze := func(model string, rating float64, category string) {
+		item.ThinkResult = &ThinkResult{LLMModel: model, PromptVersion: "v1", Overall: rating, Category: category}
+		item.ThinkRating = rating
+		item.Categories = StringArray{category}
+		item.ThinkResultID = nil
+		assert.NoError(t, repo.UpsertItemWithTrendInvalidation(item))
+		assert.NotNil(t, item.ThinkResultID)
+	}
+	analyze("good", 0.3, "business")
+	goodID := *item.ThinkResultID
+	start := time.Now().Add(-time.Second)
+	analyze("bad", 0.9, "politics")
+	analyze("bad", 0.8, "politics")
+
+	// saving again without a new result does not add to the history
+	assert.NoError(t, repo.UpsertItem(item))
+
+	history, err := repo.ListThinkResultHistory(item.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, *item.ThinkResultID, history[0].ID)
+		assert.Equal(t, "bad", history[0].LLMModel)
+		assert.Equal(t, "good", history[2].LLMModel)
+	}
+
+	ids, err := repo.RollbackThinkResults("other", time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	ids, err = repo.RollbackThinkResults("bad", start)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{item.ID}, ids)
+
+	var restored Item
+	assert.NoError(t, tx.First(&restored, "id = ?", item.ID).Error)
+	assert.Equal(t, goodID, *restored.ThinkResultID)
+	assert.Equal(t, "good", restored.ThinkResult.LLMModel)
+	assert.Equal(t, 0.3, restored.ThinkRating)
+	assert.Equal(t, StringArray{"business"}, restored.Categories)
+
+	// the rollback itself is not a new analysis
+	history, err = repo.ListThinkResultHistory(item.ID)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestItemReview(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	feed := Feed{URL: "http://item-review.test/" + uuid.New().String(), Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+	item := &Item{
+		FeedID:      feed.ID,
+		Hash:        "h1",
+		URL:         "http://item-review.test/item",
+		Content:     "c",
+		ThinkResult: &ThinkResult{LLMModel: "m", Clickbait: 0.9, Overall: 0.8, Category: "opinion"},
+		ThinkRating: 0.8,
+		PubDate:     time.Now(),
+	}
+	assert.NoError(t, repo.UpsertItem(item))
+
+	overall := 0.2
+	category := "politics"
+	assert.NoError(t, repo.UpsertItemReview(&ItemReview{ItemID: item.ID, Reviewer: "alice", Overall: &overall}))
+	// a second review replaces the first one
+	assert.NoError(t, repo.UpsertItemReview(&ItemReview{ItemID: item.ID, Reviewer: "bob", Note: "news", Overall: &overall, Category: &category}))
+
+	reviews, err := repo.ListItemReviews()
+	assert.NoError(t, err)
+	if assert.Len(t, reviews, 1) {
+		assert.Equal(t, "bob", reviews[0].Reviewer)
+		assert.Equal(t, item.URL, reviews[0].Item.URL)
+	}
+
+	// a new analysis keeps the review
+	item.ThinkResult = &ThinkResult{LLMModel: "m2", Clickbait: 0.7, Overall: 0.9, Category: "opinion"}
+	item.ThinkRating = 0.9
+	assert.NoError(t, repo.UpsertItem(item))
+
+	u, err := url.Parse(item.URL)
+	assert.NoError(t, err)
+	found, err := repo.FindFirstAnalyzedItemByUrl(u)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) && assert.NotNil(t, found.Review) {
+		assert.Equal(t, 0.2, found.ThinkRating)
+		assert.Equal(t, 0.2, found.ThinkResult.Overall)
+		assert.Equal(t, 0.7, found.ThinkResult.Clickbait)
+		assert.Equal(t, "politics", found.ThinkResult.Category)
+
+		b, err := json.Marshal(found)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `"reviewed":true`)
+		assert.Contains(t, string(b), `"review_note":"news"`)
+	}
+
+	assert.NoError(t, repo.DeleteItemReview(item.ID))
+	found, err = repo.FindFirstAnalyzedItemByUrl(u)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) {
+		assert.Nil(t, found.Review)
+		assert.Equal(t, 0.9, found.ThinkRating)
+	}
+}
+
+func TestItemFeedback(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	feed := Feed{URL: "http://item-feedback.test/" + uuid.New().String(), Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+	hash := "feedback-" + uuid.New().String()
+	item := &Item{
+		FeedID:      feed.ID,
+		Hash:        hash,
+		URL:         "http://item-feedback.test/item",
+		Content:     "c",
+		ThinkResult: &ThinkResult{LLMModel: "m", Overall: 0.8},
+		ThinkRating: 0.8,
+		PubDate:     time.Now(),
+	}
+	assert.NoError(t, repo.UpsertItem(item))
+
+	found, err := repo.CreateItemFeedback("unknown-hash", &ItemFeedback{ClientID: "client-a", Kind: FeedbackRatingTooHigh})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	for _, fb := range []ItemFeedback{
+		{ClientID: "client-a", Source: "192.0.2.1", Kind: FeedbackRatingTooHigh},
+		{ClientID: "client-a", Source: "192.0.2.1", Kind: FeedbackRatingTooHigh}, // duplicate, ignored
+		{ClientID: "client-a", Source: "192.0.2.1", Kind: FeedbackTitleWrong, Comment: "title is fine"},
+		{ClientID: "client-b", Source: "192.0.2.1", Kind: FeedbackRatingTooHigh},
+	} {
+		found, err = repo.CreateItemFeedback(hash, &fb)
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+
+	count, err := repo.CountItemFeedbackSince("client-a", time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountItemFeedbackFromSourceSince("192.0.2.1", time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	rows, err := repo.GetFeedbackReport(time.Now().Add(-time.Hour), "feed", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, feed.ID, *rows[0].FeedID)
+		assert.Equal(t, int64(1), rows[0].Items)
+		assert.Equal(t, int64(2), rows[0].Clients)
+		assert.Equal(t, int64(2), rows[0].RatingTooHigh)
+		assert.Equal(t, int64(0), rows[0].RatingTooLow)
+		assert.Equal(t, int64(1), rows[0].TitleWrong)
+		assert.InDelta(t, 0.8, rows[0].AvgRating, 0.001)
+	}
+
+	rows, err = repo.GetFeedbackReport(time.Now().Add(-time.Hour), "model", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "m", *rows[0].LLMModel)
+	}
+
+	_, err = repo.GetFeedbackReport(time.Now(), "client", 0)
+	assert.Error(t, err)
+}
+
+func TestDeadItems(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	before, err := repo.GetThinkerQueueDepth(3, 6)
+	assert.NoError(t, err)
+
+	feed := Feed{URL: "http://dead-items.test/" + uuid.New().String(), Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+	contextErr := "Context size has been exceeded"
+	otherErr := "invalid category"
+	newItem := func(n int, errorCount int, thinkError *string) *Item {
+		item := &Item{
+			FeedID:          feed.ID,
+			Hash:            fmt.Sprintf("dead-%d", n),
+			URL:             fmt.Sprintf("http://dead-items.test/%d", n),
+			Content:         "c",
+			ThinkError:      thinkError,
+			ThinkErrorCount: errorCount,
+			PubDate:         time.Now(),
+		}
+		assert.NoError(t, tx.Create(item).Error)
+		return item
+	}
+	newItem(1, 0, nil)
+	newItem(2, 5, &otherErr)
+	dead1 := newItem(3, 7, &contextErr)
+	newItem(4, 9, &contextErr)
+	dead3 := newItem(5, 7, &otherErr)
+
+	after, err := repo.GetThinkerQueueDepth(3, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, before.Thinker+1, after.Thinker)
+	assert.Equal(t, before.Fixer+1, after.Fixer)
+	assert.Equal(t, before.Dead+3, after.Dead)
+
+	filter := DeadItemFilter{MinErrorCount: 7, FeedID: &feed.ID}
+	groups, err := repo.GetDeadItemGroups(filter)
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, contextErr, groups[0].ThinkError)
+		assert.Equal(t, int64(2), groups[0].Items)
+		assert.Equal(t, feed.URL, groups[0].FeedURL)
+	}
+
+	items, err := repo.FindDeadItems(DeadItemFilter{MinErrorCount: 7, FeedID: &feed.ID, ErrorContains: "context SIZE"}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	count, err := repo.RequeueDeadItems(DeadItemFilter{MinErrorCount: 7, IDs: []uuid.UUID{dead1.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	var requeued Item
+	assert.NoError(t, tx.First(&requeued, "id = ?", dead1.ID).Error)
+	assert.Equal(t, 0, requeued.ThinkErrorCount)
+	assert.Nil(t, requeued.ThinkError)
+
+	count, err = repo.PurgeDeadItems(DeadItemFilter{MinErrorCount: 7, FeedID: &feed.ID, ErrorContains: "category"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.ErrorIs(t, tx.First(&Item{}, "id = ?", dead3.ID).Error, gorm.ErrRecordNotFound)
+
+	items, err = repo.FindDeadItems(filter, 0)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+}
+
+func TestRefusedItems(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	before, err := repo.GetThinkerQueueDepth(3, 6)
+	assert.NoError(t, err)
+
+	feed := Feed{URL: "http://refused-items.test/" + uuid.New().String(), Enabled: true}
+	assert.NoError(t, tx.Create(&feed).Error)
+	refusedKind := ThinkErrorKindRefused
+	refusedErr := "blocked by safety filter"
+	refused := &Item{
+		FeedID:          feed.ID,
+		Hash:            "refused-1",
+		URL:             "http://refused-items.test/1",
+		Content:         "c",
+		ThinkError:      &refusedErr,
+		ThinkErrorCount: 1,
+		ThinkErrorKind:  &refusedKind,
+		PubDate:         time.Now(),
+		UpdatedAt:       time.Now().Add(-time.Hour),
+	}
+	assert.NoError(t, tx.Create(refused).Error)
+
+	after, err := repo.GetThinkerQueueDepth(3, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, before.Thinker, after.Thinker)
+	assert.Equal(t, before.Refused+1, after.Refused)
+
+	items, err := repo.BeginThinkerBatch(1000, time.Time{}, 0, 3, time.Minute)
+	assert.NoError(t, err)
+	for _, item := range items {
+		assert.NotEqual(t, refused.ID, item.ID)
+	}
+
+	dead, err := repo.FindDeadItems(DeadItemFilter{MinErrorCount: 7, FeedID: &feed.ID}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, dead, 1)
+
+	count, err := repo.RequeueDeadItems(DeadItemFilter{MinErrorCount: 7, IDs: []uuid.UUID{refused.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	var requeued Item
+	assert.NoError(t, tx.First(&requeued, "id = ?", refused.ID).Error)
+	assert.Nil(t, requeued.ThinkErrorKind)
+}
+
+func TestFeedLanguageStats(t *testing.T) {
+	_, baseDB := mustOpenTestRepo(t)
+
+	tx := baseDB.Begin()
+	defer tx.Rollback()
+	repo := NewFromDB(tx)
+
+	language := "de"
+	feed := Feed{URL: "http://language-stats.test/" + uuid.New().String(), Language: &language}
+	assert.NoError(t, tx.Create(&feed).Error)
+
+	assert.NoError(t, repo.AddFeedLanguageStats(feed.ID, nil))
+	assert.NoError(t, repo.AddFeedLanguageStats(feed.ID, map[string]int64{"de": 3, "en": 1}))
+	assert.NoError(t, repo.AddFeedLanguageStats(feed.ID, map[string]int64{"de": 2, "und": 1}))
+
+	stats, err := repo.GetFeedLanguageStats()
+	assert.NoError(t, err)
+	counts := map[string]int64{}
+	for _, stat := range stats {
+		if stat.FeedID != feed.ID {
+			continue
+		}
+		assert.Equal(t, "de", *stat.DeclaredLanguage)
+		counts[stat.Language] = stat.Items
+	}
+	assert.Equal(t, map[string]int64{"de": 5, "en": 1, "und": 1}, counts)
+}
@@ -0,0 +1,26 @@
+-- Full-text search over the original and corrected texts of analyzed items.
+-- Languages without a Postgres dictionary (pl) fall back to the simple configuration.
+CREATE OR REPLACE FUNCTION deframer_search_config(lang text) RETURNS regconfig
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT (CASE lang
+        WHEN 'en' THEN 'english'
+        WHEN 'de' THEN 'german'
+        WHEN 'da' THEN 'danish'
+        WHEN 'es' THEN 'spanish'
+        WHEN 'fr' THEN 'french'
+        WHEN 'nl' THEN 'dutch'
+        WHEN 'it' THEN 'italian'
+        WHEN 'pt' THEN 'portuguese'
+        WHEN 'sv' THEN 'swedish'
+        ELSE 'simple'
+    END)::regconfig
+$$;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(deframer_search_config(language),
+        COALESCE(think_result->>'title_original', '') || ' ' || COALESCE(think_result->>'title_corrected', '')), 'A') ||
+    setweight(to_tsvector(deframer_search_config(language),
+        COALESCE(think_result->>'description_original', '') || ' ' || COALESCE(think_result->>'description_corrected', '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search_vector ON items USING GIN (search_vector);
@@ -0,0 +1,23 @@
+-- Ordered ids of finished analyses, used as SSE event ids of /api/stream.
+CREATE SEQUENCE IF NOT EXISTS think_results_event_id_seq;
+
+ALTER TABLE think_results ADD COLUMN IF NOT EXISTS event_id bigint NOT NULL DEFAULT nextval('think_results_event_id_seq');
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_think_results_event_id ON think_results (event_id);
+
+-- Streams resume after the last event id they sent, so a smaller id must never
+-- commit after a larger one. The lock is held until the inserting transaction
+-- ends, which hands out the ids in commit order: once an event id is visible,
+-- all smaller ones are too.
+CREATE OR REPLACE FUNCTION deframer_think_results_event_id() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('think_results_event_id'));
+    NEW.event_id := nextval('think_results_event_id_seq');
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS think_results_event_id ON think_results;
+CREATE TRIGGER think_results_event_id BEFORE INSERT ON think_results
+    FOR EACH ROW EXECUTE FUNCTION deframer_think_results_event_id();
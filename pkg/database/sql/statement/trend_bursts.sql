@@ -0,0 +1,25 @@
+/*
+  Topics with a burst on a domain: the outlier ratio (frequency of the day
+  divided by the average of the 7 days before, see top_trend_by_domain.sql)
+  reached @min_ratio. Days without history have no ratio and never burst.
+*/
+
+WITH settings AS (
+    SELECT set_config('duckdb.force_execution', 'true', true)
+)
+SELECT
+    stem as trend_topic,
+    "language",
+    root_domain,
+    frequency,
+    utility,
+    outlier_ratio,
+    time_slice
+FROM view_trend_metrics_by_domain
+WHERE stem_type = 'NOUN'
+  AND root_domain IS NOT NULL
+  AND time_slice >= CAST(@since AS timestamp)
+  AND frequency >= CAST(@min_frequency AS INTEGER)
+  AND outlier_ratio >= CAST(@min_ratio AS double precision)
+ORDER BY outlier_ratio DESC
+LIMIT 100;
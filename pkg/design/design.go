@@ -8,7 +8,30 @@ var BasicAuthPayload = Type("BasicAuthPayload", func() {
 	Password("pass", String)
 })
 
-var BasicAuth = BasicAuthSecurity("basic")
+var BasicAuth = BasicAuthSecurity("basic", func() {
+	Description("BASIC_AUTH_USER and BASIC_AUTH_PASSWORD, or an API key id and secret. Only API keys are limited to scopes.")
+	Scope("items:read", "Read analyzed items")
+	Scope("trends:read", "Read trends and the articles behind them")
+	Scope("feedback:write", "Submit reader feedback")
+})
+
+// authErrors are the errors of every method behind BasicAuth.
+func authErrors() {
+	Error("unauthorized", String, "Invalid credentials")
+	Error("forbidden", String, "The API key lacks a required scope")
+	Error("too_many_requests", String, "Rate limit exceeded")
+}
+
+// authResponses map authErrors, inside the service HTTP DSL.
+func authResponses() {
+	Response("unauthorized", StatusUnauthorized)
+	Response("forbidden", StatusForbidden)
+	Response("too_many_requests", StatusTooManyRequests)
+}
+
+var ReviewerAuth = BasicAuthSecurity("reviewer", func() {
+	Description("Reviewer credentials from REVIEWER_USER and REVIEWER_PASSWORD.")
+})
 
 var _ = API("service", func() {
 	Title("service")
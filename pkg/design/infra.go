@@ -2,6 +2,27 @@ package design
 
 import . "goa.design/goa/v3/dsl" //nolint:staticcheck
 
+var HealthCheck = Type("HealthCheck", func() {
+	Description("Result of a single dependency check.")
+	Attribute("name", String, "Checked dependency", func() {
+		Example("database")
+	})
+	Attribute("status", String, "Check status", func() {
+		Enum("ok", "fail")
+	})
+	Attribute("message", String, "Detail of the check, e.g. the error of a failed check")
+	Required("name", "status")
+})
+
+var HealthStatus = Type("HealthStatus", func() {
+	Description("Health of the service and its dependencies.")
+	Attribute("status", String, "ok when every check passed", func() {
+		Enum("ok", "fail")
+	})
+	Attribute("checks", ArrayOf(HealthCheck), "Checks in the order they ran")
+	Required("status", "checks")
+})
+
 var _ = Service("infra", func() {
 	Description("Infrastructure and health endpoints.")
 	HTTP(func() {
@@ -20,4 +41,24 @@ var _ = Service("infra", func() {
 			})
 		})
 	})
+
+	Method("livez", func() {
+		Description("Liveness probe: the process serves requests. Dependencies are left to readyz, so an outage of Postgres does not restart the service.")
+		Result(HealthStatus)
+		HTTP(func() {
+			GET("/livez")
+			Response(StatusOK)
+		})
+	})
+
+	Method("readyz", func() {
+		Description("Readiness probe: checks the database connection, the schema version, the trend views and pg_duckdb.")
+		Result(HealthStatus)
+		Error("unavailable", HealthStatus, "A check failed")
+		HTTP(func() {
+			GET("/readyz")
+			Response(StatusOK)
+			Response("unavailable", StatusServiceUnavailable)
+		})
+	})
 })
@@ -7,8 +7,10 @@ var _ = Service("mobile", func() {
 	Security(BasicAuth)
 	HTTP(func() {
 		Path("/mobile/api")
+		authResponses()
 	})
 
 	Error("not_found", String, "Resource not found")
+	authErrors()
 	defineWebMethods()
 })
@@ -14,6 +14,20 @@ var SentimentScores = Type("SentimentScores", func() {
 	Attribute("disgust", Float64, "Disgust score")
 })
 
+var EvidenceSpan = Type("EvidenceSpan", func() {
+	Description("Words of the original text behind a score.")
+	Attribute("dimension", String, "Score the span supports", func() {
+		Enum("framing", "clickbait", "persuasive", "hyper_stimulus", "speculative")
+	})
+	Attribute("field", String, "Quoted field", func() {
+		Enum("title", "description")
+	})
+	Attribute("quote", String, "Quoted words")
+	Attribute("start", Int, "Offset of the first character (Unicode code points, HTML stripped)")
+	Attribute("end", Int, "Offset after the last character")
+	Required("dimension", "field", "quote", "start", "end")
+})
+
 var ThinkResult = Type("ThinkResult", func() {
 	Description("Analysis result for an article.")
 	// Internal note: llm_model exists in the persisted database ThinkResult;
@@ -37,6 +51,9 @@ var ThinkResult = Type("ThinkResult", func() {
 	Attribute("overall", Float64, "Overall score")
 	Attribute("overall_reason", String, "Overall explanation")
 	Attribute("category", String, "Article category")
+	Attribute("evidence", ArrayOf(EvidenceSpan), "Words behind the scores")
+	Attribute("disagreement", Float64, "Standard deviation of the overall score across ensemble models")
+	Attribute("ensemble_models", ArrayOf(String), "Models that contributed to an ensemble result")
 })
 
 var MediaThumbnail = Type("MediaThumbnail", func() {
@@ -140,6 +157,7 @@ var AnalyzedItem = Type("AnalyzedItem", func() {
 	Attribute("tags", ArrayOf(String), "Feed tags")
 	Attribute("url", String, "Item URL")
 	Attribute("llm_model", String, "LLM model")
+	Attribute("prompt_version", String, "Version of the prompt that produced the analysis")
 	Attribute("title_original", String, "Original title")
 	Attribute("description_original", String, "Original description")
 	Attribute("title_corrected", String, "Corrected title")
@@ -159,10 +177,15 @@ var AnalyzedItem = Type("AnalyzedItem", func() {
 	Attribute("overall", Float64, "Overall score")
 	Attribute("overall_reason", String, "Overall explanation")
 	Attribute("category", String, "Article category")
+	Attribute("evidence", ArrayOf(EvidenceSpan), "Words behind the scores")
+	Attribute("disagreement", Float64, "Standard deviation of the overall score across ensemble models")
+	Attribute("ensemble_models", ArrayOf(String), "Models that contributed to an ensemble result")
 	Attribute("sentiments", SentimentScores, "Original sentiments")
 	Attribute("sentiments_deframed", SentimentScores, "Deframed sentiments")
 	Attribute("media", MediaContent, "Media content")
 	Attribute("rating", Float64, "Think rating")
+	Attribute("reviewed", Boolean, "True when scores or category were corrected by a reviewer")
+	Attribute("review_note", String, "Reviewer note")
 	Attribute("authors", ArrayOf(String), "Authors")
 	Attribute("pubDate", String, "Publication date", func() {
 		Format(FormatDateTime)
@@ -9,16 +9,124 @@ var ItemPayload = Type("ItemPayload", func() {
 	Required("url")
 })
 
+var ItemsLookupPayload = Type("ItemsLookupPayload", func() {
+	Description("Lookup many items by URL at once.")
+	Extend(BasicAuthPayload)
+	Attribute("urls", ArrayOf(String), "Item URLs", func() {
+		MinLength(1)
+		MaxLength(100)
+	})
+	Required("urls")
+})
+
+var ItemsLookupResult = Type("ItemsLookupResult", func() {
+	Description("Analyzed items of a batch lookup.")
+	Attribute("items", MapOf(String, AnalyzedItem), "Analyzed items keyed by the requested URL")
+	Attribute("not_found", ArrayOf(String), "Requested URLs without an analyzed item")
+	Required("items", "not_found")
+})
+
+var SearchPayload = Type("SearchPayload", func() {
+	Description("Full-text search across analyzed items.")
+	Extend(BasicAuthPayload)
+	Attribute("q", String, "Search query, supports quoted phrases, or and -word", func() {
+		MinLength(1)
+		MaxLength(200)
+	})
+	Attribute("lang", String, "Language code")
+	Attribute("domain", String, "Root domain")
+	Attribute("from", String, "First publication date", func() {
+		Format(FormatDate)
+	})
+	Attribute("to", String, "Last publication date", func() {
+		Format(FormatDate)
+	})
+	Attribute("max_score", Float64, "Maximum rating to include", func() {
+		Default(0)
+	})
+	Attribute("category", String, "Article category")
+	Attribute("cursor", String, "Cursor of the next page from a previous result")
+	Attribute("limit", Int, "Maximum results", func() {
+		Default(20)
+		Minimum(1)
+		Maximum(100)
+	})
+	Required("q")
+})
+
+var SearchHit = Type("SearchHit", func() {
+	Description("Analyzed item matching a search.")
+	Attribute("item", AnalyzedItem, "Analyzed item")
+	Attribute("rank", Float64, "Relevance, higher is better")
+	Attribute("title_highlight", String, "Title with the matched words in <mark> tags")
+	Attribute("description_highlight", String, "Fragments of the description with the matched words in <mark> tags")
+	Required("item", "rank", "title_highlight", "description_highlight")
+})
+
+var SearchResult = Type("SearchResult", func() {
+	Description("A page of search hits.")
+	Attribute("items", ArrayOf(SearchHit), "Hits, best first")
+	Attribute("next_cursor", String, "Cursor of the next page, missing on the last page")
+	Required("items")
+})
+
+// siteScoreDimensions can be filtered by min_<dimension> and max_<dimension>
+// on the site listing; the overall score uses min_score and max_score.
+var siteScoreDimensions = []string{"framing", "clickbait", "persuasive", "hyper_stimulus", "speculative"}
+
+var StreamPayload = Type("StreamPayload", func() {
+	Description("Subscribe to newly analyzed items.")
+	Extend(BasicAuthPayload)
+	Attribute("domain", String, "Root domain")
+	Attribute("max_score", Float64, "Maximum rating to include", func() {
+		Default(0)
+	})
+	Attribute("last_event_id", String, "Id of the last received event, resumes after it")
+})
+
+var ItemEvent = Type("ItemEvent", func() {
+	Description("An item whose analysis just finished.")
+	Attribute("id", String, "Event id")
+	Attribute("item", AnalyzedItem, "Analyzed item")
+	Required("id", "item")
+})
+
 var SitePayload = Type("SitePayload", func() {
-	Description("List items for a root domain.")
+	Description("List items for a root domain, newest first.")
 	Extend(BasicAuthPayload)
 	Attribute("root", String, "Root domain")
 	Attribute("max_score", Float64, "Maximum rating to include", func() {
 		Default(0)
 	})
+	Attribute("min_score", Float64, "Minimum rating to include")
+	for _, dim := range siteScoreDimensions {
+		Attribute("min_"+dim, Float64, "Minimum "+dim+" score to include")
+		Attribute("max_"+dim, Float64, "Maximum "+dim+" score to include")
+	}
+	Attribute("category", String, "Article category")
+	Attribute("author", String, "One of the authors, case-insensitive")
+	Attribute("from", String, "First publication date", func() {
+		Format(FormatDate)
+	})
+	Attribute("to", String, "Last publication date", func() {
+		Format(FormatDate)
+	})
+	Attribute("cursor", String, "Cursor of the next page from the X-Next-Cursor header")
+	Attribute("limit", Int, "Maximum results", func() {
+		Default(30)
+		Minimum(1)
+		Maximum(100)
+	})
 	Required("root")
 })
 
+var SitePage = Type("SitePage", func() {
+	Description("A page of the site listing.")
+	Attribute("items", ArrayOf(AnalyzedSiteItem), "Items, newest first")
+	Attribute("next_cursor", String, "Cursor of the next page, missing on the last page")
+	Required("items")
+})
+
 var ArticlesPayload = Type("ArticlesPayload", func() {
 	Description("List articles for a trend and domain.")
 	Extend(BasicAuthPayload)
@@ -125,20 +233,139 @@ var DomainComparisonPayload = Type("DomainComparisonPayload", func() {
 	Required("domain_a", "domain_b", "lang")
 })
 
+var FeedbackPayload = Type("FeedbackPayload", func() {
+	Description("Reader feedback on the analysis of an item.")
+	Extend(BasicAuthPayload)
+	Attribute("hash", String, "Item hash", func() {
+		MaxLength(64)
+	})
+	Attribute("client_id", String, "Anonymous client ID, generated once per installation", func() {
+		MinLength(8)
+		MaxLength(64)
+	})
+	Attribute("kind", String, "Kind of feedback", func() {
+		Enum("rating_too_high", "rating_too_low", "title_wrong")
+	})
+	Attribute("comment", String, "Optional comment", func() {
+		MaxLength(500)
+	})
+	Required("hash", "client_id", "kind")
+})
+
+var ReviewPayload = Type("ReviewPayload", func() {
+	Description("Reviewer correction of an analyzed item. Omitted scores keep the LLM value.")
+	Extend(BasicAuthPayload)
+	Attribute("url", String, "Item URL")
+	Attribute("framing", Float64, "Reviewed framing score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("clickbait", Float64, "Reviewed clickbait score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("persuasive", Float64, "Reviewed persuasiveness score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("hyper_stimulus", Float64, "Reviewed hyper stimulus score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("speculative", Float64, "Reviewed speculative score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("overall", Float64, "Reviewed overall score", func() {
+		Minimum(0)
+		Maximum(1)
+	})
+	Attribute("category", String, "Reviewed category (english)")
+	Attribute("note", String, "Why the analysis was corrected")
+	Required("url")
+})
+
+var DeleteReviewPayload = Type("DeleteReviewPayload", func() {
+	Description("Remove the reviewer correction of an item.")
+	Extend(BasicAuthPayload)
+	Attribute("url", String, "Item URL")
+	Required("url")
+})
+
 var _ = Service("web", func() {
 	Description("Browser/web-facing API contract.")
 	Security(BasicAuth)
 	HTTP(func() {
 		Path("/api")
+		authResponses()
 	})
 
 	Error("not_found", String, "Resource not found")
+	authErrors()
 	defineWebMethods()
+	defineReviewMethods()
+	defineStreamMethods()
 })
 
+// defineStreamMethods are only part of the web service, for dashboards and the extension.
+func defineStreamMethods() {
+	Method("stream", func() {
+		Description("Server-Sent Events stream of the items whose analysis finished, resumable with Last-Event-ID.")
+		Security(BasicAuth, func() { Scope("items:read") })
+		Payload(StreamPayload)
+		StreamingResult(ItemEvent)
+		Error("bad_request", String, "Invalid Last-Event-ID")
+		HTTP(func() {
+			GET("/stream")
+			Param("domain")
+			Param("max_score")
+			// mapped as a plain header, SSERequestID generates an invalid field name for it
+			Header("last_event_id:Last-Event-ID")
+			ServerSentEvents(func() {
+				SSEEventID("id")
+				SSEEventData("item")
+			})
+			Response(StatusOK)
+			Response("bad_request", StatusBadRequest)
+		})
+	})
+}
+
+// defineReviewMethods are only part of the web service; mobile clients do not review.
+func defineReviewMethods() {
+	Method("review", func() {
+		Description("Override the analysis of an item with reviewer corrections.")
+		Security(ReviewerAuth)
+		Payload(ReviewPayload)
+		Result(AnalyzedItem)
+		Error("bad_request", String, "Invalid review")
+		HTTP(func() {
+			POST("/review")
+			Response(StatusOK)
+			Response("not_found", StatusNotFound)
+			Response("bad_request", StatusBadRequest)
+		})
+	})
+
+	Method("deleteReview", func() {
+		Description("Remove the reviewer corrections of an item.")
+		Security(ReviewerAuth)
+		Payload(DeleteReviewPayload)
+		Error("bad_request", String, "Invalid url")
+		HTTP(func() {
+			DELETE("/review")
+			Param("url")
+			Response(StatusNoContent)
+			Response("not_found", StatusNotFound)
+			Response("bad_request", StatusBadRequest)
+		})
+	})
+}
+
 func defineWebMethods() {
 	Method("item", func() {
 		Description("Fetch a single analyzed item by URL.")
+		Security(BasicAuth, func() { Scope("items:read") })
 		Payload(ItemPayload)
 		Result(AnalyzedItem)
 		HTTP(func() {
@@ -149,21 +376,73 @@ func defineWebMethods() {
 		})
 	})
 
+	Method("lookupItems", func() {
+		Description("Fetch the analyzed items of up to 100 URLs in one request.")
+		Security(BasicAuth, func() { Scope("items:read") })
+		Payload(ItemsLookupPayload)
+		Result(ItemsLookupResult)
+		HTTP(func() {
+			POST("/items/lookup")
+			Response(StatusOK)
+		})
+	})
+
+	Method("search", func() {
+		Description("Full-text search across the titles and descriptions of analyzed items.")
+		Security(BasicAuth, func() { Scope("items:read") })
+		Payload(SearchPayload)
+		Result(SearchResult)
+		Error("bad_request", String, "Invalid search")
+		HTTP(func() {
+			GET("/search")
+			Param("q")
+			Param("lang")
+			Param("domain")
+			Param("from")
+			Param("to")
+			Param("max_score")
+			Param("category")
+			Param("cursor")
+			Param("limit")
+			Response(StatusOK)
+			Response("bad_request", StatusBadRequest)
+		})
+	})
+
 	Method("site", func() {
 		Description("List analyzed items for a root domain.")
+		Security(BasicAuth, func() { Scope("items:read") })
 		Payload(SitePayload)
-		Result(ArrayOf(AnalyzedSiteItem))
+		Result(SitePage)
+		Error("bad_request", String, "Invalid filter or cursor")
 		HTTP(func() {
 			GET("/site")
 			Param("root")
 			Param("max_score")
-			Response(StatusOK)
+			Param("min_score")
+			for _, dim := range siteScoreDimensions {
+				Param("min_" + dim)
+				Param("max_" + dim)
+			}
+			Param("category")
+			Param("author")
+			Param("from")
+			Param("to")
+			Param("cursor")
+			Param("limit")
+			// the body stays the plain array of earlier clients
+			Response(StatusOK, func() {
+				Header("next_cursor:X-Next-Cursor")
+				Body("items")
+			})
 			Response("not_found", StatusNotFound)
+			Response("bad_request", StatusBadRequest)
 		})
 	})
 
 	Method("articles", func() {
 		Description("List articles for a trend and domain.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(ArticlesPayload)
 		Result(ArrayOf(AnalyzedArticle))
 		HTTP(func() {
@@ -181,6 +460,7 @@ func defineWebMethods() {
 
 	Method("sentiments", func() {
 		Description("Get sentiment scores for a trend.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(SentimentsPayload)
 		Result(SentimentItem)
 		HTTP(func() {
@@ -196,6 +476,7 @@ func defineWebMethods() {
 
 	Method("domains", func() {
 		Description("List root domains.")
+		Security(BasicAuth, func() { Scope("items:read") })
 		Payload(DomainsPayload)
 		Result(ArrayOf(DomainEntry))
 		HTTP(func() {
@@ -206,6 +487,7 @@ func defineWebMethods() {
 
 	Method("topTrendsByDomain", func() {
 		Description("List top trends for a domain.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(TopTrendsByDomainPayload)
 		Result(ArrayOf(TrendMetric))
 		HTTP(func() {
@@ -221,6 +503,7 @@ func defineWebMethods() {
 
 	Method("contextByDomain", func() {
 		Description("List trend context for a domain.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(ContextByDomainPayload)
 		Result(ArrayOf(TrendContext))
 		HTTP(func() {
@@ -237,6 +520,7 @@ func defineWebMethods() {
 
 	Method("lifecycleByDomain", func() {
 		Description("List trend lifecycle data for a domain.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(LifecycleByDomainPayload)
 		Result(ArrayOf(Lifecycle))
 		HTTP(func() {
@@ -251,8 +535,22 @@ func defineWebMethods() {
 		})
 	})
 
+	Method("feedback", func() {
+		Description("Flag the rating or the corrected title of an item as wrong.")
+		Security(BasicAuth, func() { Scope("feedback:write") })
+		Payload(FeedbackPayload)
+		Error("too_many_requests", String, "Too much feedback from this client, API key or address")
+		HTTP(func() {
+			POST("/feedback")
+			Response(StatusAccepted)
+			Response("not_found", StatusNotFound)
+			Response("too_many_requests", StatusTooManyRequests)
+		})
+	})
+
 	Method("domainComparison", func() {
 		Description("Compare two domains for a trend.")
+		Security(BasicAuth, func() { Scope("trends:read") })
 		Payload(DomainComparisonPayload)
 		Result(ArrayOf(DomainComparison))
 		HTTP(func() {
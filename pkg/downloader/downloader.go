@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"goa.design/clue/log"
 )
 
@@ -88,11 +90,14 @@ func NewDownloader(ctx context.Context, cfg *config.Config, opts ...DownloaderOp
 }
 
 // DownloadRSSFeed downloads from http/https URLs
-func (d *downloader) DownloadRSSFeed(ctx context.Context, feed *url.URL) (io.ReadCloser, error) {
+func (d *downloader) DownloadRSSFeed(ctx context.Context, feed *url.URL) (rc io.ReadCloser, err error) {
 	if feed == nil {
 		return nil, errors.New("feed cannot be nil")
 	}
 
+	ctx, span := tracing.Start(ctx, "downloader.download_feed", attribute.String("url.full", feed.String()))
+	defer func() { tracing.End(span, err) }()
+
 	log.Printf(ctx, "downloading feed url=%s", feed.String())
 
 	switch feed.Scheme {
@@ -114,6 +119,7 @@ func (d *downloader) DownloadRSSFeed(ctx context.Context, feed *url.URL) (io.Rea
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch URL %q: %w", feed.String(), err)
 		}
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 		if resp.StatusCode != http.StatusOK {
 			// we have very stupid rss feeds
@@ -139,7 +145,10 @@ func (d *downloader) DownloadRSSFeed(ctx context.Context, feed *url.URL) (io.Rea
 }
 
 // ResolveRedirect performs a HEAD request to resolve the final URL after redirects.
-func (d *downloader) ResolveRedirect(ctx context.Context, targetURL string) (string, error) {
+func (d *downloader) ResolveRedirect(ctx context.Context, targetURL string) (resolved string, err error) {
+	ctx, span := tracing.Start(ctx, "downloader.resolve_redirect", attribute.String("url.full", targetURL))
+	defer func() { tracing.End(span, err) }()
+
 	req, err := http.NewRequestWithContext(ctx, "HEAD", targetURL, nil)
 	if err != nil {
 		return targetURL, err
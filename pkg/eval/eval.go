@@ -0,0 +1,242 @@
+// Package eval scores a think provider against a labeled dataset so prompt
+// and model changes can be compared before they reach the thinker.
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/think"
+)
+
+// Dimensions are the scored dimensions of a ThinkResult, in report order.
+var Dimensions = []string{"framing", "clickbait", "persuasive", "hyper_stimulus", "speculative", "overall"}
+
+// Range is an inclusive expected score range, written as [min, max] in the
+// dataset. A single expected value is a range with min == max.
+type Range [2]float64
+
+func (r *Range) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err == nil {
+		*r = Range{v, v}
+		return nil
+	}
+	var pair []float64
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 || pair[0] > pair[1] {
+		return fmt.Errorf("invalid range %s, expected [min, max]", data)
+	}
+	*r = Range{pair[0], pair[1]}
+	return nil
+}
+
+// Distance is 0 inside the range and the distance to the nearest bound outside.
+func (r Range) Distance(v float64) float64 {
+	switch {
+	case v < r[0]:
+		return r[0] - v
+	case v > r[1]:
+		return v - r[1]
+	default:
+		return 0
+	}
+}
+
+// Case is one labeled dataset line.
+type Case struct {
+	ID          string           `json:"id,omitempty"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Expected    map[string]Range `json:"expected,omitempty"`
+	Category    string           `json:"category,omitempty"`
+}
+
+// LoadDataset reads JSONL cases. Blank lines and lines starting with # are skipped.
+func LoadDataset(r io.Reader) ([]Case, error) {
+	var cases []Case
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.Title == "" && c.Description == "" {
+			return nil, fmt.Errorf("line %d: title or description is required", line)
+		}
+		for dim := range c.Expected {
+			if !slices.Contains(Dimensions, dim) {
+				return nil, fmt.Errorf("line %d: unknown dimension %q", line, dim)
+			}
+		}
+		if c.Language == "" {
+			c.Language = "en"
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// Outcome is the result of one case.
+type Outcome struct {
+	ID        string             `json:"id"`
+	Error     string             `json:"error,omitempty"`
+	ErrorKind string             `json:"error_kind,omitempty"`
+	Scores    map[string]float64 `json:"scores,omitempty"`
+	Category  string             `json:"category,omitempty"`
+	LatencyMs int64              `json:"latency_ms"`
+}
+
+// Report aggregates the outcomes of a run. Answers that could not be decoded
+// (think.ErrorInvalidJSON) are counted in InvalidJSON, as they point at the
+// prompt or the model rather than at the provider; Failures are all others.
+type Report struct {
+	Label            string             `json:"label"`
+	Cases            int                `json:"cases"`
+	Failures         int                `json:"failures"`
+	FailureRate      float64            `json:"failure_rate"`
+	InvalidJSON      int                `json:"invalid_json"`
+	InvalidJSONRate  float64            `json:"invalid_json_rate"`
+	MAE              map[string]float64 `json:"mae"`
+	CategoryAccuracy float64            `json:"category_accuracy"`
+	CategoryCases    int                `json:"category_cases"`
+	AvgLatencyMs     float64            `json:"avg_latency_ms"`
+	P95LatencyMs     int64              `json:"p95_latency_ms"`
+	Outcomes         []Outcome          `json:"outcomes"`
+}
+
+// Run analyzes every case with t and scores the answers. Failed calls count
+// towards the failure or invalid JSON rate and are left out of MAE and
+// category accuracy.
+func Run(label string, t think.Think, prompt string, cases []Case) *Report {
+	report := &Report{Label: label, Cases: len(cases), MAE: make(map[string]float64)}
+
+	errSum := make(map[string]float64)
+	errCount := make(map[string]int)
+	categoryHits := 0
+	var latencies []int64
+
+	for _, c := range cases {
+		start := time.Now()
+		res, err := t.Run(prompt, c.Language, think.Request{Title: c.Title, Description: c.Description}, false)
+		outcome := Outcome{ID: c.ID, LatencyMs: time.Since(start).Milliseconds()}
+		latencies = append(latencies, outcome.LatencyMs)
+
+		if err != nil {
+			kind := think.KindOf(err)
+			outcome.Error = err.Error()
+			outcome.ErrorKind = string(kind)
+			if kind == think.ErrorInvalidJSON {
+				report.InvalidJSON++
+			} else {
+				report.Failures++
+			}
+			report.Outcomes = append(report.Outcomes, outcome)
+			continue
+		}
+
+		outcome.Scores = scores(res)
+		outcome.Category = res.Category
+		for dim, expected := range c.Expected {
+			errSum[dim] += expected.Distance(outcome.Scores[dim])
+			errCount[dim]++
+		}
+		if c.Category != "" {
+			report.CategoryCases++
+			if strings.EqualFold(c.Category, res.Category) {
+				categoryHits++
+			}
+		}
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+
+	for dim, n := range errCount {
+		report.MAE[dim] = errSum[dim] / float64(n)
+	}
+	if report.Cases > 0 {
+		report.FailureRate = float64(report.Failures) / float64(report.Cases)
+		report.InvalidJSONRate = float64(report.InvalidJSON) / float64(report.Cases)
+	}
+	if report.CategoryCases > 0 {
+		report.CategoryAccuracy = float64(categoryHits) / float64(report.CategoryCases)
+	}
+	if len(latencies) > 0 {
+		var total int64
+		for _, l := range latencies {
+			total += l
+		}
+		report.AvgLatencyMs = float64(total) / float64(len(latencies))
+		slices.Sort(latencies)
+		report.P95LatencyMs = latencies[int(math.Ceil(0.95*float64(len(latencies))))-1]
+	}
+	return report
+}
+
+// Metric is one line of a comparison between two reports.
+type Metric struct {
+	Name  string  `json:"name"`
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+	// Better is true when B improves on A; lower is better except for accuracy.
+	Better bool `json:"better"`
+}
+
+// Compare lists the metrics of b against a.
+func Compare(a, b *Report) []Metric {
+	var metrics []Metric
+	add := func(name string, va, vb float64, higherIsBetter bool) {
+		better := vb < va
+		if higherIsBetter {
+			better = vb > va
+		}
+		metrics = append(metrics, Metric{Name: name, A: va, B: vb, Delta: vb - va, Better: better})
+	}
+	for _, dim := range Dimensions {
+		_, okA := a.MAE[dim]
+		_, okB := b.MAE[dim]
+		if okA || okB {
+			add("mae_"+dim, a.MAE[dim], b.MAE[dim], false)
+		}
+	}
+	add("category_accuracy", a.CategoryAccuracy, b.CategoryAccuracy, true)
+	add("failure_rate", a.FailureRate, b.FailureRate, false)
+	add("invalid_json_rate", a.InvalidJSONRate, b.InvalidJSONRate, false)
+	add("avg_latency_ms", a.AvgLatencyMs, b.AvgLatencyMs, false)
+	add("p95_latency_ms", float64(a.P95LatencyMs), float64(b.P95LatencyMs), false)
+	return metrics
+}
+
+func scores(res *database.ThinkResult) map[string]float64 {
+	return map[string]float64{
+		"framing":        res.Framing,
+		"clickbait":      res.Clickbait,
+		"persuasive":     res.Persuasive,
+		"hyper_stimulus": res.HyperStimulus,
+		"speculative":    res.Speculative,
+		"overall":        res.Overall,
+	}
+}
@@ -0,0 +1,121 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/think"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedThink struct {
+	res map[string]*database.ThinkResult
+}
+
+func (f *fixedThink) Run(prompt string, language string, request think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	res, ok := f.res[request.Title]
+	if !ok {
+		return nil, errors.New("connection reset")
+	}
+	if res == nil {
+		return nil, &think.Error{Kind: think.ErrorInvalidJSON, Err: errors.New("failed to unmarshal result")}
+	}
+	return res, nil
+}
+
+func TestLoadDataset(t *testing.T) {
+	file, err := os.Open("testdata/gold.jsonl")
+	assert.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	cases, err := LoadDataset(file)
+	assert.NoError(t, err)
+	if assert.Len(t, cases, 3) {
+		assert.Equal(t, "calm-business", cases[0].ID)
+		assert.Equal(t, Range{0, 0.2}, cases[0].Expected["clickbait"])
+		assert.Equal(t, Range{0.1, 0.1}, cases[2].Expected["overall"])
+		assert.Equal(t, "de", cases[2].Language)
+	}
+
+	_, err = LoadDataset(strings.NewReader(`{"title":"x","expected":{"sarcasm":[0,1]}}`))
+	assert.ErrorContains(t, err, `unknown dimension "sarcasm"`)
+
+	_, err = LoadDataset(strings.NewReader(`{"title":"x","expected":{"overall":[0.8,0.2]}}`))
+	assert.ErrorContains(t, err, "line 1")
+
+	cases, err = LoadDataset(strings.NewReader(`{"title":"x"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "en", cases[0].Language)
+	assert.Equal(t, "line-1", cases[0].ID)
+}
+
+func TestRun(t *testing.T) {
+	cases := []Case{
+		{ID: "a", Title: "a", Language: "en", Expected: map[string]Range{"overall": {0.2, 0.4}, "clickbait": {0, 0}}, Category: "business"},
+		{ID: "b", Title: "b", Language: "en", Expected: map[string]Range{"overall": {0.6, 1}}, Category: "politics"},
+		{ID: "c", Title: "c", Language: "en", Expected: map[string]Range{"overall": {0, 1}}},
+		{ID: "d", Title: "d", Language: "en", Expected: map[string]Range{"overall": {0, 1}}},
+	}
+	th := &fixedThink{res: map[string]*database.ThinkResult{
+		"a": {Overall: 0.3, Clickbait: 0.5, Category: "business"},
+		"b": {Overall: 0.4, Category: "world"},
+		"d": nil,
+	}}
+
+	report := Run("fixed", th, "deframer", cases)
+	assert.Equal(t, 4, report.Cases)
+	assert.Equal(t, 1, report.Failures)
+	assert.InDelta(t, 0.25, report.FailureRate, 1e-9)
+	assert.Equal(t, 1, report.InvalidJSON)
+	assert.InDelta(t, 0.25, report.InvalidJSONRate, 1e-9)
+	assert.Equal(t, "invalid_json", report.Outcomes[3].ErrorKind)
+	assert.InDelta(t, 0.1, report.MAE["overall"], 1e-9)
+	assert.InDelta(t, 0.5, report.MAE["clickbait"], 1e-9)
+	assert.NotContains(t, report.MAE, "framing")
+	assert.Equal(t, 2, report.CategoryCases)
+	assert.InDelta(t, 0.5, report.CategoryAccuracy, 1e-9)
+	assert.Equal(t, "connection reset", report.Outcomes[2].Error)
+	assert.Equal(t, "unknown", report.Outcomes[2].ErrorKind)
+
+	better := Run("better", &fixedThink{res: map[string]*database.ThinkResult{
+		"a": {Overall: 0.3, Category: "business"},
+		"b": {Overall: 0.7, Category: "politics"},
+		"c": {Overall: 0.5},
+		"d": {Overall: 0.5},
+	}}, "deframer", cases)
+	metrics := Compare(report, better)
+	byName := map[string]Metric{}
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+	assert.True(t, byName["mae_overall"].Better)
+	assert.InDelta(t, -0.1, byName["mae_overall"].Delta, 1e-9)
+	assert.True(t, byName["category_accuracy"].Better)
+	assert.True(t, byName["failure_rate"].Better)
+	assert.True(t, byName["invalid_json_rate"].Better)
+	assert.Equal(t, "mae_clickbait", metrics[0].Name)
+}
+
+func TestRun_Dummy(t *testing.T) {
+	th, err := think.New(context.Background(), &config.Config{LLM_Type: config.Dummy, LLM_Model: "dummy"})
+	assert.NoError(t, err)
+
+	file, err := os.Open("testdata/gold.jsonl")
+	assert.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	cases, err := LoadDataset(file)
+	assert.NoError(t, err)
+
+	report := Run("dummy", th, "deframer", cases)
+	assert.Equal(t, 0, report.Failures)
+	assert.Len(t, report.Outcomes, 3)
+	assert.Contains(t, report.MAE, "overall")
+	// the dummy always answers with the first category
+	assert.Equal(t, 3, report.CategoryCases)
+	assert.InDelta(t, 1.0/3.0, report.CategoryAccuracy, 1e-9)
+}
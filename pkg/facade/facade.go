@@ -2,17 +2,47 @@ package facade
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/url"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/deframer/news-deframer/pkg/util/netutil"
 )
 
+// MaxItemsForRootDomain is the default page size of GetItemsForRootDomain.
 const MaxItemsForRootDomain = 30
 
+// MaxSiteLimit is the largest page GetItemsForRootDomain returns.
+const MaxSiteLimit = 100
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ErrInvalidReview wraps the validation errors of ReviewItem.
+var ErrInvalidReview = errors.New("invalid review")
+
+// ErrInvalidCursor is returned for a page cursor the facade did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrRateLimited is returned by SubmitFeedback when a client sent too much
+// feedback and by AuthenticateAPIKey when a key used up its hourly requests.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrUnauthorized is returned for an unknown or revoked API key or a wrong secret.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrForbidden is returned for an API key without a scope the method requires.
+var ErrForbidden = errors.New("forbidden")
+
 type DomainEntry struct {
 	Domain    string               `json:"domain"`
 	Language  string               `json:"language"`
@@ -22,8 +52,16 @@ type DomainEntry struct {
 }
 
 type Facade interface {
-	GetItemsForRootDomain(ctx context.Context, rootDomain string, maxScore float64) ([]database.AnalyzedItem, error)
+	// GetItemsForRootDomain returns a page of the newest items of a root domain
+	// and the cursor of the next page, or "" on the last page.
+	GetItemsForRootDomain(ctx context.Context, rootDomain string, filter database.SiteFilter, cursor string, limit int) ([]database.AnalyzedItem, string, error)
 	GetFirstItemForUrl(ctx context.Context, u *url.URL) (*database.AnalyzedItem, error)
+	// GetFirstItemsForUrls looks up many URLs at once. The map is keyed by the
+	// URLs as given; URLs without an item are missing.
+	GetFirstItemsForUrls(ctx context.Context, urls []string) (map[string]database.AnalyzedItem, error)
+	// SearchItems runs a full-text search and returns a page of hits and the
+	// cursor of the next page, or "" on the last page.
+	SearchItems(ctx context.Context, search database.ItemSearch, cursor string) ([]database.SearchHit, string, error)
 	GetRootDomains(ctx context.Context) ([]DomainEntry, error)
 	GetTopTrendByDomain(ctx context.Context, domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error)
 	GetContextByDomain(ctx context.Context, term string, domain string, language string, date *time.Time, days int) ([]database.TrendContext, error)
@@ -31,44 +69,225 @@ type Facade interface {
 	GetDomainComparison(ctx context.Context, domainA string, domainB string, language string, date *time.Time, days int) ([]database.DomainComparison, error)
 	GetArticlesByTrend(ctx context.Context, term string, domain string, date *time.Time, days int, offset int, limit int) ([]database.AnalyzedArticle, error)
 	GetSentimentsByTrend(ctx context.Context, term string, domain string, date *time.Time, days int) (*database.SentimentItem, error)
+	// ReviewItem stores the review for every item with the URL and returns the
+	// reviewed item, or nil when there is no item for the URL.
+	ReviewItem(ctx context.Context, u *url.URL, review database.ItemReview) (*database.AnalyzedItem, error)
+	// DeleteItemReview removes the reviews of the items with the URL and
+	// reports whether there was an item.
+	DeleteItemReview(ctx context.Context, u *url.URL) (bool, error)
+	// SubmitFeedback stores reader feedback on the item with the hash and
+	// reports whether the item exists.
+	SubmitFeedback(ctx context.Context, hash string, feedback database.ItemFeedback) (bool, error)
+	// StreamAnalyzedItems sends the analyses finished after lastEventID and
+	// then every new one until ctx ends; 0 starts with the next analysis.
+	StreamAnalyzedItems(ctx context.Context, filter database.ItemEventFilter, lastEventID int64) (<-chan database.ItemEvent, error)
+	// AuthenticateAPIKey checks the key and its scopes and counts the request
+	// against its rate limit.
+	AuthenticateAPIKey(ctx context.Context, keyID string, secret string, requiredScopes []string) (*database.APIKey, error)
+}
+
+// Repository is what the facade needs from the database: the feeds and
+// items, the reviews that correct them, the feedback of readers and the API
+// keys of external clients.
+type Repository interface {
+	database.Repository
+	database.ReviewRepository
+	database.FeedbackRepository
+	database.APIKeyRepository
 }
 
 type facade struct {
-	ctx  context.Context
-	cfg  *config.Config
-	repo database.Repository
+	ctx    context.Context
+	cfg    *config.Config
+	repo   Repository
+	events *Notifier
 }
 
-func New(ctx context.Context, cfg *config.Config, repo database.Repository) Facade {
+// New returns the facade; events wakes its streams and is shared by all
+// facades of the process. Without it streams only send what they find when
+// they start.
+func New(ctx context.Context, cfg *config.Config, repo Repository, events *Notifier) Facade {
+	if events == nil {
+		events = newNotifier(nil)
+	}
 	return &facade{
-		ctx:  ctx,
-		cfg:  cfg,
-		repo: repo,
+		ctx:    ctx,
+		cfg:    cfg,
+		repo:   repo,
+		events: events,
 	}
 }
 
-func (f *facade) GetItemsForRootDomain(ctx context.Context, rootDomain string, maxScore float64) ([]database.AnalyzedItem, error) {
-	dbItems, err := f.repo.FindAnalyzedItemsByRootDomain(rootDomain, MaxItemsForRootDomain)
+// withContext returns the repository running its queries with ctx.
+func (f *facade) withContext(ctx context.Context) Repository {
+	if repo, ok := f.repo.WithContext(ctx).(Repository); ok {
+		return repo
+	}
+	return f.repo
+}
+
+func (f *facade) GetItemsForRootDomain(ctx context.Context, rootDomain string, filter database.SiteFilter, cursor string, limit int) ([]database.AnalyzedItem, string, error) {
+	if cursor != "" {
+		after, err := database.ParseSiteCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		filter.After = after
+	}
+	if limit <= 0 || limit > MaxSiteLimit {
+		limit = MaxItemsForRootDomain
+	}
+
+	items, err := f.withContext(ctx).FindAnalyzedItemsByRootDomain(rootDomain, filter, limit)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	next := ""
+	if len(items) == limit {
+		last := items[len(items)-1]
+		next = database.SiteCursor{PubDate: last.PubDate, ID: last.ID}.Encode()
+	}
+	return items, next, nil
+}
 
-	items := make([]database.AnalyzedItem, 0, len(dbItems))
-	for _, dbItem := range dbItems {
-		if maxScore > 0 && dbItem.ThinkRating > maxScore {
-			continue
+func (f *facade) GetFirstItemForUrl(ctx context.Context, u *url.URL) (*database.AnalyzedItem, error) {
+	return f.withContext(ctx).FindFirstAnalyzedItemByUrl(u)
+}
+
+func (f *facade) GetFirstItemsForUrls(ctx context.Context, urls []string) (map[string]database.AnalyzedItem, error) {
+	// items are stored with normalized URLs, the extension sends them as found in the page
+	requested := make(map[string][]string, len(urls))
+	normalized := make([]string, 0, len(urls))
+	for _, rawURL := range urls {
+		n := netutil.NormalizeURL(strings.TrimSpace(rawURL))
+		if _, ok := requested[n]; !ok {
+			normalized = append(normalized, n)
+		}
+		requested[n] = append(requested[n], rawURL)
+	}
+
+	found, err := f.withContext(ctx).FindFirstAnalyzedItemsByUrls(normalized)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string]database.AnalyzedItem, len(urls))
+	for n, item := range found {
+		for _, rawURL := range requested[n] {
+			items[rawURL] = item
 		}
-		items = append(items, dbItem)
 	}
 	return items, nil
 }
 
-func (f *facade) GetFirstItemForUrl(ctx context.Context, u *url.URL) (*database.AnalyzedItem, error) {
-	return f.repo.FindFirstAnalyzedItemByUrl(u)
+func (f *facade) SearchItems(ctx context.Context, search database.ItemSearch, cursor string) ([]database.SearchHit, string, error) {
+	if cursor != "" {
+		after, err := database.ParseSearchCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		search.After = after
+	}
+	if search.Limit <= 0 || search.Limit > MaxSearchLimit {
+		search.Limit = DefaultSearchLimit
+	}
+
+	hits, err := f.withContext(ctx).SearchItems(search)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(hits) == search.Limit {
+		next = hits[len(hits)-1].Cursor().Encode()
+	}
+	return hits, next, nil
+}
+
+func (f *facade) ReviewItem(ctx context.Context, u *url.URL, review database.ItemReview) (*database.AnalyzedItem, error) {
+	if err := review.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReview, err)
+	}
+	items, err := f.withContext(ctx).FindItemsByUrl(u)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	// syndicated copies of the article share the review
+	for _, item := range items {
+		itemReview := review
+		itemReview.ItemID = item.ID
+		if err := f.withContext(ctx).UpsertItemReview(&itemReview); err != nil {
+			return nil, err
+		}
+	}
+	return f.withContext(ctx).FindFirstAnalyzedItemByUrl(u)
+}
+
+func (f *facade) DeleteItemReview(ctx context.Context, u *url.URL) (bool, error) {
+	items, err := f.withContext(ctx).FindItemsByUrl(u)
+	if err != nil || len(items) == 0 {
+		return false, err
+	}
+	for _, item := range items {
+		if err := f.withContext(ctx).DeleteItemReview(item.ID); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (f *facade) SubmitFeedback(ctx context.Context, hash string, feedback database.ItemFeedback) (bool, error) {
+	// counted in the database, so the limits hold across service replicas
+	since := time.Now().Add(-time.Hour)
+	if f.cfg != nil && f.cfg.FeedbackRateLimit > 0 {
+		count, err := f.withContext(ctx).CountItemFeedbackSince(feedback.ClientID, since)
+		if err != nil {
+			return false, err
+		}
+		if count >= int64(f.cfg.FeedbackRateLimit) {
+			return false, ErrRateLimited
+		}
+	}
+	// a new client id per request must not lift the limit
+	if f.cfg != nil && f.cfg.FeedbackSourceRateLimit > 0 && feedback.Source != "" {
+		count, err := f.withContext(ctx).CountItemFeedbackFromSourceSince(feedback.Source, since)
+		if err != nil {
+			return false, err
+		}
+		if count >= int64(f.cfg.FeedbackSourceRateLimit) {
+			return false, ErrRateLimited
+		}
+	}
+	return f.withContext(ctx).CreateItemFeedback(hash, &feedback)
+}
+
+func (f *facade) AuthenticateAPIKey(ctx context.Context, keyID string, secret string, requiredScopes []string) (*database.APIKey, error) {
+	key, err := f.withContext(ctx).FindAPIKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.RevokedAt != nil {
+		return nil, ErrUnauthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(database.HashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrUnauthorized
+	}
+	for _, scope := range requiredScopes {
+		if !slices.Contains(key.Scopes, scope) {
+			return nil, fmt.Errorf("%w: missing scope %s", ErrForbidden, scope)
+		}
+	}
+
+	requests, err := f.withContext(ctx).RecordAPIKeyRequest(key.ID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RateLimit > 0 && requests > int64(key.RateLimit) {
+		return nil, ErrRateLimited
+	}
+	return key, nil
 }
 
 func (f *facade) GetRootDomains(ctx context.Context) ([]DomainEntry, error) {
-	feeds, err := f.repo.GetAllFeeds(false)
+	feeds, err := f.withContext(ctx).GetAllFeeds(false)
 	if err != nil {
 		return nil, err
 	}
@@ -133,25 +352,25 @@ func getSupportedDomainTags(tags database.StringArray) database.StringArray {
 }
 
 func (f *facade) GetTopTrendByDomain(ctx context.Context, domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error) {
-	return f.repo.GetTopTrendByDomain(domain, language, date, days)
+	return f.withContext(ctx).GetTopTrendByDomain(domain, language, date, days)
 }
 
 func (f *facade) GetContextByDomain(ctx context.Context, term string, domain string, language string, date *time.Time, days int) ([]database.TrendContext, error) {
-	return f.repo.GetContextByDomain(term, domain, language, date, days)
+	return f.withContext(ctx).GetContextByDomain(term, domain, language, date, days)
 }
 
 func (f *facade) GetLifecycleByDomain(ctx context.Context, term string, domain string, language string, date *time.Time, days int) ([]database.Lifecycle, error) {
-	return f.repo.GetLifecycleByDomain(term, domain, language, date, days)
+	return f.withContext(ctx).GetLifecycleByDomain(term, domain, language, date, days)
 }
 
 func (f *facade) GetDomainComparison(ctx context.Context, domainA string, domainB string, language string, date *time.Time, days int) ([]database.DomainComparison, error) {
-	return f.repo.GetDomainComparison(domainA, domainB, language, date, days, database.DomainComparisonUtilityThreshold, database.DomainComparisonOutlierRatioThreshold, database.DomainComparisonLimit)
+	return f.withContext(ctx).GetDomainComparison(domainA, domainB, language, date, days, database.DomainComparisonUtilityThreshold, database.DomainComparisonOutlierRatioThreshold, database.DomainComparisonLimit)
 }
 
 func (f *facade) GetArticlesByTrend(ctx context.Context, term string, domain string, date *time.Time, days int, offset int, limit int) ([]database.AnalyzedArticle, error) {
-	return f.repo.GetArticlesByTrend(term, domain, date, days, offset, limit)
+	return f.withContext(ctx).GetArticlesByTrend(term, domain, date, days, offset, limit)
 }
 
 func (f *facade) GetSentimentsByTrend(ctx context.Context, term string, domain string, date *time.Time, days int) (*database.SentimentItem, error) {
-	return f.repo.GetSentimentsByTrend(term, domain, date, days)
+	return f.withContext(ctx).GetSentimentsByTrend(term, domain, date, days)
 }
@@ -2,10 +2,13 @@ package facade
 
 import (
 	"context"
+	"fmt"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -30,14 +33,24 @@ type mockRepo struct {
 	beginThinkBatch               func(limit int, since time.Time, minErrorCount int, maxErrorCount int, lockDuration time.Duration) ([]database.Item, error)
 	findFeedScheduleById          func(feedID uuid.UUID) (*database.FeedSchedule, error)
 	findItemsByRootDomain         func(rootDomain string, limit int) ([]database.Item, error)
-	findAnalyzedItemsByRootDomain func(rootDomain string, limit int) ([]database.AnalyzedItem, error)
+	findAnalyzedItemsByRootDomain func(rootDomain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error)
 	findFirstAnalyzedItemByUrl    func(u *url.URL) (*database.AnalyzedItem, error)
+	findFirstAnalyzedItemsByUrls  func(urls []string) (map[string]database.AnalyzedItem, error)
+	searchItems                   func(search database.ItemSearch) ([]database.SearchHit, error)
+	findItemEventsAfter           func(after int64, filter database.ItemEventFilter, limit int) ([]database.ItemEvent, error)
+	latestItemEventID             func() (int64, error)
 	getTopTrendByDomain           func(domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error)
 	getContextByDomain            func(term string, domain string, language string, date *time.Time, days int) ([]database.TrendContext, error)
 	getLifecycleByDomain          func(term string, domain string, language string, date *time.Time, days int) ([]database.Lifecycle, error)
 	getDomainComparison           func(domainA string, domainB string, language string, date *time.Time, days int, utilityThreshold float64, outlierRatioThreshold float64, limit int) ([]database.DomainComparison, error)
 	getArticlesByTrend            func(term string, domain string, date *time.Time, days int, offset int, limit int) ([]database.AnalyzedArticle, error)
 	getSentimentsByTrend          func(term string, domain string, date *time.Time, days int) (*database.SentimentItem, error)
+	upsertItemReview              func(review *database.ItemReview) error
+	createItemFeedback            func(hash string, feedback *database.ItemFeedback) (bool, error)
+	countItemFeedbackSince        func(clientID string, since time.Time) (int64, error)
+	countFeedbackFromSource       func(source string, since time.Time) (int64, error)
+	findAPIKey                    func(keyID string) (*database.APIKey, error)
+	recordAPIKeyRequest           func(id uuid.UUID) (int64, error)
 }
 
 func mustParseTestDate(raw string) *time.Time {
@@ -215,6 +228,106 @@ func (m *mockRepo) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string, l
 	return nil, nil
 }
 
+func (m *mockRepo) AddFeedLanguageStats(feedID uuid.UUID, counts map[string]int64) error {
+	return nil
+}
+
+func (m *mockRepo) GetFeedLanguageStats() ([]database.FeedLanguageStatsResult, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) ListThinkResultHistory(itemID uuid.UUID) ([]database.ThinkResultRecord, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) RollbackThinkResults(llmModel string, since time.Time) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) UpsertItemReview(review *database.ItemReview) error {
+	if m.upsertItemReview != nil {
+		return m.upsertItemReview(review)
+	}
+	return nil
+}
+
+func (m *mockRepo) DeleteItemReview(itemID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockRepo) ListItemReviews() ([]database.ItemReview, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) CreateItemFeedback(hash string, feedback *database.ItemFeedback) (bool, error) {
+	if m.createItemFeedback != nil {
+		return m.createItemFeedback(hash, feedback)
+	}
+	return false, nil
+}
+
+func (m *mockRepo) CountItemFeedbackSince(clientID string, since time.Time) (int64, error) {
+	if m.countItemFeedbackSince != nil {
+		return m.countItemFeedbackSince(clientID, since)
+	}
+	return 0, nil
+}
+
+func (m *mockRepo) CountItemFeedbackFromSourceSince(source string, since time.Time) (int64, error) {
+	if m.countFeedbackFromSource != nil {
+		return m.countFeedbackFromSource(source, since)
+	}
+	return 0, nil
+}
+
+func (m *mockRepo) GetFeedbackReport(since time.Time, groupBy string, limit int) ([]database.FeedbackReport, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) CountLockWaits() (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) Ping(timeout time.Duration) error {
+	return nil
+}
+
+func (m *mockRepo) GetSchemaStatus(timeout time.Duration) (*database.SchemaStatus, error) {
+	return &database.SchemaStatus{Version: database.SchemaVersion}, nil
+}
+
+func (m *mockRepo) WithContext(ctx context.Context) database.Repository {
+	return m
+}
+
+func (m *mockRepo) CountThinkerUpdateCandidates(llmModel string, promptVersions map[string]string) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (m *mockRepo) GetThinkerQueueDepth(maxRetries int, maxFixerErrorCount int) (*database.ThinkerQueueDepth, error) {
+	return &database.ThinkerQueueDepth{}, nil
+}
+
+func (m *mockRepo) GetDeadItemGroups(filter database.DeadItemFilter) ([]database.DeadItemGroup, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) FindDeadItems(filter database.DeadItemFilter, limit int) ([]database.Item, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) RequeueDeadItems(filter database.DeadItemFilter) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) PurgeDeadItems(filter database.DeadItemFilter) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) BeginThinkerUpdatePromptVersionBatch(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]database.Item, error) {
+	return nil, nil
+}
+
 func (m *mockRepo) FindFeedScheduleById(feedID uuid.UUID) (*database.FeedSchedule, error) {
 	if m.findFeedScheduleById != nil {
 		return m.findFeedScheduleById(feedID)
@@ -233,9 +346,9 @@ func (m *mockRepo) FindItemsByRootDomain(rootDomain string, limit int) ([]databa
 	return nil, nil
 }
 
-func (m *mockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, limit int) ([]database.AnalyzedItem, error) {
+func (m *mockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
 	if m.findAnalyzedItemsByRootDomain != nil {
-		return m.findAnalyzedItemsByRootDomain(rootDomain, limit)
+		return m.findAnalyzedItemsByRootDomain(rootDomain, filter, limit)
 	}
 	return nil, nil
 }
@@ -247,6 +360,64 @@ func (m *mockRepo) FindFirstAnalyzedItemByUrl(u *url.URL) (*database.AnalyzedIte
 	return nil, nil
 }
 
+func (m *mockRepo) FindFirstAnalyzedItemsByUrls(urls []string) (map[string]database.AnalyzedItem, error) {
+	if m.findFirstAnalyzedItemsByUrls != nil {
+		return m.findFirstAnalyzedItemsByUrls(urls)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) SearchItems(search database.ItemSearch) ([]database.SearchHit, error) {
+	if m.searchItems != nil {
+		return m.searchItems(search)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) FindItemEventsAfter(after int64, filter database.ItemEventFilter, limit int) ([]database.ItemEvent, error) {
+	if m.findItemEventsAfter != nil {
+		return m.findItemEventsAfter(after, filter, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) LatestItemEventID() (int64, error) {
+	if m.latestItemEventID != nil {
+		return m.latestItemEventID()
+	}
+	return 0, nil
+}
+
+func (m *mockRepo) CreateAPIKey(key *database.APIKey) error {
+	return nil
+}
+
+func (m *mockRepo) ListAPIKeys() ([]database.APIKey, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) RevokeAPIKey(id uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (m *mockRepo) GetAPIKeyUsage(since time.Time) ([]database.APIKeyUsageReport, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) FindAPIKey(keyID string) (*database.APIKey, error) {
+	if m.findAPIKey != nil {
+		return m.findAPIKey(keyID)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) RecordAPIKeyRequest(id uuid.UUID) (int64, error) {
+	if m.recordAPIKeyRequest != nil {
+		return m.recordAPIKeyRequest(id)
+	}
+	return 0, nil
+}
+
 func (m *mockRepo) GetTopTrendByDomain(domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error) {
 	if m.getTopTrendByDomain != nil {
 		return m.getTopTrendByDomain(domain, language, date, days)
@@ -318,18 +489,20 @@ func TestGetItemsForRootDomain(t *testing.T) {
 		}
 
 		mockR := &mockRepo{
-			findAnalyzedItemsByRootDomain: func(domain string, limit int) ([]database.AnalyzedItem, error) {
+			findAnalyzedItemsByRootDomain: func(domain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
 				assert.Equal(t, rootDomain, domain)
 				assert.Equal(t, MaxItemsForRootDomain, limit)
+				assert.Nil(t, filter.After)
 				return expectedItems, nil
 			},
 		}
 
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 
-		items, err := f.GetItemsForRootDomain(ctx, rootDomain, 0.0)
+		items, next, err := f.GetItemsForRootDomain(ctx, rootDomain, database.SiteFilter{}, "", 0)
 		assert.NoError(t, err)
 		assert.Len(t, items, 2)
+		assert.Empty(t, next)
 
 		// Verify Item 1
 		assert.Equal(t, "hash1", items[0].Hash)
@@ -351,37 +524,57 @@ func TestGetItemsForRootDomain(t *testing.T) {
 	})
 
 	t.Run("WithFilter", func(t *testing.T) {
-		expectedItems := []database.AnalyzedItem{
-			{
-				Hash:        "hash1",
-				ThinkRating: 0.8,
-			},
-			{
-				Hash:        "hash2",
-				ThinkRating: 0.2,
+		filter := database.SiteFilter{Category: "politics", MaxScores: map[string]float64{"overall": 0.5}}
+		mockR := &mockRepo{
+			findAnalyzedItemsByRootDomain: func(domain string, got database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
+				assert.Equal(t, filter, got)
+				return []database.AnalyzedItem{{Hash: "hash2", ThinkRating: 0.2}}, nil
 			},
 		}
+		f := New(ctx, nil, mockR, nil)
+		items, _, err := f.GetItemsForRootDomain(ctx, rootDomain, filter, "", 0)
+		assert.NoError(t, err)
+		assert.Len(t, items, 1)
+		assert.Equal(t, "hash2", items[0].Hash)
+	})
 
+	t.Run("Paging", func(t *testing.T) {
+		last := database.AnalyzedItem{ID: uuid.New(), Hash: "hash2", PubDate: time.Now().Add(-time.Hour)}
 		mockR := &mockRepo{
-			findAnalyzedItemsByRootDomain: func(domain string, limit int) ([]database.AnalyzedItem, error) {
-				return expectedItems, nil
+			findAnalyzedItemsByRootDomain: func(domain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
+				assert.Equal(t, 2, limit)
+				if filter.After == nil {
+					return []database.AnalyzedItem{{ID: uuid.New(), Hash: "hash1", PubDate: time.Now()}, last}, nil
+				}
+				assert.Equal(t, last.ID, filter.After.ID)
+				assert.True(t, last.PubDate.Equal(filter.After.PubDate))
+				return []database.AnalyzedItem{{ID: uuid.New(), Hash: "hash3"}}, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
-		items, err := f.GetItemsForRootDomain(ctx, rootDomain, 0.5)
+		f := New(ctx, nil, mockR, nil)
+
+		items, next, err := f.GetItemsForRootDomain(ctx, rootDomain, database.SiteFilter{}, "", 2)
+		assert.NoError(t, err)
+		assert.Len(t, items, 2)
+		assert.NotEmpty(t, next)
+
+		items, next, err = f.GetItemsForRootDomain(ctx, rootDomain, database.SiteFilter{}, next, 2)
 		assert.NoError(t, err)
 		assert.Len(t, items, 1)
-		assert.Equal(t, "hash2", items[0].Hash)
+		assert.Empty(t, next)
+
+		_, _, err = f.GetItemsForRootDomain(ctx, rootDomain, database.SiteFilter{}, "garbage!", 2)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
 	})
 
 	t.Run("RepoError", func(t *testing.T) {
 		mockR := &mockRepo{
-			findAnalyzedItemsByRootDomain: func(domain string, limit int) ([]database.AnalyzedItem, error) {
+			findAnalyzedItemsByRootDomain: func(domain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
 				return nil, assert.AnError
 			},
 		}
-		f := New(ctx, nil, mockR)
-		items, err := f.GetItemsForRootDomain(ctx, rootDomain, 0.0)
+		f := New(ctx, nil, mockR, nil)
+		items, _, err := f.GetItemsForRootDomain(ctx, rootDomain, database.SiteFilter{}, "", 0)
 		assert.Error(t, err)
 		assert.Nil(t, items)
 	})
@@ -412,7 +605,7 @@ func TestGetFirstItemForUrl(t *testing.T) {
 			},
 		}
 
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 
 		item, err := f.GetFirstItemForUrl(ctx, u)
 		assert.NoError(t, err)
@@ -430,13 +623,240 @@ func TestGetFirstItemForUrl(t *testing.T) {
 				return nil, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		item, err := f.GetFirstItemForUrl(ctx, u)
 		assert.NoError(t, err)
 		assert.Nil(t, item)
 	})
 }
 
+func TestGetFirstItemsForUrls(t *testing.T) {
+	ctx := context.Background()
+	item := database.AnalyzedItem{Hash: "hash1", URL: "http://example.com/article"}
+
+	mockR := &mockRepo{
+		findFirstAnalyzedItemsByUrls: func(urls []string) (map[string]database.AnalyzedItem, error) {
+			assert.Equal(t, []string{"http://example.com/article", "http://example.com/other"}, urls)
+			return map[string]database.AnalyzedItem{item.URL: item}, nil
+		},
+	}
+	f := New(ctx, nil, mockR, nil)
+
+	items, err := f.GetFirstItemsForUrls(ctx, []string{
+		"http://example.com/article/?utm_source=rss#top",
+		"http://example.com/article",
+		"http://example.com/other",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "hash1", items["http://example.com/article/?utm_source=rss#top"].Hash)
+	assert.Equal(t, "hash1", items["http://example.com/article"].Hash)
+	assert.NotContains(t, items, "http://example.com/other")
+}
+
+func TestSearchItems(t *testing.T) {
+	ctx := context.Background()
+	hit := func(rank float32) database.SearchHit {
+		return database.SearchHit{Item: database.AnalyzedItem{ID: uuid.New(), PubDate: time.Now()}, Rank: rank}
+	}
+
+	t.Run("NextCursorOnFullPage", func(t *testing.T) {
+		hits := []database.SearchHit{hit(0.5), hit(0.3)}
+		mockR := &mockRepo{
+			searchItems: func(search database.ItemSearch) ([]database.SearchHit, error) {
+				assert.Equal(t, "climate", search.Query)
+				assert.Equal(t, 2, search.Limit)
+				assert.Nil(t, search.After)
+				return hits, nil
+			},
+		}
+		f := New(ctx, nil, mockR, nil)
+
+		result, next, err := f.SearchItems(ctx, database.ItemSearch{Query: "climate", Limit: 2}, "")
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.NotEmpty(t, next)
+
+		cursor, err := database.ParseSearchCursor(next)
+		assert.NoError(t, err)
+		assert.Equal(t, hits[1].Item.ID, cursor.ID)
+		assert.Equal(t, hits[1].Rank, cursor.Rank)
+	})
+
+	t.Run("LastPage", func(t *testing.T) {
+		after := hit(0.4).Cursor()
+		mockR := &mockRepo{
+			searchItems: func(search database.ItemSearch) ([]database.SearchHit, error) {
+				assert.Equal(t, DefaultSearchLimit, search.Limit)
+				if assert.NotNil(t, search.After) {
+					assert.Equal(t, after.ID, search.After.ID)
+				}
+				return []database.SearchHit{hit(0.2)}, nil
+			},
+		}
+		f := New(ctx, nil, mockR, nil)
+
+		result, next, err := f.SearchItems(ctx, database.ItemSearch{Query: "climate"}, after.Encode())
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Empty(t, next)
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		f := New(ctx, nil, &mockRepo{}, nil)
+		_, _, err := f.SearchItems(ctx, database.ItemSearch{Query: "climate"}, "not a cursor")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestReviewItem(t *testing.T) {
+	ctx := context.Background()
+	u, _ := url.Parse("http://example.com/article")
+	overall := 0.1
+
+	t.Run("ReviewsAllCopies", func(t *testing.T) {
+		var reviewed []uuid.UUID
+		mockR := &mockRepo{
+			findItemsByUrl: func(u *url.URL) ([]database.Item, error) {
+				return []database.Item{{ID: uuid.New()}, {ID: uuid.New()}}, nil
+			},
+			upsertItemReview: func(review *database.ItemReview) error {
+				assert.Equal(t, "alice", review.Reviewer)
+				reviewed = append(reviewed, review.ItemID)
+				return nil
+			},
+			findFirstAnalyzedItemByUrl: func(u *url.URL) (*database.AnalyzedItem, error) {
+				return &database.AnalyzedItem{URL: u.String(), ThinkRating: overall}, nil
+			},
+		}
+		f := New(ctx, nil, mockR, nil)
+		item, err := f.ReviewItem(ctx, u, database.ItemReview{Reviewer: "alice", Overall: &overall})
+		assert.NoError(t, err)
+		assert.NotNil(t, item)
+		assert.Len(t, reviewed, 2)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tooHigh := 1.5
+		category := "gossip"
+		f := New(ctx, nil, &mockRepo{}, nil)
+		_, err := f.ReviewItem(ctx, u, database.ItemReview{Overall: &tooHigh})
+		assert.ErrorIs(t, err, ErrInvalidReview)
+		_, err = f.ReviewItem(ctx, u, database.ItemReview{Category: &category})
+		assert.ErrorIs(t, err, ErrInvalidReview)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		f := New(ctx, nil, &mockRepo{
+			findItemsByUrl: func(u *url.URL) ([]database.Item, error) {
+				return nil, nil
+			},
+		}, nil)
+		item, err := f.ReviewItem(ctx, u, database.ItemReview{Overall: &overall})
+		assert.NoError(t, err)
+		assert.Nil(t, item)
+	})
+}
+
+func TestSubmitFeedback(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{FeedbackRateLimit: 2, FeedbackSourceRateLimit: 3}
+	submitted := map[string]int64{}
+	fromSource := map[string]int64{}
+	mockR := &mockRepo{
+		countItemFeedbackSince: func(clientID string, since time.Time) (int64, error) {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), since, time.Minute)
+			return submitted[clientID], nil
+		},
+		countFeedbackFromSource: func(source string, since time.Time) (int64, error) {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), since, time.Minute)
+			return fromSource[source], nil
+		},
+		createItemFeedback: func(hash string, feedback *database.ItemFeedback) (bool, error) {
+			if hash != "known" {
+				return false, nil
+			}
+			submitted[feedback.ClientID]++
+			fromSource[feedback.Source]++
+			return true, nil
+		},
+	}
+	f := New(ctx, cfg, mockR, nil)
+
+	found, err := f.SubmitFeedback(ctx, "unknown", database.ItemFeedback{ClientID: "client-a", Kind: database.FeedbackTitleWrong})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	for range 2 {
+		found, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: "client-a", Kind: database.FeedbackRatingTooHigh})
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+	_, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: "client-a", Kind: database.FeedbackRatingTooLow})
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// the limit is per client
+	found, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: "client-b", Kind: database.FeedbackRatingTooLow})
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// fresh client ids from one address run into the limit of the source
+	for i := range 3 {
+		found, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: fmt.Sprintf("rotating-%d", i), Source: "192.0.2.1", Kind: database.FeedbackRatingTooLow})
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+	_, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: "rotating-3", Source: "192.0.2.1", Kind: database.FeedbackRatingTooLow})
+	assert.ErrorIs(t, err, ErrRateLimited)
+	found, err = f.SubmitFeedback(ctx, "known", database.ItemFeedback{ClientID: "rotating-3", Source: "dfk_0123456789abcdef", Kind: database.FeedbackRatingTooLow})
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	key, secret, err := database.GenerateAPIKey("extension", []string{database.ScopeItemsRead}, 2)
+	assert.NoError(t, err)
+	key.ID = uuid.New()
+
+	requests := int64(0)
+	mockR := &mockRepo{
+		findAPIKey: func(keyID string) (*database.APIKey, error) {
+			if keyID != key.KeyID {
+				return nil, nil
+			}
+			return key, nil
+		},
+		recordAPIKeyRequest: func(id uuid.UUID) (int64, error) {
+			assert.Equal(t, key.ID, id)
+			requests++
+			return requests, nil
+		},
+	}
+	f := New(ctx, nil, mockR, nil)
+
+	_, err = f.AuthenticateAPIKey(ctx, "dfk_unknown", secret, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = f.AuthenticateAPIKey(ctx, key.KeyID, "wrong", nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = f.AuthenticateAPIKey(ctx, key.KeyID, secret, []string{database.ScopeTrendsRead})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.Zero(t, requests, "rejected requests are not counted")
+
+	for range 2 {
+		authenticated, err := f.AuthenticateAPIKey(ctx, key.KeyID, secret, []string{database.ScopeItemsRead})
+		assert.NoError(t, err)
+		assert.Equal(t, key.ID, authenticated.ID)
+	}
+	_, err = f.AuthenticateAPIKey(ctx, key.KeyID, secret, []string{database.ScopeItemsRead})
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	revokedAt := time.Now()
+	key.RevokedAt = &revokedAt
+	_, err = f.AuthenticateAPIKey(ctx, key.KeyID, secret, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
 func TestGetRootDomains(t *testing.T) {
 	ctx := context.Background()
 
@@ -494,7 +914,7 @@ func TestGetRootDomains(t *testing.T) {
 			},
 		}
 
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		domains, err := f.GetRootDomains(ctx)
 		assert.NoError(t, err)
 		assert.Equal(t, []DomainEntry{
@@ -510,7 +930,7 @@ func TestGetRootDomains(t *testing.T) {
 			},
 		}
 
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		domains, err := f.GetRootDomains(ctx)
 		assert.Error(t, err)
 		assert.Nil(t, domains)
@@ -534,7 +954,7 @@ func TestGetTopTrendByDomain(t *testing.T) {
 				return expected, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetTopTrendByDomain(ctx, domain, lang, date, days)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, res)
@@ -546,7 +966,7 @@ func TestGetTopTrendByDomain(t *testing.T) {
 				return nil, assert.AnError
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetTopTrendByDomain(ctx, domain, lang, date, days)
 		assert.Error(t, err)
 		assert.Nil(t, res)
@@ -571,7 +991,7 @@ func TestGetContextByDomain(t *testing.T) {
 				return expected, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetContextByDomain(ctx, term, domain, lang, date, days)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, res)
@@ -598,7 +1018,7 @@ func TestGetLifecycleByDomain(t *testing.T) {
 				return expected, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetLifecycleByDomain(ctx, term, domain, language, date, days)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, res)
@@ -610,7 +1030,7 @@ func TestGetLifecycleByDomain(t *testing.T) {
 				return nil, assert.AnError
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetLifecycleByDomain(ctx, term, domain, language, date, days)
 		assert.Error(t, err)
 		assert.Nil(t, res)
@@ -640,7 +1060,7 @@ func TestGetDomainComparison(t *testing.T) {
 				return expected, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetDomainComparison(ctx, domainA, domainB, lang, date, days)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, res)
@@ -652,7 +1072,7 @@ func TestGetDomainComparison(t *testing.T) {
 				return nil, assert.AnError
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetDomainComparison(ctx, domainA, domainB, lang, date, days)
 		assert.Error(t, err)
 		assert.Nil(t, res)
@@ -688,7 +1108,7 @@ func TestGetArticlesByTrend(t *testing.T) {
 				return expectedItems, nil
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 
 		items, err := f.GetArticlesByTrend(ctx, term, domain, date, days, 0, 10)
 		assert.NoError(t, err)
@@ -711,9 +1131,63 @@ func TestGetArticlesByTrend(t *testing.T) {
 				return nil, assert.AnError
 			},
 		}
-		f := New(ctx, nil, mockR)
+		f := New(ctx, nil, mockR, nil)
 		res, err := f.GetArticlesByTrend(ctx, term, domain, date, days, 0, 10)
 		assert.Error(t, err)
 		assert.Nil(t, res)
 	})
 }
+
+func TestStreamAnalyzedItems(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		stored = []database.ItemEvent{
+			{EventID: 4, Item: database.AnalyzedItem{Hash: "old"}},
+			{EventID: 5, Item: database.AnalyzedItem{Hash: "missed"}},
+		}
+	)
+	filter := database.ItemEventFilter{RootDomain: "example.com"}
+	mockR := &mockRepo{
+		findItemEventsAfter: func(after int64, got database.ItemEventFilter, limit int) ([]database.ItemEvent, error) {
+			assert.Equal(t, filter, got)
+			mu.Lock()
+			defer mu.Unlock()
+			var events []database.ItemEvent
+			for _, event := range stored {
+				if event.EventID > after {
+					events = append(events, event)
+				}
+			}
+			return events, nil
+		},
+	}
+	events := newNotifier(nil)
+	f := &facade{ctx: ctx, repo: mockR, events: events}
+
+	// resuming after event 4 delivers the missed event 5 first
+	stream, err := f.StreamAnalyzedItems(ctx, filter, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "missed", (<-stream).Item.Hash)
+
+	mu.Lock()
+	stored = append(stored, database.ItemEvent{EventID: 6, Item: database.AnalyzedItem{Hash: "live"}})
+	mu.Unlock()
+	events.broadcast()
+	select {
+	case event := <-stream:
+		assert.Equal(t, int64(6), event.EventID)
+		assert.Equal(t, "live", event.Item.Hash)
+	case <-time.After(time.Second):
+		t.Fatal("no event after the wake-up")
+	}
+
+	cancel()
+	for range stream {
+	}
+	events.mu.Lock()
+	assert.Empty(t, events.subs)
+	events.mu.Unlock()
+}
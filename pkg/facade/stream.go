@@ -0,0 +1,121 @@
+package facade
+
+import (
+	"context"
+	"sync"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/database"
+	"goa.design/clue/log"
+)
+
+// streamBatchSize bounds the events loaded per query of a stream.
+const streamBatchSize = 100
+
+// Notifier fans the wake-ups of the one LISTEN connection of the process out to
+// the open streams. Each stream then loads what is new after its own last event,
+// which also covers clients resuming with Last-Event-ID. Create one per process
+// and share it between the facades.
+type Notifier struct {
+	source func() <-chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+// NewNotifier returns a notifier listening for new analyses until ctx ends. The
+// connection is opened with the first stream.
+func NewNotifier(ctx context.Context, cfg *config.Config) *Notifier {
+	return newNotifier(func() <-chan struct{} {
+		return database.ListenItemsAnalyzed(ctx, cfg)
+	})
+}
+
+func newNotifier(source func() <-chan struct{}) *Notifier {
+	return &Notifier{source: source, subs: make(map[chan struct{}]struct{})}
+}
+
+// subscribe returns a channel woken after new analyses and its cancel func.
+// The source is started with the first subscription.
+func (n *Notifier) subscribe() (<-chan struct{}, func()) {
+	n.once.Do(func() {
+		if n.source != nil {
+			go n.run(n.source())
+		}
+	})
+
+	wake := make(chan struct{}, 1)
+	n.mu.Lock()
+	n.subs[wake] = struct{}{}
+	n.mu.Unlock()
+	return wake, func() {
+		n.mu.Lock()
+		delete(n.subs, wake)
+		n.mu.Unlock()
+	}
+}
+
+func (n *Notifier) run(source <-chan struct{}) {
+	for range source {
+		n.broadcast()
+	}
+}
+
+func (n *Notifier) broadcast() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for wake := range n.subs {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (f *facade) StreamAnalyzedItems(ctx context.Context, filter database.ItemEventFilter, lastEventID int64) (<-chan database.ItemEvent, error) {
+	// subscribe first, an analysis finished in between is found by the first query
+	wake, unsubscribe := f.events.subscribe()
+	if lastEventID <= 0 {
+		latest, err := f.repo.LatestItemEventID()
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		lastEventID = latest
+	}
+
+	events := make(chan database.ItemEvent)
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+		after := lastEventID
+		for {
+			for {
+				batch, err := f.repo.FindItemEventsAfter(after, filter, streamBatchSize)
+				if err != nil {
+					log.Errorf(ctx, err, "FindItemEventsAfter failed")
+					break
+				}
+				for _, event := range batch {
+					select {
+					case events <- event:
+						after = event.EventID
+					case <-ctx.Done():
+						return
+					}
+				}
+				if len(batch) < streamBatchSize {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,142 @@
+// Package langdetect identifies the language of short news texts offline with
+// a character trigram model built from the embedded sample texts.
+package langdetect
+
+import (
+	"embed"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed corpus/*.txt
+var corpusFS embed.FS
+
+// evidenceCap limits how many trigrams count towards the confidence. Without
+// it every long text looks certain, even when the languages are close.
+const evidenceCap = 24
+
+// minLetters is the shortest text that is classified at all.
+const minLetters = 12
+
+// Undetermined is the ISO 639-2 code for texts whose language is unknown.
+const Undetermined = "und"
+
+// Result is the detected language with its confidence in [0, 1].
+type Result struct {
+	Language   string
+	Confidence float64
+}
+
+type profile struct {
+	logProb map[string]float64
+	unseen  float64
+}
+
+var profiles = buildProfiles()
+
+func buildProfiles() map[string]*profile {
+	files, err := fs.Glob(corpusFS, "corpus/*.txt")
+	if err != nil {
+		panic(err)
+	}
+
+	counts := make(map[string]map[string]int, len(files))
+	vocabulary := make(map[string]struct{})
+	for _, file := range files {
+		content, err := corpusFS.ReadFile(file)
+		if err != nil {
+			// the corpus is part of the binary; this is a build error
+			panic(err)
+		}
+		lang := strings.TrimSuffix(path.Base(file), ".txt")
+		counts[lang] = make(map[string]int)
+		for _, tri := range trigrams(string(content)) {
+			counts[lang][tri]++
+			vocabulary[tri] = struct{}{}
+		}
+	}
+
+	// add-one smoothing over the shared vocabulary
+	v := float64(len(vocabulary))
+	built := make(map[string]*profile, len(counts))
+	for lang, c := range counts {
+		total := 0
+		for _, n := range c {
+			total += n
+		}
+		p := &profile{logProb: make(map[string]float64, len(c)), unseen: math.Log(1 / (float64(total) + v))}
+		for tri, n := range c {
+			p.logProb[tri] = math.Log((float64(n) + 1) / (float64(total) + v))
+		}
+		built[lang] = p
+	}
+	return built
+}
+
+// Languages returns the sorted codes of the languages that can be detected.
+func Languages() []string {
+	languages := make([]string, 0, len(profiles))
+	for lang := range profiles {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Detect returns the most likely language of text. Texts that are too short
+// to tell yield an empty language with zero confidence.
+func Detect(text string) Result {
+	tris := trigrams(text)
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters || len(tris) == 0 {
+		return Result{}
+	}
+
+	scores := make(map[string]float64, len(profiles))
+	for lang, p := range profiles {
+		score := 0.0
+		for _, tri := range tris {
+			if lp, ok := p.logProb[tri]; ok {
+				score += lp
+			} else {
+				score += p.unseen
+			}
+		}
+		// average log-likelihood per trigram, weighed by the capped evidence
+		scores[lang] = score / float64(len(tris)) * math.Min(float64(len(tris)), evidenceCap)
+	}
+
+	best, bestScore := "", math.Inf(-1)
+	for lang, score := range scores {
+		if score > bestScore || (score == bestScore && lang < best) {
+			best, bestScore = lang, score
+		}
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+	return Result{Language: best, Confidence: 1 / sum}
+}
+
+// trigrams splits text into lower case letter trigrams per word, with the
+// word boundaries as spaces, e.g. "Der" yields " de", "der", "er ".
+func trigrams(text string) []string {
+	var tris []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			tris = append(tris, string(runes[i:i+3]))
+		}
+	}
+	return tris
+}
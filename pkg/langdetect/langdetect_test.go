@@ -0,0 +1,49 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		lang string
+	}{
+		{"Trump says tariffs on Chinese goods will stay in place until a deal is signed", "en"},
+		{"Scholz kündigt neues Entlastungspaket für Rentner an", "de"},
+		{"Politiet efterlyser vidner til røveri i Aarhus", "da"},
+		{"Regeringen vill sänka skatten på arbete", "sv"},
+		{"Ajax verliest van Feyenoord in de Klassieker", "nl"},
+		{"El Real Madrid gana la Liga por tercera vez consecutiva", "es"},
+		{"Incendie dans un immeuble à Marseille : trois blessés", "fr"},
+		{"Il governo approva la manovra economica", "it"},
+		{"Lula anuncia novo programa de habitação", "pt"},
+		{"Sejm przyjął ustawę o podatkach", "pl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			res := Detect(tt.text)
+			assert.Equal(t, tt.lang, res.Language)
+			assert.Greater(t, res.Confidence, 0.95)
+		})
+	}
+}
+
+func TestDetect_LowConfidence(t *testing.T) {
+	assert.Equal(t, Result{}, Detect("Netflix"))
+	assert.Equal(t, Result{}, Detect("   123 !!! "))
+
+	// names and loan words alone are not enough to tell
+	res := Detect("Breaking: Biden in Berlin")
+	assert.Less(t, res.Confidence, 0.95)
+}
+
+func TestLanguages(t *testing.T) {
+	assert.Equal(t, []string{"da", "de", "en", "es", "fr", "it", "nl", "pl", "pt", "sv"}, Languages())
+}
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, []string{" de", "der", "er ", " ho", "hof", "of "}, trigrams("Der Hof!"))
+}
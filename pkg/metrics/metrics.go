@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"goa.design/clue/log"
+	goa "goa.design/goa/v3/pkg"
+)
+
+const (
+	namespace = "deframer"
+
+	ResultOK    = "ok"
+	ResultError = "error"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	// APIRequests counts the Goa method calls by result: ok, the name of the
+	// Goa error (e.g. not_found) or error.
+	APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "API requests per Goa service, method and result.",
+	}, []string{"service", "method", "result"})
+
+	APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of the API requests per Goa service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	FeedsSynced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feeds_synced_total",
+		Help:      "Feed syncs of the ingester by result.",
+	}, []string{"result"})
+
+	ItemsIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_ingested_total",
+		Help:      "Feed items stored by the ingester.",
+	})
+
+	// ThinkerItems counts the analyses of the thinker lanes; error_kind is the
+	// think.ErrorKind of failures and empty on success.
+	ThinkerItems = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "thinker_items_total",
+		Help:      "Items analyzed by the thinker by result and error kind.",
+	}, []string{"result", "error_kind"})
+
+	LLMRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of the LLM calls per provider and model.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"provider", "model"})
+)
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Endpoint is a Goa endpoint middleware recording APIRequests and
+// APIRequestDuration.
+func Endpoint(e goa.Endpoint) goa.Endpoint {
+	return func(ctx context.Context, req any) (any, error) {
+		service, _ := ctx.Value(goa.ServiceKey).(string)
+		method, _ := ctx.Value(goa.MethodKey).(string)
+		start := time.Now()
+		res, err := e(ctx, req)
+		APIRequestDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+		APIRequests.WithLabelValues(service, method, resultOf(err)).Inc()
+		return res, err
+	}
+}
+
+func resultOf(err error) string {
+	if err == nil {
+		return ResultOK
+	}
+	var serviceErr *goa.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Name != "" {
+		return serviceErr.Name
+	}
+	return ResultError
+}
+
+// RegisterDBStats exports the statistics of the connection pool.
+func RegisterDBStats(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterQueues exports the depth of the thinker lanes and the number of
+// queries waiting for a lock. Both are queried from the database on every
+// scrape.
+func RegisterQueues(ctx context.Context, depth func() (*database.ThinkerQueueDepth, error), lockWaits func() (int64, error)) error {
+	return prometheus.Register(&queueCollector{ctx: ctx, depth: depth, lockWaits: lockWaits})
+}
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "thinker", "queue_depth"),
+		"Items waiting per lane of the thinker queue; dead and refused are the dead-lettered items.",
+		[]string{"lane"}, nil,
+	)
+	lockWaitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "lock_waits"),
+		"Queries of the database waiting for a lock.",
+		nil, nil,
+	)
+)
+
+type queueCollector struct {
+	ctx       context.Context
+	depth     func() (*database.ThinkerQueueDepth, error)
+	lockWaits func() (int64, error)
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- lockWaitsDesc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	if depth, err := c.depth(); err != nil {
+		log.Errorf(c.ctx, err, "Failed to query thinker queue depth for metrics")
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+	} else {
+		for lane, count := range map[string]int64{
+			"thinker":          depth.Thinker,
+			"fixer":            depth.Fixer,
+			"dead":             depth.Dead,
+			"refused":          depth.Refused,
+			"update_llm_model": depth.UpdateLLMModel,
+			"update_prompt":    depth.UpdatePrompt,
+		} {
+			ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(count), lane)
+		}
+	}
+
+	if waits, err := c.lockWaits(); err != nil {
+		log.Errorf(c.ctx, err, "Failed to query lock waits for metrics")
+		ch <- prometheus.NewInvalidMetric(lockWaitsDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(lockWaitsDesc, prometheus.GaugeValue, float64(waits))
+	}
+}
+
+// Serve runs a metrics listener on addr until ctx is done. Workers use it as
+// they have no HTTP server of their own.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 60 * time.Second}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	goa "goa.design/goa/v3/pkg"
+)
+
+func TestEndpoint(t *testing.T) {
+	ctx := context.WithValue(context.Background(), goa.ServiceKey, "web")
+	ctx = context.WithValue(ctx, goa.MethodKey, "get_items")
+	APIRequests.Reset()
+	APIRequestDuration.Reset()
+
+	fail := errors.New("boom")
+	notFound := goa.NewServiceError(errors.New("missing"), "not_found", false, false, false)
+	for _, err := range []error{nil, fail, notFound, notFound} {
+		endpoint := Endpoint(func(ctx context.Context, req any) (any, error) {
+			return req, err
+		})
+		res, gotErr := endpoint(ctx, "payload")
+		assert.Equal(t, "payload", res)
+		assert.Equal(t, err, gotErr)
+	}
+
+	body := scrape(t)
+	assert.Contains(t, body, `deframer_api_requests_total{method="get_items",result="ok",service="web"} 1`)
+	assert.Contains(t, body, `deframer_api_requests_total{method="get_items",result="error",service="web"} 1`)
+	assert.Contains(t, body, `deframer_api_requests_total{method="get_items",result="not_found",service="web"} 2`)
+	assert.Contains(t, body, `deframer_api_request_duration_seconds_count{method="get_items",service="web"} 4`)
+}
+
+func TestRegisterQueues(t *testing.T) {
+	lockWaitErr := errors.New("no database")
+	lockWaits := func() (int64, error) { return 0, lockWaitErr }
+	err := RegisterQueues(context.Background(), func() (*database.ThinkerQueueDepth, error) {
+		return &database.ThinkerQueueDepth{Thinker: 7, Fixer: 2, Dead: 1, UpdateLLMModel: 40, UpdatePrompt: 5}, nil
+	}, func() (int64, error) { return lockWaits() })
+	assert.NoError(t, err)
+	t.Cleanup(func() { prometheus.Unregister(&queueCollector{}) })
+
+	// a failing query fails the scrape
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	lockWaits = func() (int64, error) { return 3, nil }
+	body := scrape(t)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="thinker"} 7`)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="fixer"} 2`)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="dead"} 1`)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="refused"} 0`)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="update_llm_model"} 40`)
+	assert.Contains(t, body, `deframer_thinker_queue_depth{lane="update_prompt"} 5`)
+	assert.Contains(t, body, `deframer_db_lock_waits 3`)
+}
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return strings.TrimSpace(rec.Body.String())
+}
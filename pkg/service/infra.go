@@ -2,20 +2,101 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	infra "github.com/deframer/news-deframer/gen/infra"
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/database"
 	"goa.design/clue/log"
 )
 
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+
+	// probes of Kubernetes time out after one second by default
+	readinessTimeout = 800 * time.Millisecond
+)
+
 type infrasrvc struct {
+	repo database.Repository
 }
 
 // NewInfra returns the infra service implementation.
-func NewInfra() infra.Service {
-	return &infrasrvc{}
+func NewInfra(ctx context.Context) infra.Service {
+	cfg, err := config.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	repo, err := database.NewRepository(ctx, cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return &infrasrvc{repo: repo}
 }
 
 func (s *infrasrvc) Ping(ctx context.Context) (res string, err error) {
 	log.Printf(ctx, "infra.ping")
 	return "pong", nil
 }
+
+// Livez answers as long as the process serves requests.
+func (s *infrasrvc) Livez(ctx context.Context) (res *infra.HealthStatus, err error) {
+	return &infra.HealthStatus{Status: healthOK, Checks: []*infra.HealthCheck{}}, nil
+}
+
+// Readyz checks the dependencies; any failed check answers 503 with the same body.
+func (s *infrasrvc) Readyz(ctx context.Context) (res *infra.HealthStatus, err error) {
+	res = &infra.HealthStatus{Status: healthOK, Checks: []*infra.HealthCheck{}}
+	add := func(name string, err error, message string) {
+		check := &infra.HealthCheck{Name: name, Status: healthOK}
+		if err != nil {
+			check.Status = healthFail
+			message = err.Error()
+			res.Status = healthFail
+		}
+		if message != "" {
+			check.Message = &message
+		}
+		res.Checks = append(res.Checks, check)
+	}
+
+	if err := s.repo.Ping(readinessTimeout); err != nil {
+		log.Errorf(ctx, err, "readiness: database unreachable")
+		add("database", err, "")
+		return nil, res
+	}
+	add("database", nil, "")
+
+	status, err := s.repo.GetSchemaStatus(readinessTimeout)
+	if err != nil {
+		log.Errorf(ctx, err, "readiness: failed to query schema status")
+		add("schema", err, "")
+		return nil, res
+	}
+
+	if status.Version < database.SchemaVersion {
+		add("schema", fmt.Errorf("migrations pending: schema version %d, expected %d", status.Version, database.SchemaVersion), "")
+	} else {
+		add("schema", nil, fmt.Sprintf("version %d", status.Version))
+	}
+	if len(status.MissingViews) > 0 {
+		add("views", fmt.Errorf("missing %s", strings.Join(status.MissingViews, ", ")), "")
+	} else {
+		add("views", nil, "")
+	}
+	if status.DuckDBVersion == "" {
+		add("pg_duckdb", fmt.Errorf("extension pg_duckdb is not installed"), "")
+	} else {
+		add("pg_duckdb", nil, "version "+status.DuckDBVersion)
+	}
+
+	if res.Status != healthOK {
+		return nil, res
+	}
+	return res, nil
+}
@@ -6,6 +6,7 @@ import (
 	web "github.com/deframer/news-deframer/gen/web"
 
 	mobile "github.com/deframer/news-deframer/gen/mobile"
+	"github.com/deframer/news-deframer/pkg/facade"
 	"goa.design/goa/v3/security"
 )
 
@@ -17,8 +18,8 @@ type mobilesrvc struct {
 }
 
 // NewMobile returns the mobile service implementation.
-func NewMobile(ctx context.Context) mobile.Service {
-	return &mobilesrvc{svc: NewWebImplementation(ctx)}
+func NewMobile(ctx context.Context, events *facade.Notifier) mobile.Service {
+	return &mobilesrvc{svc: NewWebImplementation(ctx, events)}
 }
 
 func (s *mobilesrvc) Item(ctx context.Context, p *mobile.ItemPayload) (res *mobile.AnalyzedItem, err error) {
@@ -29,14 +30,77 @@ func (s *mobilesrvc) Item(ctx context.Context, p *mobile.ItemPayload) (res *mobi
 	return convertMobileAnalyzedItem(item), nil
 }
 
-func (s *mobilesrvc) Site(ctx context.Context, p *mobile.SitePayload) (res []*mobile.AnalyzedSiteItem, err error) {
-	items, err := s.svc.Site(ctx, &web.SitePayload{Root: p.Root, MaxScore: p.MaxScore, User: p.User, Pass: p.Pass})
+func (s *mobilesrvc) LookupItems(ctx context.Context, p *mobile.ItemsLookupPayload) (res *mobile.ItemsLookupResult, err error) {
+	result, err := s.svc.LookupItems(ctx, &web.ItemsLookupPayload{Urls: p.Urls, User: p.User, Pass: p.Pass})
 	if err != nil {
 		return nil, translateMobileError(err)
 	}
-	res = make([]*mobile.AnalyzedSiteItem, 0, len(items))
-	for i := range items {
-		res = append(res, convertMobileAnalyzedSiteItem(items[i]))
+	res = &mobile.ItemsLookupResult{Items: make(map[string]*mobile.AnalyzedItem, len(result.Items)), NotFound: result.NotFound}
+	for rawURL, item := range result.Items {
+		res.Items[rawURL] = convertMobileAnalyzedItem(item)
+	}
+	return res, nil
+}
+
+func (s *mobilesrvc) Search(ctx context.Context, p *mobile.SearchPayload) (res *mobile.SearchResult, err error) {
+	result, err := s.svc.Search(ctx, &web.SearchPayload{
+		Q:        p.Q,
+		Lang:     p.Lang,
+		Domain:   p.Domain,
+		From:     p.From,
+		To:       p.To,
+		MaxScore: p.MaxScore,
+		Category: p.Category,
+		Cursor:   p.Cursor,
+		Limit:    p.Limit,
+		User:     p.User,
+		Pass:     p.Pass,
+	})
+	if err != nil {
+		return nil, translateMobileError(err)
+	}
+	res = &mobile.SearchResult{Items: make([]*mobile.SearchHit, 0, len(result.Items)), NextCursor: result.NextCursor}
+	for _, hit := range result.Items {
+		res.Items = append(res.Items, &mobile.SearchHit{
+			Item:                 convertMobileAnalyzedItem(hit.Item),
+			Rank:                 hit.Rank,
+			TitleHighlight:       hit.TitleHighlight,
+			DescriptionHighlight: hit.DescriptionHighlight,
+		})
+	}
+	return res, nil
+}
+
+func (s *mobilesrvc) Site(ctx context.Context, p *mobile.SitePayload) (res *mobile.SitePage, err error) {
+	page, err := s.svc.Site(ctx, &web.SitePayload{
+		Root:             p.Root,
+		MaxScore:         p.MaxScore,
+		MinScore:         p.MinScore,
+		MinFraming:       p.MinFraming,
+		MaxFraming:       p.MaxFraming,
+		MinClickbait:     p.MinClickbait,
+		MaxClickbait:     p.MaxClickbait,
+		MinPersuasive:    p.MinPersuasive,
+		MaxPersuasive:    p.MaxPersuasive,
+		MinHyperStimulus: p.MinHyperStimulus,
+		MaxHyperStimulus: p.MaxHyperStimulus,
+		MinSpeculative:   p.MinSpeculative,
+		MaxSpeculative:   p.MaxSpeculative,
+		Category:         p.Category,
+		Author:           p.Author,
+		From:             p.From,
+		To:               p.To,
+		Cursor:           p.Cursor,
+		Limit:            p.Limit,
+		User:             p.User,
+		Pass:             p.Pass,
+	})
+	if err != nil {
+		return nil, translateMobileError(err)
+	}
+	res = &mobile.SitePage{Items: make([]*mobile.AnalyzedSiteItem, 0, len(page.Items)), NextCursor: page.NextCursor}
+	for i := range page.Items {
+		res.Items = append(res.Items, convertMobileAnalyzedSiteItem(page.Items[i]))
 	}
 	return res, nil
 }
@@ -121,11 +185,17 @@ func (s *mobilesrvc) DomainComparisonEndpoint(ctx context.Context, p *mobile.Dom
 	return res, nil
 }
 
+func (s *mobilesrvc) Feedback(ctx context.Context, p *mobile.FeedbackPayload) error {
+	err := s.svc.Feedback(ctx, &web.FeedbackPayload{Hash: p.Hash, ClientID: p.ClientID, Kind: p.Kind, Comment: p.Comment, User: p.User, Pass: p.Pass})
+	return translateMobileError(err)
+}
+
 func (s *mobilesrvc) BasicAuth(ctx context.Context, user, pass string, scheme *security.BasicScheme) (context.Context, error) {
 	if auther, ok := s.svc.(interface {
 		BasicAuth(context.Context, string, string, *security.BasicScheme) (context.Context, error)
 	}); ok {
-		return auther.BasicAuth(ctx, user, pass, scheme)
+		ctx, err := auther.BasicAuth(ctx, user, pass, scheme)
+		return ctx, translateMobileError(err)
 	}
 	return ctx, nil
 }
@@ -137,6 +207,18 @@ func translateMobileError(err error) error {
 	if nf, ok := err.(web.NotFound); ok {
 		return mobile.NotFound(nf)
 	}
+	if tm, ok := err.(web.TooManyRequests); ok {
+		return mobile.TooManyRequests(tm)
+	}
+	if br, ok := err.(web.BadRequest); ok {
+		return mobile.BadRequest(br)
+	}
+	if ua, ok := err.(web.Unauthorized); ok {
+		return mobile.Unauthorized(ua)
+	}
+	if fb, ok := err.(web.Forbidden); ok {
+		return mobile.Forbidden(fb)
+	}
 	return err
 }
 
@@ -149,6 +231,7 @@ func convertMobileAnalyzedItem(item *web.AnalyzedItem) *mobile.AnalyzedItem {
 		Tags:                        append([]string{}, item.Tags...),
 		URL:                         item.URL,
 		LlmModel:                    item.LlmModel,
+		PromptVersion:               item.PromptVersion,
 		TitleOriginal:               item.TitleOriginal,
 		DescriptionOriginal:         item.DescriptionOriginal,
 		TitleCorrected:              item.TitleCorrected,
@@ -168,10 +251,15 @@ func convertMobileAnalyzedItem(item *web.AnalyzedItem) *mobile.AnalyzedItem {
 		Overall:                     item.Overall,
 		OverallReason:               item.OverallReason,
 		Category:                    item.Category,
+		Evidence:                    convertMobileEvidenceSpans(item.Evidence),
+		Disagreement:                item.Disagreement,
+		EnsembleModels:              append([]string(nil), item.EnsembleModels...),
 		Sentiments:                  convertMobileSentimentScores(item.Sentiments),
 		SentimentsDeframed:          convertMobileSentimentScores(item.SentimentsDeframed),
 		Media:                       convertMobileMediaContent(item.Media),
 		Rating:                      item.Rating,
+		Reviewed:                    item.Reviewed,
+		ReviewNote:                  item.ReviewNote,
 		Authors:                     append([]string{}, item.Authors...),
 		PubDate:                     item.PubDate,
 	}
@@ -218,6 +306,23 @@ func convertMobileSentimentItem(item *web.SentimentItem) *mobile.SentimentItem {
 	}
 }
 
+func convertMobileEvidenceSpans(spans []*web.EvidenceSpan) []*mobile.EvidenceSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]*mobile.EvidenceSpan, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, &mobile.EvidenceSpan{
+			Dimension: span.Dimension,
+			Field:     span.Field,
+			Quote:     span.Quote,
+			Start:     span.Start,
+			End:       span.End,
+		})
+	}
+	return out
+}
+
 func convertMobileSentimentScores(scores *web.SentimentScores) *mobile.SentimentScores {
 	if scores == nil {
 		return nil
@@ -34,7 +34,7 @@ func (s *openapisrvc) OpenAPI(ctx context.Context, p *openapi.OpenAPIPayload) (r
 	)
 	log.Printf(ctx, "creating openapi")
 
-	filterPaths := []string{"/ping", "/openapi", "/openapi/{tag}"}
+	filterPaths := []string{"/ping", "/livez", "/readyz", "/openapi", "/openapi/{tag}"}
 	json, err := openapiutil.OpenAPI(url, *p.Tag, &filterPaths, nil, "./gen/http/openapi3.json")
 	if err != nil {
 		return "", openapi.MakeInvalidOpenapiFile(err)
@@ -59,7 +59,7 @@ func (s *openapisrvc) OpenAPIOnlyFor(ctx context.Context, p *openapi.OpenAPIOnly
 	)
 	log.Printf(ctx, "creating openapi")
 
-	filterPaths := []string{"/ping", "/openapi", "/openapi/{tag}"}
+	filterPaths := []string{"/ping", "/livez", "/readyz", "/openapi", "/openapi/{tag}"}
 	json, err := openapiutil.OpenAPI(url, *p.Tag, &filterPaths, &p.Paths, "./gen/http/openapi3.json")
 	if err != nil {
 		return "", openapi.MakeInvalidOpenapiFile(err)
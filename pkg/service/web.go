@@ -4,9 +4,10 @@ import (
 	"context"
 
 	web "github.com/deframer/news-deframer/gen/web"
+	"github.com/deframer/news-deframer/pkg/facade"
 )
 
 // NewWeb returns the web service implementation.
-func NewWeb(ctx context.Context) web.Service {
-	return NewWebImplementation(ctx)
+func NewWeb(ctx context.Context, events *facade.Notifier) web.Service {
+	return NewWebImplementation(ctx, events)
 }
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/deframer/news-deframer/pkg/facade"
 	"goa.design/clue/log"
+	"goa.design/goa/v3/http/middleware"
 	"goa.design/goa/v3/security"
 )
 
@@ -21,8 +26,9 @@ type WebImpl struct {
 	cfg    *config.Config
 }
 
-// NewWebImplementation returns the shared web service implementation.
-func NewWebImplementation(ctx context.Context) web.Service {
+// NewWebImplementation returns the shared web service implementation. events
+// wakes the live streams, see facade.NewNotifier.
+func NewWebImplementation(ctx context.Context, events *facade.Notifier) web.Service {
 	cfg, err := config.Load()
 	if err != nil {
 		panic(err)
@@ -34,7 +40,7 @@ func NewWebImplementation(ctx context.Context) web.Service {
 	}
 
 	return &WebImpl{
-		facade: facade.New(ctx, cfg, repo),
+		facade: facade.New(ctx, cfg, repo, events),
 		cfg:    cfg,
 	}
 }
@@ -64,28 +70,216 @@ func (w *WebImpl) Item(ctx context.Context, p *web.ItemPayload) (res *web.Analyz
 	return convertAnalyzedItem(item), nil
 }
 
-func (w *WebImpl) Site(ctx context.Context, p *web.SitePayload) (res []*web.AnalyzedSiteItem, err error) {
+func (w *WebImpl) LookupItems(ctx context.Context, p *web.ItemsLookupPayload) (res *web.ItemsLookupResult, err error) {
+	log.Printf(ctx, "handleLookupItems urls=%d", len(p.Urls))
+	items, err := w.facade.GetFirstItemsForUrls(ctx, p.Urls)
+	if err != nil {
+		log.Errorf(ctx, err, "GetFirstItemsForUrls failed")
+		return nil, fmt.Errorf("lookup failed")
+	}
+
+	res = &web.ItemsLookupResult{Items: make(map[string]*web.AnalyzedItem, len(items)), NotFound: []string{}}
+	for _, rawURL := range p.Urls {
+		if _, done := res.Items[rawURL]; done || slices.Contains(res.NotFound, rawURL) {
+			continue
+		}
+		item, ok := items[rawURL]
+		if !ok || item.ThinkResult == nil {
+			res.NotFound = append(res.NotFound, rawURL)
+			continue
+		}
+		res.Items[rawURL] = convertAnalyzedItem(&item)
+	}
+	return res, nil
+}
+
+func (w *WebImpl) Search(ctx context.Context, p *web.SearchPayload) (res *web.SearchResult, err error) {
+	log.Printf(ctx, "handleSearch q=%s", p.Q)
+	from, err := parseOptionalDateParam(p.From)
+	if err != nil {
+		return nil, web.BadRequest("invalid from, expected YYYY-MM-DD")
+	}
+	to, err := parseOptionalDateParam(p.To)
+	if err != nil {
+		return nil, web.BadRequest("invalid to, expected YYYY-MM-DD")
+	}
+	if to != nil {
+		// the whole last day is included
+		next := to.AddDate(0, 0, 1)
+		to = &next
+	}
+
+	search := database.ItemSearch{
+		Query:    strings.TrimSpace(p.Q),
+		From:     from,
+		To:       to,
+		MaxScore: p.MaxScore,
+		Limit:    p.Limit,
+	}
+	if p.Lang != nil {
+		search.Language = strings.ToLower(*p.Lang)
+	}
+	if p.Domain != nil {
+		search.Domain = strings.TrimSuffix(*p.Domain, "/")
+	}
+	if p.Category != nil {
+		search.Category = *p.Category
+	}
+	cursor := ""
+	if p.Cursor != nil {
+		cursor = *p.Cursor
+	}
+
+	hits, next, err := w.facade.SearchItems(ctx, search, cursor)
+	if err != nil {
+		if errors.Is(err, facade.ErrInvalidCursor) {
+			return nil, web.BadRequest("invalid cursor")
+		}
+		log.Errorf(ctx, err, "SearchItems failed")
+		return nil, fmt.Errorf("search failed")
+	}
+
+	res = &web.SearchResult{Items: make([]*web.SearchHit, 0, len(hits))}
+	for i := range hits {
+		res.Items = append(res.Items, &web.SearchHit{
+			Item:                 convertAnalyzedItem(&hits[i].Item),
+			Rank:                 float64(hits[i].Rank),
+			TitleHighlight:       hits[i].TitleHighlight,
+			DescriptionHighlight: hits[i].DescriptionHighlight,
+		})
+	}
+	if next != "" {
+		res.NextCursor = &next
+	}
+	return res, nil
+}
+
+func (w *WebImpl) Stream(ctx context.Context, p *web.StreamPayload, stream web.StreamServerStream) error {
+	filter := database.ItemEventFilter{MaxScore: p.MaxScore}
+	if p.Domain != nil {
+		filter.RootDomain = strings.TrimSuffix(*p.Domain, "/")
+	}
+	log.Printf(ctx, "handleStream domain=%s", filter.RootDomain)
+	var lastEventID int64
+	if p.LastEventID != nil && *p.LastEventID != "" {
+		id, err := strconv.ParseInt(*p.LastEventID, 10, 64)
+		if err != nil || id < 0 {
+			return web.BadRequest("invalid Last-Event-ID")
+		}
+		lastEventID = id
+	}
+
+	// stops the stream of the facade when the client is gone
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := w.facade.StreamAnalyzedItems(ctx, filter, lastEventID)
+	if err != nil {
+		log.Errorf(ctx, err, "StreamAnalyzedItems failed")
+		return fmt.Errorf("stream failed")
+	}
+	for event := range events {
+		if err := stream.SendWithContext(ctx, &web.ItemEvent{
+			ID:   strconv.FormatInt(event.EventID, 10),
+			Item: convertAnalyzedItem(&event.Item),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WebImpl) Site(ctx context.Context, p *web.SitePayload) (res *web.SitePage, err error) {
 	log.Printf(ctx, "handleSite root=%s", p.Root)
 	rootDomain := strings.TrimSuffix(p.Root, "/")
 	if rootDomain == "" {
 		return nil, fmt.Errorf("missing root")
 	}
 
-	items, err := w.facade.GetItemsForRootDomain(ctx, rootDomain, p.MaxScore)
-	if err != nil || len(items) == 0 {
-		if err != nil {
-			log.Errorf(ctx, err, "GetItemsForRootDomain failed")
+	filter, err := siteFilter(p)
+	if err != nil {
+		return nil, web.BadRequest(err.Error())
+	}
+	cursor := ""
+	if p.Cursor != nil {
+		cursor = *p.Cursor
+	}
+
+	items, next, err := w.facade.GetItemsForRootDomain(ctx, rootDomain, filter, cursor, p.Limit)
+	if err != nil {
+		if errors.Is(err, facade.ErrInvalidCursor) {
+			return nil, web.BadRequest("invalid cursor")
 		}
+		log.Errorf(ctx, err, "GetItemsForRootDomain failed")
+		return nil, web.NotFound("not found")
+	}
+	// an empty later page is the end of the listing, not an unknown domain
+	if len(items) == 0 && cursor == "" {
 		return nil, web.NotFound("not found")
 	}
 
-	res = make([]*web.AnalyzedSiteItem, 0, len(items))
+	res = &web.SitePage{Items: make([]*web.AnalyzedSiteItem, 0, len(items))}
 	for i := range items {
-		res = append(res, convertAnalyzedSiteItem(&items[i]))
+		res.Items = append(res.Items, convertAnalyzedSiteItem(&items[i]))
+	}
+	if next != "" {
+		res.NextCursor = &next
 	}
 	return res, nil
 }
 
+func siteFilter(p *web.SitePayload) (database.SiteFilter, error) {
+	filter := database.SiteFilter{
+		MinScores: map[string]float64{},
+		MaxScores: map[string]float64{},
+	}
+	if p.MaxScore > 0 {
+		filter.MaxScores["overall"] = p.MaxScore
+	}
+	for dim, score := range map[string]*float64{
+		"overall":        p.MinScore,
+		"framing":        p.MinFraming,
+		"clickbait":      p.MinClickbait,
+		"persuasive":     p.MinPersuasive,
+		"hyper_stimulus": p.MinHyperStimulus,
+		"speculative":    p.MinSpeculative,
+	} {
+		if score != nil {
+			filter.MinScores[dim] = *score
+		}
+	}
+	for dim, score := range map[string]*float64{
+		"framing":        p.MaxFraming,
+		"clickbait":      p.MaxClickbait,
+		"persuasive":     p.MaxPersuasive,
+		"hyper_stimulus": p.MaxHyperStimulus,
+		"speculative":    p.MaxSpeculative,
+	} {
+		if score != nil {
+			filter.MaxScores[dim] = *score
+		}
+	}
+	if p.Category != nil {
+		filter.Category = *p.Category
+	}
+	if p.Author != nil {
+		filter.Author = strings.TrimSpace(*p.Author)
+	}
+
+	var err error
+	if filter.From, err = parseOptionalDateParam(p.From); err != nil {
+		return filter, fmt.Errorf("invalid from, expected YYYY-MM-DD")
+	}
+	if filter.To, err = parseOptionalDateParam(p.To); err != nil {
+		return filter, fmt.Errorf("invalid to, expected YYYY-MM-DD")
+	}
+	if filter.To != nil {
+		// the whole last day is included
+		next := filter.To.AddDate(0, 0, 1)
+		filter.To = &next
+	}
+	return filter, nil
+}
+
 func (w *WebImpl) Articles(ctx context.Context, p *web.ArticlesPayload) (res []*web.AnalyzedArticle, err error) {
 	log.Printf(ctx, "handleArticles root=%s term=%s", p.Root, p.Term)
 	rootDomain := strings.TrimSuffix(p.Root, "/")
@@ -266,24 +460,157 @@ func (w *WebImpl) DomainComparisonEndpoint(ctx context.Context, p *web.DomainCom
 	return res, nil
 }
 
+func (w *WebImpl) Feedback(ctx context.Context, p *web.FeedbackPayload) error {
+	log.Printf(ctx, "handleFeedback hash=%s kind=%s", p.Hash, p.Kind)
+	feedback := database.ItemFeedback{ClientID: p.ClientID, Kind: p.Kind, Source: feedbackSource(ctx, w.cfg.TrustedProxies, p.User)}
+	if p.Comment != nil {
+		feedback.Comment = *p.Comment
+	}
+
+	found, err := w.facade.SubmitFeedback(ctx, p.Hash, feedback)
+	if err != nil {
+		if errors.Is(err, facade.ErrRateLimited) {
+			return web.TooManyRequests("too many requests")
+		}
+		log.Errorf(ctx, err, "failed to store feedback")
+		return err
+	}
+	if !found {
+		return web.NotFound("not found")
+	}
+	return nil
+}
+
+// feedbackSource is the API key id of the request or, with the shared
+// credentials, the client address from the values set by
+// middleware.PopulateRequestContext.
+func feedbackSource(ctx context.Context, proxies config.TrustedProxies, user *string) string {
+	if user != nil && strings.HasPrefix(*user, database.APIKeyPrefix) {
+		return *user
+	}
+	remoteAddr, _ := ctx.Value(middleware.RequestRemoteAddrKey).(string)
+	forwardedFor, _ := ctx.Value(middleware.RequestXForwardedForKey).(string)
+	realIP, _ := ctx.Value(middleware.RequestXRealIPKey).(string)
+	return proxies.ClientAddr(remoteAddr, forwardedFor, realIP)
+}
+
+func (w *WebImpl) Review(ctx context.Context, p *web.ReviewPayload) (res *web.AnalyzedItem, err error) {
+	log.Printf(ctx, "handleReview url=%s", p.URL)
+	u, err := url.ParseRequestURI(strings.TrimSuffix(p.URL, "/"))
+	if err != nil {
+		return nil, web.BadRequest("invalid url")
+	}
+
+	review := database.ItemReview{
+		Framing:       p.Framing,
+		Clickbait:     p.Clickbait,
+		Persuasive:    p.Persuasive,
+		HyperStimulus: p.HyperStimulus,
+		Speculative:   p.Speculative,
+		Overall:       p.Overall,
+		Category:      p.Category,
+	}
+	if p.User != nil {
+		review.Reviewer = *p.User
+	}
+	if p.Note != nil {
+		review.Note = *p.Note
+	}
+
+	item, err := w.facade.ReviewItem(ctx, u, review)
+	if err != nil {
+		if errors.Is(err, facade.ErrInvalidReview) {
+			return nil, web.BadRequest(err.Error())
+		}
+		log.Errorf(ctx, err, "failed to review item")
+		return nil, err
+	}
+	if item == nil || item.ThinkResult == nil {
+		return nil, web.NotFound("not found")
+	}
+	return convertAnalyzedItem(item), nil
+}
+
+func (w *WebImpl) DeleteReview(ctx context.Context, p *web.DeleteReviewPayload) error {
+	log.Printf(ctx, "handleDeleteReview url=%s", p.URL)
+	u, err := url.ParseRequestURI(strings.TrimSuffix(p.URL, "/"))
+	if err != nil {
+		return web.BadRequest("invalid url")
+	}
+
+	found, err := w.facade.DeleteItemReview(ctx, u)
+	if err != nil {
+		log.Errorf(ctx, err, "failed to delete review")
+		return err
+	}
+	if !found {
+		return web.NotFound("not found")
+	}
+	return nil
+}
+
 func (w *WebImpl) BasicAuth(ctx context.Context, user, pass string, scheme *security.BasicScheme) (context.Context, error) {
+	if scheme != nil && scheme.Name == "reviewer" {
+		return ctx, w.authorizeReviewer(user, pass)
+	}
+	// a key id is never taken for the shared user, even when that is unset
+	if strings.HasPrefix(user, database.APIKeyPrefix) {
+		var required []string
+		if scheme != nil {
+			required = scheme.RequiredScopes
+		}
+		return ctx, w.authorizeAPIKey(ctx, user, pass, required)
+	}
 	if w.cfg != nil {
 		if w.cfg.BasicAuthUser != "" && user != w.cfg.BasicAuthUser {
-			return ctx, fmt.Errorf("unauthorized")
+			return ctx, web.Unauthorized("unauthorized")
 		}
 		if w.cfg.BasicAuthPassword != "" && pass != w.cfg.BasicAuthPassword {
-			return ctx, fmt.Errorf("unauthorized")
+			return ctx, web.Unauthorized("unauthorized")
 		}
 	}
 	return ctx, nil
 }
 
+// authorizeAPIKey accepts a key that has the required scopes and requests
+// left in the current hour.
+func (w *WebImpl) authorizeAPIKey(ctx context.Context, keyID, secret string, requiredScopes []string) error {
+	_, err := w.facade.AuthenticateAPIKey(ctx, keyID, secret, requiredScopes)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, facade.ErrUnauthorized):
+		return web.Unauthorized("unauthorized")
+	case errors.Is(err, facade.ErrForbidden):
+		return web.Forbidden(err.Error())
+	case errors.Is(err, facade.ErrRateLimited):
+		return web.TooManyRequests("too many requests")
+	}
+	log.Errorf(ctx, err, "failed to authenticate api key")
+	return err
+}
+
+// authorizeReviewer requires the reviewer credentials; without them
+// configured nobody may review.
+func (w *WebImpl) authorizeReviewer(user, pass string) error {
+	if w.cfg == nil || w.cfg.ReviewerUser == "" || w.cfg.ReviewerPassword == "" {
+		return web.Unauthorized("unauthorized")
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(w.cfg.ReviewerUser)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(w.cfg.ReviewerPassword)) == 1
+	if !userOK || !passOK {
+		return web.Unauthorized("unauthorized")
+	}
+	return nil
+}
+
 func convertAnalyzedItem(item *database.AnalyzedItem) *web.AnalyzedItem {
 	if item == nil {
 		return nil
 	}
 	var (
 		llmModel                    *string
+		promptVersion               *string
 		titleOriginal               *string
 		descriptionOriginal         *string
 		titleCorrected              *string
@@ -303,6 +630,11 @@ func convertAnalyzedItem(item *database.AnalyzedItem) *web.AnalyzedItem {
 		overall                     *float64
 		overallReason               *string
 		category                    *string
+		evidence                    []*web.EvidenceSpan
+		disagreement                *float64
+		ensembleModels              []string
+		reviewed                    *bool
+		reviewNote                  *string
 	)
 	if item.LLMModel != nil {
 		llmModel = item.LLMModel
@@ -311,6 +643,7 @@ func convertAnalyzedItem(item *database.AnalyzedItem) *web.AnalyzedItem {
 		if llmModel == nil && tr.LLMModel != "" {
 			llmModel = stringPtr(tr.LLMModel)
 		}
+		promptVersion = stringPtr(tr.PromptVersion)
 		titleOriginal = stringPtr(tr.TitleOriginal)
 		descriptionOriginal = stringPtr(tr.DescriptionOriginal)
 		titleCorrected = stringPtr(tr.TitleCorrected)
@@ -330,12 +663,25 @@ func convertAnalyzedItem(item *database.AnalyzedItem) *web.AnalyzedItem {
 		overall = float64Ptr(tr.Overall)
 		overallReason = stringPtr(tr.OverallReason)
 		category = stringPtr(tr.Category)
+		evidence = convertEvidenceSpans(tr.Evidence)
+		if len(tr.EnsembleModels) > 0 {
+			// zero is a meaningful value here: all models agreed
+			value := tr.Disagreement
+			disagreement = &value
+			ensembleModels = append([]string{}, tr.EnsembleModels...)
+		}
+	}
+	if item.Review != nil {
+		value := true
+		reviewed = &value
+		reviewNote = stringPtr(item.Review.Note)
 	}
 	return &web.AnalyzedItem{
 		Hash:                        item.Hash,
 		Tags:                        append([]string{}, item.Tags...),
 		URL:                         item.URL,
 		LlmModel:                    llmModel,
+		PromptVersion:               promptVersion,
 		TitleOriginal:               titleOriginal,
 		DescriptionOriginal:         descriptionOriginal,
 		TitleCorrected:              titleCorrected,
@@ -355,10 +701,15 @@ func convertAnalyzedItem(item *database.AnalyzedItem) *web.AnalyzedItem {
 		Overall:                     overall,
 		OverallReason:               overallReason,
 		Category:                    category,
+		Evidence:                    evidence,
+		Disagreement:                disagreement,
+		EnsembleModels:              ensembleModels,
 		Sentiments:                  convertSentimentScores(item.Sentiments),
 		SentimentsDeframed:          convertSentimentScores(item.SentimentsDeframed),
 		Media:                       convertMediaContent(item.MediaContent),
 		Rating:                      item.ThinkRating,
+		Reviewed:                    reviewed,
+		ReviewNote:                  reviewNote,
 		Authors:                     append([]string{}, item.Authors...),
 		PubDate:                     item.PubDate.Format(time.RFC3339),
 	}
@@ -419,6 +770,23 @@ func convertSentimentItem(item *database.SentimentItem) *web.SentimentItem {
 	}
 }
 
+func convertEvidenceSpans(spans []database.EvidenceSpan) []*web.EvidenceSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]*web.EvidenceSpan, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, &web.EvidenceSpan{
+			Dimension: span.Dimension,
+			Field:     span.Field,
+			Quote:     span.Quote,
+			Start:     span.Start,
+			End:       span.End,
+		})
+	}
+	return out
+}
+
 func convertSentimentScores(scores *database.SentimentScores) *web.SentimentScores {
 	if scores == nil {
 		return nil
@@ -380,6 +380,89 @@ func TestThinkItem_UsesTrendInvalidatingUpsert(t *testing.T) {
 	assert.True(t, invalidateCalled)
 }
 
+func TestThinkItem_RecordsNewHistoryEntry(t *testing.T) {
+	repo := &mockRepo{}
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+
+	s, err := New(context.Background(), cfg, repo)
+	assert.NoError(t, err)
+	s.think = &mockThink{}
+	s.feeds = feeds.NewFeeds(context.Background(), cfg)
+
+	previous := uuid.New()
+	dbItem := &database.Item{
+		ID:            uuid.New(),
+		FeedID:        uuid.New(),
+		URL:           "http://example.com/item",
+		Content:       "<item><title>Thinker item</title><description>hello</description></item>",
+		ThinkResult:   &database.ThinkResult{LLMModel: "old"},
+		ThinkResultID: &previous,
+	}
+
+	var saved *database.Item
+	repo.upsertItemInvalidateFunc = func(updated *database.Item) error {
+		saved = updated
+		return nil
+	}
+	s.thinkItem(dbItem)
+
+	if assert.NotNil(t, saved) {
+		assert.NotNil(t, saved.ThinkResult)
+		assert.Nil(t, saved.ThinkResultID)
+	}
+}
+
+func TestThinkItem_RecordsUsage(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{LLM_Prices: config.LLMPrices{"m": {Input: 1, Output: 4}}}
+	dbItem := &database.Item{
+		ID:      uuid.New(),
+		FeedID:  uuid.New(),
+		URL:     "http://example.com/1",
+		Content: "<item><title>Test Item</title><description>Desc</description></item>",
+	}
+
+	var recorded []database.LLMUsage
+	s := &Syncer{
+		ctx: ctx,
+		cfg: cfg,
+		repo: &mockRepo{
+			createLLMUsagesFunc: func(usages []database.LLMUsage) error {
+				recorded = append(recorded, usages...)
+				return nil
+			},
+		},
+		feeds: feeds.NewFeeds(ctx, cfg),
+	}
+
+	s.think = &mockThinkEH{
+		runFunc: func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+			return &database.ThinkResult{Usage: []database.LLMUsage{{LLMModel: "m", PromptTokens: 1_000_000, CompletionTokens: 500_000}}}, nil
+		},
+	}
+	s.thinkItem(dbItem)
+
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, dbItem.ID, *recorded[0].ItemID)
+		assert.Equal(t, dbItem.FeedID, *recorded[0].FeedID)
+		assert.InDelta(t, 3.0, recorded[0].Cost, 1e-9)
+	}
+
+	// a billed call whose answer was unusable is still accounted for
+	s.think = &mockThinkEH{
+		runFunc: func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+			return nil, &think.UsageError{Usage: []database.LLMUsage{{LLMModel: "unpriced", PromptTokens: 10}}, Err: errors.New("bad json")}
+		},
+	}
+	s.thinkItem(dbItem)
+
+	if assert.Len(t, recorded, 2) {
+		assert.Equal(t, "unpriced", recorded[1].LLMModel)
+		assert.Zero(t, recorded[1].Cost)
+	}
+}
+
 func TestThinkItemErrorHandling(t *testing.T) {
 	ctx := context.Background()
 	strPtr := func(s string) *string { return &s }
@@ -433,6 +516,7 @@ func TestThinkItemErrorHandling(t *testing.T) {
 				assert.Equal(t, "hash2", item.Hash)
 				assert.Nil(t, item.ThinkError)
 				assert.Equal(t, 0, item.ThinkErrorCount)
+				assert.Nil(t, item.ThinkErrorKind)
 				return nil
 			},
 		}
@@ -452,4 +536,99 @@ func TestThinkItemErrorHandling(t *testing.T) {
 
 		s.thinkItem(&database.Item{ID: uuid.New(), Hash: "hash2", FeedID: feedID, URL: item.Link, Content: "<item><title>Test Item 2</title><description>Desc</description></item>", ThinkErrorCount: 2, Language: strPtr("en")})
 	})
+
+	t.Run("KeepErrorCountWhenRateLimited", func(t *testing.T) {
+		mockT := &mockThinkEH{
+			runFunc: func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+				return nil, &think.Error{Kind: think.ErrorRateLimited, Err: errors.New("429 too many requests")}
+			},
+		}
+
+		mockR := &mockRepo{
+			upsertItemFunc: func(item *database.Item) error {
+				assert.Equal(t, 1, item.ThinkErrorCount)
+				if assert.NotNil(t, item.ThinkErrorKind) {
+					assert.Equal(t, "rate_limited", *item.ThinkErrorKind)
+				}
+				return nil
+			},
+		}
+
+		s := &Syncer{
+			ctx:   ctx,
+			repo:  mockR,
+			think: mockT,
+			feeds: feeds.NewFeeds(ctx, &config.Config{}),
+		}
+
+		assert.False(t, s.thinkerRateLimited())
+		s.thinkItem(&database.Item{ID: uuid.New(), Hash: "hash3", FeedID: feedID, URL: "http://example.com/3", Content: "<item><title>Test Item 3</title><description>Desc</description></item>", ThinkErrorCount: 1, Language: strPtr("en")})
+		assert.True(t, s.thinkerRateLimited())
+		assert.False(t, s.processThinkerBatch(), "the thinker must back off while rate limited")
+	})
+
+	t.Run("MarkRefused", func(t *testing.T) {
+		mockT := &mockThinkEH{
+			runFunc: func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+				return nil, &think.Error{Kind: think.ErrorRefused, Err: errors.New("blocked by safety filter")}
+			},
+		}
+
+		mockR := &mockRepo{
+			upsertItemFunc: func(item *database.Item) error {
+				assert.Equal(t, 1, item.ThinkErrorCount)
+				if assert.NotNil(t, item.ThinkErrorKind) {
+					assert.Equal(t, database.ThinkErrorKindRefused, *item.ThinkErrorKind)
+				}
+				return nil
+			},
+		}
+
+		s := &Syncer{
+			ctx:   ctx,
+			repo:  mockR,
+			think: mockT,
+			feeds: feeds.NewFeeds(ctx, &config.Config{}),
+		}
+
+		s.thinkItem(&database.Item{ID: uuid.New(), Hash: "hash4", FeedID: feedID, URL: "http://example.com/4", Content: "<item><title>Test Item 4</title><description>Desc</description></item>", Language: strPtr("en")})
+		assert.False(t, s.thinkerRateLimited())
+	})
+
+	t.Run("KeepResultWhenReanalysisFails", func(t *testing.T) {
+		for _, kind := range []think.ErrorKind{think.ErrorRateLimited, think.ErrorTransient} {
+			mockT := &mockThinkEH{
+				runFunc: func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+					return nil, &think.Error{Kind: kind, Err: errors.New("provider failed")}
+				},
+			}
+
+			upserts := 0
+			mockR := &mockRepo{
+				upsertItemFunc: func(item *database.Item) error {
+					upserts++
+					return nil
+				},
+			}
+
+			s := &Syncer{
+				ctx:   ctx,
+				repo:  mockR,
+				think: mockT,
+				feeds: feeds.NewFeeds(ctx, &config.Config{}),
+			}
+
+			resultID := uuid.New()
+			result := &database.ThinkResult{Overall: 0.4}
+			dbItem := &database.Item{ID: uuid.New(), Hash: "hash5", FeedID: feedID, URL: "http://example.com/5", Content: "<item><title>Test Item 5</title><description>Desc</description></item>", Language: strPtr("en"), ThinkResult: result, ThinkResultID: &resultID, ThinkRating: 0.4}
+
+			assert.False(t, s.thinkItem(dbItem), kind)
+			assert.Zero(t, upserts, "a failed re-analysis must not overwrite the stored item (%s)", kind)
+			assert.Same(t, result, dbItem.ThinkResult)
+			assert.Equal(t, &resultID, dbItem.ThinkResultID)
+			assert.Equal(t, 0.4, dbItem.ThinkRating)
+			assert.Nil(t, dbItem.ThinkError)
+			assert.Zero(t, dbItem.ThinkErrorCount)
+		}
+	})
 }
@@ -5,19 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	categorypkg "github.com/deframer/news-deframer/pkg/category"
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/deframer/news-deframer/pkg/downloader"
 	"github.com/deframer/news-deframer/pkg/feeds"
+	"github.com/deframer/news-deframer/pkg/langdetect"
+	"github.com/deframer/news-deframer/pkg/metrics"
 	"github.com/deframer/news-deframer/pkg/think"
+	"github.com/deframer/news-deframer/pkg/tracing"
 	"github.com/deframer/news-deframer/pkg/util/netutil"
 	"github.com/deframer/news-deframer/pkg/util/text"
 	"github.com/google/uuid"
 	"github.com/mmcdole/gofeed"
+	"go.opentelemetry.io/otel/attribute"
 	"goa.design/clue/log"
 	"golang.org/x/net/publicsuffix"
 )
@@ -29,6 +36,11 @@ const thinkerFixerLookback = 90 * 24 * time.Hour
 const thinkerFixerMinErrorCount = 4
 const thinkerFixerMaxErrorCount = 6
 const publicationDateGracePeriod = 10 * time.Minute
+const rateLimitBackoff = time.Minute
+
+// DeadLetterErrorCount is the error count from which neither the thinker nor
+// the fixer picks an item up again.
+const DeadLetterErrorCount = thinkerFixerMaxErrorCount + 1
 
 type Mode string
 
@@ -37,8 +49,18 @@ const (
 	ModeThinker               Mode = "thinker"
 	ModeThinkerFixer          Mode = "thinker-fixer"
 	ModeThinkerUpdateLLMModel Mode = "thinker-update-llm-model"
+	ModeThinkerUpdatePrompt   Mode = "thinker-update-prompt"
+	ModeWebhooks              Mode = "webhooks"
 )
 
+// Repository is what the syncer needs from the database: the feeds and
+// items, the analysis cache and the accounting of the LLM calls.
+type Repository interface {
+	database.Repository
+	database.ThinkCacheRepository
+	database.LLMUsageRepository
+}
+
 type FeedSyncer interface {
 	SyncFeed(id uuid.UUID) error
 	StopPolling(id uuid.UUID) error
@@ -48,17 +70,22 @@ type FeedSyncer interface {
 type Syncer struct {
 	ctx   context.Context
 	cfg   *config.Config
-	repo  database.Repository
+	repo  Repository
 	dl    downloader.Downloader
 	feeds feeds.Feeds
 	think think.Think
+	// rateLimitedUntil holds the unix nanos until the provider asked us to back off
+	rateLimitedUntil atomic.Int64
 }
 
-func New(ctx context.Context, cfg *config.Config, repo database.Repository) (*Syncer, error) {
+func New(ctx context.Context, cfg *config.Config, repo Repository) (*Syncer, error) {
 	th, err := think.New(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.ThinkCache {
+		th = think.NewCached(ctx, th, repo, cfg.ThinkModel())
+	}
 
 	return &Syncer{
 		ctx:   ctx,
@@ -94,6 +121,10 @@ func (s *Syncer) Poll(mode Mode) {
 		s.pollThinkerUpdateLLMModel()
 		return
 	}
+	if mode == ModeThinkerUpdatePrompt {
+		s.pollThinkerUpdatePrompt()
+		return
+	}
 	if mode != ModeIngester {
 		log.Warnf(s.ctx, "Unknown mode, defaulting to ingester mode=%s", mode)
 	}
@@ -192,7 +223,33 @@ func (s *Syncer) pollThinkerUpdateLLMModel() {
 	}
 }
 
+func (s *Syncer) pollThinkerUpdatePrompt() {
+	for {
+		if s.ctx.Err() != nil {
+			log.Printf(s.ctx, "Stopping poller")
+			return
+		}
+
+		if s.processThinkerUpdatePromptBatch() {
+			log.Printf(s.ctx, "Thinker update prompt batch checked")
+			continue
+		}
+
+		log.Debugf(s.ctx, "Thinker update prompt sleep duration=%s", config.IdleSleepTime)
+
+		select {
+		case <-s.ctx.Done():
+			log.Printf(s.ctx, "Stopping poller")
+			return
+		case <-time.After(config.IdleSleepTime):
+		}
+	}
+}
+
 func (s *Syncer) processThinkerBatch() bool {
+	if s.thinkerRateLimited() || s.thinkerBudgetExceeded() {
+		return false
+	}
 	log.Printf(log.With(s.ctx,
 		log.KV{K: "llm_base_url", V: s.cfg.LLM_BaseURL},
 	), "processThinkerBatch")
@@ -210,11 +267,18 @@ func (s *Syncer) processThinkerBatch() bool {
 		current := i + 1
 		log.Debugf(s.ctx, "processThinkerItem item_id=%s feed_id=%s progress=%d/%d", items[i].ID, items[i].FeedID, current, len(items))
 		s.thinkItem(&items[i])
+		if s.thinkerRateLimited() {
+			// the remaining items stay locked and are picked up once the lock expires
+			break
+		}
 	}
 	return true
 }
 
 func (s *Syncer) processThinkerFixerBatch(lookback time.Duration) bool {
+	if s.thinkerRateLimited() || s.thinkerBudgetExceeded() {
+		return false
+	}
 	log.Printf(log.With(s.ctx,
 		log.KV{K: "llm_base_url", V: s.cfg.LLM_BaseURL},
 	), "processThinkerFixerBatch")
@@ -236,16 +300,23 @@ func (s *Syncer) processThinkerFixerBatch(lookback time.Duration) bool {
 		current := i + 1
 		log.Debugf(s.ctx, "processThinkerItem item_id=%s feed_id=%s progress=%d/%d", items[i].ID, items[i].FeedID, current, len(items))
 		s.thinkItem(&items[i])
+		if s.thinkerRateLimited() {
+			// the remaining items stay locked and are picked up once the lock expires
+			break
+		}
 	}
 	return true
 }
 
 func (s *Syncer) processThinkerUpdateLLMModelBatch() bool {
+	if s.thinkerRateLimited() || s.thinkerBudgetExceeded() {
+		return false
+	}
 	log.Printf(log.With(s.ctx,
 		log.KV{K: "llm_base_url", V: s.cfg.LLM_BaseURL},
-		log.KV{K: "llm_model", V: s.cfg.LLM_Model},
+		log.KV{K: "llm_model", V: s.cfg.ThinkModel()},
 	), "processThinkerUpdateLLMModelBatch")
-	items, err := s.repo.BeginThinkerUpdateLLMModelBatch(thinkerBatchSize, s.cfg.LLM_Model, config.DefaultLockDuration)
+	items, err := s.repo.BeginThinkerUpdateLLMModelBatch(thinkerBatchSize, s.cfg.ThinkModel(), config.DefaultLockDuration)
 	if err != nil {
 		log.Errorf(s.ctx, err, "Failed to query thinker update llm model candidates")
 		return false
@@ -259,6 +330,41 @@ func (s *Syncer) processThinkerUpdateLLMModelBatch() bool {
 		current := i + 1
 		log.Debugf(s.ctx, "processThinkerItem item_id=%s feed_id=%s progress=%d/%d", items[i].ID, items[i].FeedID, current, len(items))
 		s.thinkItem(&items[i])
+		if s.thinkerRateLimited() {
+			// the remaining items stay locked and are picked up once the lock expires
+			break
+		}
+	}
+	return true
+}
+
+func (s *Syncer) processThinkerUpdatePromptBatch() bool {
+	if s.thinkerRateLimited() || s.thinkerBudgetExceeded() {
+		return false
+	}
+	versions := think.PromptVersions(promptScope)
+	log.Printf(log.With(s.ctx,
+		log.KV{K: "llm_base_url", V: s.cfg.LLM_BaseURL},
+		log.KV{K: "prompt_versions", V: versions},
+	), "processThinkerUpdatePromptBatch")
+	items, err := s.repo.BeginThinkerUpdatePromptVersionBatch(thinkerBatchSize, versions, config.DefaultLockDuration)
+	if err != nil {
+		log.Errorf(s.ctx, err, "Failed to query thinker update prompt candidates")
+		return false
+	}
+	if len(items) == 0 {
+		return false
+	}
+
+	log.Printf(s.ctx, "Thinker update prompt candidates fetched count=%d", len(items))
+	for i := range items {
+		current := i + 1
+		log.Debugf(s.ctx, "processThinkerItem item_id=%s feed_id=%s progress=%d/%d", items[i].ID, items[i].FeedID, current, len(items))
+		s.thinkItem(&items[i])
+		if s.thinkerRateLimited() {
+			// the remaining items stay locked and are picked up once the lock expires
+			break
+		}
 	}
 	return true
 }
@@ -275,27 +381,34 @@ func (s *Syncer) syncNextScheduledFeed() bool {
 		return false
 	}
 	err = s.updatingFeed(feed)
+	if err != nil {
+		metrics.FeedsSynced.WithLabelValues(metrics.ResultError).Inc()
+	} else {
+		metrics.FeedsSynced.WithLabelValues(metrics.ResultOK).Inc()
+	}
 	if err := s.repo.EndFeedUpdate(feed.ID, err, config.PollingInterval); err != nil {
 		log.Errorf(s.ctx, err, "Failed to end feed update")
 	}
 	return true
 }
 
-func (s *Syncer) updatingFeed(feed *database.Feed) error {
+func (s *Syncer) updatingFeed(feed *database.Feed) (err error) {
 	log.Printf(s.ctx, "Updating feed id=%s url=%s", feed.ID, feed.URL)
+	ctx, span := tracing.Start(s.ctx, "syncer.update_feed", tracing.FeedID(feed.ID), attribute.String("url.full", feed.URL))
+	defer func() { tracing.End(span, err) }()
 
 	u, err := url.Parse(feed.URL)
 	if err != nil {
 		return err
 	}
 
-	rc, err := s.dl.DownloadRSSFeed(s.ctx, u)
+	rc, err := s.dl.DownloadRSSFeed(ctx, u)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = rc.Close() }()
 
-	parsedFeed, err := s.feeds.ParseFeed(s.ctx, rc)
+	parsedFeed, err := s.feeds.ParseFeed(ctx, rc)
 	if err != nil {
 		return err
 	}
@@ -426,6 +539,7 @@ func (s *Syncer) syncPendingFeedItems(feed *database.Feed, parsedFeed *gofeed.Fe
 
 	total := len(pendingItems)
 	count = 0
+	detected := make(map[string]int64)
 	for _, item := range items {
 		if s.ctx.Err() != nil {
 			// context might be canceled
@@ -435,14 +549,40 @@ func (s *Syncer) syncPendingFeedItems(feed *database.Feed, parsedFeed *gofeed.Fe
 		}
 		if _, ok := pendingItems[item.Hash]; ok {
 			count++
-			log.Debugf(s.ctx, "syncItem feed=%s hash=%s progress=%d/%d", feed.ID, item.Hash, count, total)
-			s.syncItem(feed, item.Hash, item.Item, language)
+			itemLanguage, detectedLanguage := s.itemLanguage(item.Item, language)
+			if detectedLanguage != "" {
+				detected[detectedLanguage]++
+			}
+			log.Debugf(s.ctx, "syncItem feed=%s hash=%s progress=%d/%d language=%s", feed.ID, item.Hash, count, total, itemLanguage)
+			s.syncItem(feed, item.Hash, item.Item, itemLanguage)
 		}
 	}
 
+	if err := s.repo.AddFeedLanguageStats(feed.ID, detected); err != nil {
+		log.Errorf(s.ctx, err, "Failed to update feed language stats feed_id=%s", feed.ID)
+	}
+
 	return count, nil
 }
 
+// itemLanguage returns the language an item is analyzed in together with the
+// detected language for the feed stats. The feed language is kept when the
+// detection is not confident or finds a language without prompts.
+func (s *Syncer) itemLanguage(item *gofeed.Item, feedLanguage string) (string, string) {
+	if s.cfg == nil || !s.cfg.LanguageDetection {
+		return feedLanguage, ""
+	}
+
+	res := langdetect.Detect(text.StripHTML(item.Title) + "\n" + text.StripHTML(item.Description))
+	if res.Language == "" || res.Confidence < s.cfg.LanguageMinConfidence {
+		return feedLanguage, langdetect.Undetermined
+	}
+	if res.Language != feedLanguage && slices.Contains(categorypkg.Languages(), res.Language) {
+		return res.Language, res.Language
+	}
+	return feedLanguage, res.Language
+}
+
 func (s *Syncer) syncItem(feed *database.Feed, hash string, item *gofeed.Item, language string) {
 	pubDate := time.Now()
 	if item.PublishedParsed != nil {
@@ -493,6 +633,8 @@ func (s *Syncer) syncItem(feed *database.Feed, hash string, item *gofeed.Item, l
 
 	if err := s.repo.UpsertItem(dbItem); err != nil {
 		log.Errorf(s.ctx, err, "failed to create item hash=%s", hash)
+	} else {
+		metrics.ItemsIngested.Inc()
 	}
 
 	// move the lock time in the future (we also extend the sleep time to have a fair execution window)
@@ -501,15 +643,19 @@ func (s *Syncer) syncItem(feed *database.Feed, hash string, item *gofeed.Item, l
 	}
 }
 
-func (s *Syncer) thinkItem(dbItem *database.Item) {
+// thinkItem analyzes the item and stores the outcome; it reports whether the
+// item got a new result.
+func (s *Syncer) thinkItem(dbItem *database.Item) bool {
 	if dbItem == nil {
-		return
+		return false
 	}
+	ctx, span := tracing.Start(s.ctx, "syncer.think_item", tracing.ItemID(dbItem.ID), tracing.FeedID(dbItem.FeedID))
+	defer span.End()
 
 	parsedItem, err := s.parseItemContent(dbItem.Content)
 	if err != nil {
 		log.Errorf(s.ctx, err, "Failed to parse item content item_id=%s", dbItem.ID)
-		return
+		return false
 	}
 
 	language := "en"
@@ -521,10 +667,24 @@ func (s *Syncer) thinkItem(dbItem *database.Item) {
 	if dbItem.Feed.HasTag("image_preference_enclosure") {
 		pref = MediaResolverAffinityEnclosure
 	}
-	result, err := s.renderThoughtsAndItem(parsedItem, language, dbItem.ThinkErrorCount, pref, "item_id", dbItem.ID, "item_url", parsedItem.Link)
+	result, err := s.renderThoughtsAndItem(ctx, parsedItem, language, dbItem.ThinkErrorCount, pref, "item_id", dbItem.ID, "item_url", parsedItem.Link)
 	if err != nil {
-		return
+		return false
 	}
+	s.recordUsage(dbItem, result.usage)
+	if result.thinkErrorKind != nil {
+		metrics.ThinkerItems.WithLabelValues(metrics.ResultError, *result.thinkErrorKind).Inc()
+	} else {
+		metrics.ThinkerItems.WithLabelValues(metrics.ResultOK, "").Inc()
+	}
+
+	if result.thinkResult == nil && dbItem.ThinkResult != nil {
+		// a failed re-analysis (update lanes, admin re-runs) leaves the published
+		// analysis alone; the update lanes retry the item once its lock expired
+		log.Warnf(s.ctx, "re-analysis failed, keeping the current result item_id=%s", dbItem.ID)
+		return false
+	}
+
 	if result.mediaContent == nil {
 		result.mediaContent = dbItem.MediaContent
 	}
@@ -539,17 +699,98 @@ func (s *Syncer) thinkItem(dbItem *database.Item) {
 
 	dbItem.Content = result.content
 	dbItem.ThinkResult = result.thinkResult
+	// a new result is appended to the think_results history on upsert
+	dbItem.ThinkResultID = nil
 	dbItem.MediaContent = result.mediaContent
 	dbItem.ThinkError = result.thinkError
 	dbItem.ThinkErrorCount = result.nextErrorCount
+	dbItem.ThinkErrorKind = result.thinkErrorKind
 	dbItem.ThinkRating = result.thinkRating
 	dbItem.Categories = emptyStringArray(result.categories)
 	dbItem.Authors = emptyStringArray(result.authors)
 
 	// let the trend miner recreate it as it now has access to the thinker results
-	if err := s.repo.UpsertItemWithTrendInvalidation(dbItem); err != nil {
+	if err := s.repo.WithContext(ctx).UpsertItemWithTrendInvalidation(dbItem); err != nil {
 		log.Errorf(s.ctx, err, "failed to update item item_id=%s", dbItem.ID)
+		return false
+	}
+	return dbItem.ThinkResult != nil
+}
+
+// ThinkerQueueDepth counts the items waiting in the thinker, fixer and update
+// lanes and the dead-lettered ones.
+func ThinkerQueueDepth(cfg *config.Config, repo database.Repository) (*database.ThinkerQueueDepth, error) {
+	depth, err := repo.GetThinkerQueueDepth(maxThinkRetries, thinkerFixerMaxErrorCount)
+	if err != nil {
+		return nil, err
+	}
+	depth.UpdateLLMModel, depth.UpdatePrompt, err = repo.CountThinkerUpdateCandidates(cfg.ThinkModel(), think.PromptVersions(promptScope))
+	if err != nil {
+		return nil, err
 	}
+	return depth, nil
+}
+
+// ThinkItems analyzes the items right away, whatever lane they are in, and
+// returns how many got a result. Failures count as errors of the item like
+// in the queue. The items need their feed loaded.
+func (s *Syncer) ThinkItems(items []database.Item) (int, error) {
+	if s.thinkerBudgetExceeded() {
+		return 0, fmt.Errorf("LLM monthly budget exceeded")
+	}
+	analyzed := 0
+	for i := range items {
+		if s.thinkerRateLimited() {
+			return analyzed, fmt.Errorf("LLM provider rate limit, stopped after %d of %d items", i, len(items))
+		}
+		if s.thinkItem(&items[i]) {
+			analyzed++
+		}
+	}
+	return analyzed, nil
+}
+
+func (s *Syncer) recordUsage(dbItem *database.Item, usage []database.LLMUsage) {
+	if len(usage) == 0 {
+		return
+	}
+	for i := range usage {
+		metrics.LLMRequestDuration.WithLabelValues(usage[i].Provider, usage[i].LLMModel).Observe(float64(usage[i].LatencyMs) / 1000)
+		usage[i].ItemID = &dbItem.ID
+		usage[i].FeedID = &dbItem.FeedID
+		usage[i].Cost = s.cfg.LLM_Prices.Cost(usage[i].LLMModel, usage[i].PromptTokens, usage[i].CompletionTokens)
+	}
+	if err := s.repo.CreateLLMUsages(usage); err != nil {
+		log.Errorf(s.ctx, err, "failed to record llm usage item_id=%s", dbItem.ID)
+	}
+}
+
+// thinkerBudgetExceeded reports whether the LLM cost of the current month has
+// reached LLM_MonthlyBudget. The thinker lanes stay idle until the next month
+// or until the budget is raised.
+func (s *Syncer) thinkerBudgetExceeded() bool {
+	if s.cfg.LLM_MonthlyBudget <= 0 {
+		return false
+	}
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	cost, err := s.repo.SumLLMCostSince(monthStart)
+	if err != nil {
+		log.Errorf(s.ctx, err, "Failed to query llm cost, pausing thinker")
+		return true
+	}
+	if cost >= s.cfg.LLM_MonthlyBudget {
+		log.Warnf(s.ctx, "LLM monthly budget exceeded, pausing thinker cost=%.2f budget=%.2f", cost, s.cfg.LLM_MonthlyBudget)
+		return true
+	}
+	return false
+}
+
+// thinkerRateLimited reports whether the provider recently answered with a
+// rate limit. The thinker lanes stay idle until rateLimitBackoff has passed.
+func (s *Syncer) thinkerRateLimited() bool {
+	until := s.rateLimitedUntil.Load()
+	return until != 0 && time.Now().UnixNano() < until
 }
 
 type thinkerOutcome struct {
@@ -557,11 +798,13 @@ type thinkerOutcome struct {
 	thinkResult    *database.ThinkResult
 	mediaContent   *database.MediaContent
 	thinkError     *string
+	thinkErrorKind *string
 	nextErrorCount int
 	thinkRating    float64
 	categories     []string
 	authors        database.StringArray
 	pubDate        *time.Time
+	usage          []database.LLMUsage
 }
 
 func emptyStringArray(values []string) database.StringArray {
@@ -571,7 +814,7 @@ func emptyStringArray(values []string) database.StringArray {
 	return database.StringArray(values)
 }
 
-func (s *Syncer) renderThoughtsAndItem(parsedItem *gofeed.Item, language string, currentErrorCount int, pref MediaResolverAffinity, logKeys ...any) (*thinkerOutcome, error) {
+func (s *Syncer) renderThoughtsAndItem(ctx context.Context, parsedItem *gofeed.Item, language string, currentErrorCount int, pref MediaResolverAffinity, logKeys ...any) (*thinkerOutcome, error) {
 	if parsedItem == nil {
 		return nil, fmt.Errorf("parsed item is nil")
 	}
@@ -582,9 +825,11 @@ func (s *Syncer) renderThoughtsAndItem(parsedItem *gofeed.Item, language string,
 	}
 	futureErrorCount := currentErrorCount + 1
 	ignoreCategoryErrors := futureErrorCount > maxThinkRetries || futureErrorCount > thinkerFixerMaxErrorCount
-	res, err := s.think.Run(promptScope, language, req, ignoreCategoryErrors)
+	res, err := s.runThink(ctx, promptScope, language, req, ignoreCategoryErrors)
+	usage := think.UsageOf(res, err)
 
 	var thinkError *string
+	var thinkErrorKind *string
 	var mediaContent *database.MediaContent
 	var thinkRating float64
 	nextErrorCount := currentErrorCount
@@ -594,10 +839,18 @@ func (s *Syncer) renderThoughtsAndItem(parsedItem *gofeed.Item, language string,
 			log.Debugf(s.ctx, "%s", formatLogKeys("context canceled", logKeys...))
 			return nil, err
 		}
-		log.Errorf(s.ctx, err, "%s", formatLogKeys("analysis failed", logKeys...))
+		kind := think.KindOf(err)
+		log.Errorf(s.ctx, err, "%s", formatLogKeys("analysis failed", append(logKeys, "error_kind", kind)...))
 		errStr := err.Error()
 		thinkError = &errStr
-		nextErrorCount++
+		kindStr := string(kind)
+		thinkErrorKind = &kindStr
+		if kind == think.ErrorRateLimited {
+			// not the item's fault: keep its error count and let the whole thinker back off
+			s.rateLimitedUntil.Store(time.Now().Add(rateLimitBackoff).UnixNano())
+		} else {
+			nextErrorCount++
+		}
 	} else {
 		synthesizedMedia, err := updateContent(parsedItem, res, pref)
 		if err != nil {
@@ -636,14 +889,48 @@ func (s *Syncer) renderThoughtsAndItem(parsedItem *gofeed.Item, language string,
 		thinkResult:    res,
 		mediaContent:   mediaContent,
 		thinkError:     thinkError,
+		thinkErrorKind: thinkErrorKind,
 		nextErrorCount: nextErrorCount,
 		thinkRating:    thinkRating,
 		categories:     s.feeds.ExtractCategories(parsedItem),
 		authors:        s.extractAndNormalizeAuthors(parsedItem, language),
 		pubDate:        pubDate,
+		usage:          usage,
 	}, nil
 }
 
+// runThink calls the LLM in a span carrying the models, the token counts and
+// the error kind of a failure.
+func (s *Syncer) runThink(ctx context.Context, scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	_, span := tracing.Start(ctx, "llm.think",
+		attribute.String("deframer.prompt.scope", scope),
+		attribute.String("deframer.language", language),
+	)
+	res, err := s.think.Run(scope, language, req, ignoreCategoryErrors)
+
+	var providers, models []string
+	var promptTokens, completionTokens int64
+	for _, u := range think.UsageOf(res, err) {
+		if !slices.Contains(providers, u.Provider) {
+			providers = append(providers, u.Provider)
+		}
+		models = append(models, u.LLMModel)
+		promptTokens += u.PromptTokens
+		completionTokens += u.CompletionTokens
+	}
+	span.SetAttributes(
+		attribute.StringSlice("gen_ai.provider.name", providers),
+		attribute.StringSlice("gen_ai.request.model", models),
+		attribute.Int64("gen_ai.usage.input_tokens", promptTokens),
+		attribute.Int64("gen_ai.usage.output_tokens", completionTokens),
+	)
+	if err != nil {
+		span.SetAttributes(attribute.String("error.type", string(think.KindOf(err))))
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
 func (s *Syncer) parseItemContent(content string) (*gofeed.Item, error) {
 	wrapped := `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel>` + content + `</channel></rss>`
 	pf, err := gofeed.NewParser().Parse(strings.NewReader(wrapped))
@@ -2,14 +2,20 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/url"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	categorypkg "github.com/deframer/news-deframer/pkg/category"
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
 	"github.com/deframer/news-deframer/pkg/feeds"
+	"github.com/deframer/news-deframer/pkg/langdetect"
 	"github.com/deframer/news-deframer/pkg/think"
 	"github.com/google/uuid"
 	"github.com/mmcdole/gofeed"
@@ -27,8 +33,13 @@ type mockRepo struct {
 	beginThinkerBatchFunc                func(limit int, since time.Time, minErrorCount int, maxErrorCount int, lockDuration time.Duration) ([]database.Item, error)
 	beginThinkerFixerFunc                func(limit int, since time.Time, minErrorCount int, maxErrorCount int, lockDuration time.Duration) ([]database.Item, error)
 	beginThinkerUpdateLLMModelBatchFunc  func(limit int, llmModel string, lockDuration time.Duration) ([]database.Item, error)
+	beginThinkerUpdatePromptBatchFunc    func(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]database.Item, error)
 	beginThinkerBatchCalls               int
 	beginThinkerUpdateLLMModelBatchCalls int
+	beginThinkerUpdatePromptBatchCalls   int
+	createLLMUsagesFunc                  func(usages []database.LLMUsage) error
+	addFeedLanguageStatsFunc             func(feedID uuid.UUID, counts map[string]int64) error
+	sumLLMCostSinceFunc                  func(since time.Time) (float64, error)
 	getTopTrendByDomainFunc              func(domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error)
 	getContextByDomainFunc               func(term string, domain string, language string, date *time.Time, days int) ([]database.TrendContext, error)
 	getLifecycleByDomainFunc             func(term string, domain string, language string, date *time.Time, days int) ([]database.Lifecycle, error)
@@ -115,6 +126,85 @@ func (m *mockRepo) BeginThinkerUpdateLLMModelBatch(limit int, llmModel string, l
 	}
 	return nil, nil
 }
+func (m *mockRepo) CreateLLMUsages(usages []database.LLMUsage) error {
+	if m.createLLMUsagesFunc != nil {
+		return m.createLLMUsagesFunc(usages)
+	}
+	return nil
+}
+func (m *mockRepo) SumLLMCostSince(since time.Time) (float64, error) {
+	if m.sumLLMCostSinceFunc != nil {
+		return m.sumLLMCostSinceFunc(since)
+	}
+	return 0, nil
+}
+func (m *mockRepo) AddFeedLanguageStats(feedID uuid.UUID, counts map[string]int64) error {
+	if m.addFeedLanguageStatsFunc != nil {
+		return m.addFeedLanguageStatsFunc(feedID, counts)
+	}
+	return nil
+}
+func (m *mockRepo) GetFeedLanguageStats() ([]database.FeedLanguageStatsResult, error) {
+	return nil, nil
+}
+func (m *mockRepo) ListThinkResultHistory(itemID uuid.UUID) ([]database.ThinkResultRecord, error) {
+	return nil, nil
+}
+func (m *mockRepo) RollbackThinkResults(llmModel string, since time.Time) ([]uuid.UUID, error) {
+	return nil, nil
+}
+func (m *mockRepo) CountLockWaits() (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) Ping(timeout time.Duration) error {
+	return nil
+}
+
+func (m *mockRepo) GetSchemaStatus(timeout time.Duration) (*database.SchemaStatus, error) {
+	return &database.SchemaStatus{Version: database.SchemaVersion}, nil
+}
+
+func (m *mockRepo) WithContext(ctx context.Context) database.Repository {
+	return m
+}
+
+func (m *mockRepo) CountThinkerUpdateCandidates(llmModel string, promptVersions map[string]string) (int64, int64, error) {
+	return 0, 0, nil
+}
+func (m *mockRepo) GetThinkerQueueDepth(maxRetries int, maxFixerErrorCount int) (*database.ThinkerQueueDepth, error) {
+	return &database.ThinkerQueueDepth{}, nil
+}
+func (m *mockRepo) GetDeadItemGroups(filter database.DeadItemFilter) ([]database.DeadItemGroup, error) {
+	return nil, nil
+}
+func (m *mockRepo) FindDeadItems(filter database.DeadItemFilter, limit int) ([]database.Item, error) {
+	return nil, nil
+}
+func (m *mockRepo) RequeueDeadItems(filter database.DeadItemFilter) (int64, error) {
+	return 0, nil
+}
+func (m *mockRepo) PurgeDeadItems(filter database.DeadItemFilter) (int64, error) {
+	return 0, nil
+}
+func (m *mockRepo) GetLLMUsageReport(since time.Time, groupBy []string) ([]database.LLMUsageReport, error) {
+	return nil, nil
+}
+func (m *mockRepo) FindThinkCache(key string) (*database.ThinkResult, error) {
+	return nil, nil
+}
+
+func (m *mockRepo) UpsertThinkCache(entry *database.ThinkCache) error {
+	return nil
+}
+
+func (m *mockRepo) BeginThinkerUpdatePromptVersionBatch(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]database.Item, error) {
+	m.beginThinkerUpdatePromptBatchCalls++
+	if m.beginThinkerUpdatePromptBatchFunc != nil {
+		return m.beginThinkerUpdatePromptBatchFunc(limit, promptVersions, lockDuration)
+	}
+	return nil, nil
+}
 func (m *mockRepo) FindFeedScheduleById(feedID uuid.UUID) (*database.FeedSchedule, error) {
 	return nil, nil
 }
@@ -125,13 +215,25 @@ func (m *mockRepo) FindItemsByRootDomain(rootDomain string, limit int) ([]databa
 	return nil, nil
 }
 
-func (m *mockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, limit int) ([]database.AnalyzedItem, error) {
+func (m *mockRepo) FindAnalyzedItemsByRootDomain(rootDomain string, filter database.SiteFilter, limit int) ([]database.AnalyzedItem, error) {
 	return nil, nil
 }
 
 func (m *mockRepo) FindFirstAnalyzedItemByUrl(u *url.URL) (*database.AnalyzedItem, error) {
 	return nil, nil
 }
+func (m *mockRepo) FindFirstAnalyzedItemsByUrls(urls []string) (map[string]database.AnalyzedItem, error) {
+	return nil, nil
+}
+func (m *mockRepo) SearchItems(search database.ItemSearch) ([]database.SearchHit, error) {
+	return nil, nil
+}
+func (m *mockRepo) FindItemEventsAfter(after int64, filter database.ItemEventFilter, limit int) ([]database.ItemEvent, error) {
+	return nil, nil
+}
+func (m *mockRepo) LatestItemEventID() (int64, error) {
+	return 0, nil
+}
 
 func (m *mockRepo) GetTopTrendByDomain(domain string, language string, date *time.Time, days int) ([]database.TrendMetric, error) {
 	if m.getTopTrendByDomainFunc != nil {
@@ -243,6 +345,34 @@ func TestProcessThinkerBatchUsesSingleQuery(t *testing.T) {
 	assert.Equal(t, 1, repo.beginThinkerBatchCalls)
 }
 
+func TestProcessThinkerBatchPausesOverBudget(t *testing.T) {
+	repo := &mockRepo{}
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+	cfg.LLM_MonthlyBudget = 5
+
+	var costSince time.Time
+	repo.sumLLMCostSinceFunc = func(since time.Time) (float64, error) {
+		costSince = since
+		return 5.01, nil
+	}
+
+	s, err := New(context.Background(), cfg, repo)
+	assert.NoError(t, err)
+
+	assert.False(t, s.processThinkerBatch())
+	assert.False(t, s.processThinkerFixerBatch(time.Hour))
+	assert.Equal(t, 0, repo.beginThinkerBatchCalls)
+	assert.Equal(t, 1, costSince.Day())
+	assert.Equal(t, time.UTC, costSince.Location())
+
+	repo.sumLLMCostSinceFunc = func(since time.Time) (float64, error) {
+		return 4.99, nil
+	}
+	assert.False(t, s.processThinkerBatch())
+	assert.Equal(t, 1, repo.beginThinkerBatchCalls)
+}
+
 func TestProcessThinkerUpdateLLMModelBatchUsesSingleQuery(t *testing.T) {
 	repo := &mockRepo{}
 	cfg, err := config.Load()
@@ -283,6 +413,29 @@ func TestPollThinkerUpdateLLMModel(t *testing.T) {
 	assert.Equal(t, 1, repo.beginThinkerUpdateLLMModelBatchCalls)
 }
 
+func TestProcessThinkerUpdatePromptBatchUsesPromptVersions(t *testing.T) {
+	repo := &mockRepo{}
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+
+	expected := think.PromptVersions(promptScope)
+
+	repo.beginThinkerUpdatePromptBatchFunc = func(limit int, promptVersions map[string]string, lockDuration time.Duration) ([]database.Item, error) {
+		assert.Equal(t, thinkerBatchSize, limit)
+		assert.Equal(t, expected, promptVersions)
+		assert.NotEmpty(t, promptVersions["en"])
+		assert.Equal(t, config.DefaultLockDuration, lockDuration)
+		return nil, nil
+	}
+
+	s, err := New(context.Background(), cfg, repo)
+	assert.NoError(t, err)
+
+	ok := s.processThinkerUpdatePromptBatch()
+	assert.False(t, ok)
+	assert.Equal(t, 1, repo.beginThinkerUpdatePromptBatchCalls)
+}
+
 func TestStopPolling(t *testing.T) {
 	repo := &mockRepo{}
 	cfg, err := config.Load()
@@ -517,6 +670,96 @@ func TestSyncPendingFeedItemsUpdatesFeedLanguageWhenMissing(t *testing.T) {
 	assert.True(t, repo.upsertFeedCalled)
 }
 
+func TestSyncPendingFeedItemsDetectsItemLanguage(t *testing.T) {
+	var saved []database.Item
+	var stats map[string]int64
+	repo := &mockRepo{
+		upsertItemFunc: func(item *database.Item) error {
+			saved = append(saved, *item)
+			return nil
+		},
+		addFeedLanguageStatsFunc: func(feedID uuid.UUID, counts map[string]int64) error {
+			stats = counts
+			return nil
+		},
+	}
+	cfg := &config.Config{LanguageDetection: true, LanguageMinConfidence: 0.95}
+	s := &Syncer{ctx: context.Background(), cfg: cfg, repo: repo, feeds: &mockFeeds{}}
+	feedLanguage := "de"
+	feed := &database.Feed{Base: database.Base{ID: uuid.New()}, URL: "https://example.com/feed.xml", Language: &feedLanguage}
+
+	items := []feeds.ItemHashPair{
+		{Hash: "de", Item: &gofeed.Item{Title: "Bundesregierung beschließt neues Entlastungspaket", Description: "Die Koalition hat sich nach langen Verhandlungen auf eine Senkung der Steuern geeinigt.", Link: "https://example.com/de"}},
+		{Hash: "en", Item: &gofeed.Item{Title: "Government agrees on new relief package", Description: "The coalition reached a deal on tax cuts after long negotiations with the opposition.", Link: "https://example.com/en"}},
+		{Hash: "it", Item: &gofeed.Item{Title: "Il governo approva la manovra economica", Description: "La maggioranza ha trovato un accordo sulle tasse dopo lunghe trattative.", Link: "https://example.com/it"}},
+		{Hash: "short", Item: &gofeed.Item{Title: "Live", Link: "https://example.com/short"}},
+	}
+	hashes := []string{"de", "en", "it", "short"}
+
+	count, err := s.syncPendingFeedItems(feed, &gofeed.Feed{}, items, hashes)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	languages := map[string]string{}
+	for _, item := range saved {
+		languages[item.Hash] = *item.Language
+	}
+	assert.Equal(t, map[string]string{"de": "de", "en": "en", "it": "it", "short": "de"}, languages)
+	assert.Equal(t, map[string]int64{"de": 1, "en": 1, "it": 1, langdetect.Undetermined: 1}, stats)
+
+	cfg.LanguageDetection = false
+	saved, stats = nil, nil
+	_, err = s.syncPendingFeedItems(feed, &gofeed.Feed{}, items, hashes)
+	assert.NoError(t, err)
+	for _, item := range saved {
+		assert.Equal(t, "de", *item.Language)
+	}
+	assert.Empty(t, stats)
+}
+
+// TestSupportedLanguagesAreComplete fails when a language is only partially
+// supported: every language with localized categories needs a prompt, author
+// joiners, a detection profile, example stop words and a spaCy model of the
+// miner, and every detected language needs categories so items are not
+// silently given the feed language.
+func TestSupportedLanguagesAreComplete(t *testing.T) {
+	content, err := os.ReadFile("../../dummy-stopwords.json")
+	assert.NoError(t, err)
+	var stopWords []struct {
+		Language  string   `json:"language"`
+		StopWords []string `json:"stop_words"`
+	}
+	assert.NoError(t, json.Unmarshal(content, &stopWords))
+	hasStopWords := map[string]bool{}
+	for _, entry := range stopWords {
+		hasStopWords[entry.Language] = len(entry.StopWords) > 0
+	}
+
+	spacyModels := map[string][]string{}
+	for file, pattern := range map[string]*regexp.Regexp{
+		"../../docker/docker-compose.yml": regexp.MustCompile(`SPACY_MODELS=\$\{SPACY_MODELS:-([^}]*)\}`),
+		"../../docker/env.example":        regexp.MustCompile(`(?m)^SPACY_MODELS=(.*)$`),
+	} {
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		if match := pattern.FindSubmatch(content); assert.NotNil(t, match, "missing SPACY_MODELS in %s", file) {
+			spacyModels[file] = strings.Fields(string(match[1]))
+		}
+	}
+
+	prompts := think.PromptVersions("deframer")
+	languages := categorypkg.Languages()
+	for _, lang := range languages {
+		assert.NotEmpty(t, prompts[lang], "missing deframer prompt for %s", lang)
+		assert.NotEmpty(t, authorJoinersByLanguage[lang], "missing author joiners for %s", lang)
+		assert.True(t, hasStopWords[lang], "missing example stop words for %s", lang)
+		for file, models := range spacyModels {
+			assert.Contains(t, models, lang, "missing spaCy model for %s in %s", lang, file)
+		}
+	}
+	assert.Equal(t, languages, langdetect.Languages())
+}
+
 // Mocks for testing processItem
 type mockThink struct {
 	runFunc func(scope string, language string, req think.Request, ignoreCategoryErrors bool) (*database.ThinkResult, error)
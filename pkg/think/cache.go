@@ -0,0 +1,89 @@
+package think
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"goa.design/clue/log"
+	"golang.org/x/text/unicode/norm"
+)
+
+type cached struct {
+	ctx   context.Context
+	think Think
+	store database.ThinkCacheRepository
+	model string
+}
+
+// NewCached answers requests that were already analyzed with the same prompt
+// version, model and language from store and only forwards misses to t.
+// Results of lenient runs (ignoreCategoryErrors) are never stored, as they may
+// carry a category that did not pass validation. Storage errors are logged and
+// never fail the analysis.
+func NewCached(ctx context.Context, t Think, store database.ThinkCacheRepository, model string) Think {
+	return &cached{
+		ctx:   ctx,
+		think: t,
+		store: store,
+		model: model,
+	}
+}
+
+func (c *cached) Run(prompt string, language string, request Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	p, err := getPrompt(prompt, language)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(p.version, c.model, language, request)
+
+	res, err := c.store.FindThinkCache(key)
+	if err != nil {
+		log.Errorf(c.ctx, err, "Failed to read think cache key=%s", key)
+	}
+	if res != nil {
+		log.Debugf(c.ctx, "think cache hit key=%s", key)
+		// the key folds whitespace, so the offsets of the stored evidence may
+		// not fit this request's text
+		res.Evidence = resolveEvidence(request, res.Evidence)
+		return res, nil
+	}
+
+	res, err = c.think.Run(prompt, language, request, ignoreCategoryErrors)
+	if err != nil {
+		return nil, err
+	}
+	if ignoreCategoryErrors {
+		return res, nil
+	}
+
+	if err := c.store.UpsertThinkCache(&database.ThinkCache{
+		Key:           key,
+		LLMModel:      c.model,
+		PromptVersion: p.version,
+		Language:      language,
+		ThinkResult:   res,
+	}); err != nil {
+		log.Errorf(c.ctx, err, "Failed to write think cache key=%s", key)
+	}
+
+	return res, nil
+}
+
+func cacheKey(promptVersion, model, language string, request Request) string {
+	h := sha256.New()
+	for _, part := range []string{promptVersion, model, language, normalizeCacheText(request.Title), normalizeCacheText(request.Description)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeCacheText folds the differences syndicated copies usually have:
+// unicode composition and whitespace. Case is kept on purpose, shouting in
+// capitals is part of what gets scored.
+func normalizeCacheText(s string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(s)), " ")
+}
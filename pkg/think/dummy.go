@@ -19,7 +19,8 @@ func (d *dummy) Run(prompt string, language string, request Request, ignoreCateg
 		return nil, err
 	}
 
-	if _, err := getPrompt(prompt, language); err != nil {
+	p, err := getPrompt(prompt, language)
+	if err != nil {
 		return nil, err
 	}
 
@@ -32,6 +33,7 @@ func (d *dummy) Run(prompt string, language string, request Request, ignoreCateg
 
 	result := &database.ThinkResult{
 		LLMModel:                    "dummy",
+		PromptVersion:               p.version,
 		TitleCorrected:              request.Title,
 		TitleCorrectionReason:       "Dummy output, not AI-generated.",
 		DescriptionCorrected:        request.Description,
@@ -56,7 +58,7 @@ func (d *dummy) Run(prompt string, language string, request Request, ignoreCateg
 	}
 	result.Category = category
 
-	if err := validateAndNormalizeThinkResult(language, result, ignoreCategoryErrors); err != nil {
+	if err := validateAndNormalizeThinkResult(language, request, result, ignoreCategoryErrors); err != nil {
 		return nil, err
 	}
 
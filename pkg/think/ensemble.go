@@ -0,0 +1,191 @@
+package think
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/database"
+)
+
+type ensembleMember struct {
+	model string
+	think Think
+}
+
+// ensemble asks every member for a result and merges the scores. The reasons
+// and corrected texts are taken from the member closest to the aggregated
+// overall score, so the explanation matches the published rating.
+type ensemble struct {
+	label       string
+	aggregation config.EnsembleAggregation
+	members     []ensembleMember
+}
+
+func newEnsemble(label string, aggregation config.EnsembleAggregation, members []ensembleMember) *ensemble {
+	return &ensemble{
+		label:       label,
+		aggregation: aggregation,
+		members:     members,
+	}
+}
+
+func (e *ensemble) Run(prompt string, language string, request Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	results := make([]*database.ThinkResult, len(e.members))
+	errs := make([]error, len(e.members))
+
+	var wg sync.WaitGroup
+	for i := range e.members {
+		wg.Go(func() {
+			res, err := e.members[i].think.Run(prompt, language, request, ignoreCategoryErrors)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", e.members[i].model, err)
+				return
+			}
+			results[i] = res
+		})
+	}
+	wg.Wait()
+
+	votes := make([]*database.ThinkResult, 0, len(results))
+	models := make([]string, 0, len(results))
+	var usage []database.LLMUsage
+	for i, res := range results {
+		usage = append(usage, UsageOf(res, errs[i])...)
+		if res == nil {
+			continue
+		}
+		votes = append(votes, res)
+		models = append(models, e.members[i].model)
+	}
+
+	// without a majority the result is too thin to trust; let the queue retry
+	if len(votes)*2 <= len(e.members) {
+		err := fmt.Errorf("ensemble quorum not reached (%d/%d): %w", len(votes), len(e.members), errors.Join(errs...))
+		return nil, withUsage(newError(ensembleErrorKind(errs), err), usage...)
+	}
+
+	result := e.aggregate(votes, models)
+	result.Usage = usage
+	return result, nil
+}
+
+func (e *ensemble) aggregate(votes []*database.ThinkResult, models []string) *database.ThinkResult {
+	score := func(pick func(*database.ThinkResult) float64) float64 {
+		values := make([]float64, len(votes))
+		for i := range votes {
+			values[i] = pick(votes[i])
+		}
+		if e.aggregation == config.Mean {
+			return mean(values)
+		}
+		return median(values)
+	}
+
+	overall := score(func(r *database.ThinkResult) float64 { return r.Overall })
+
+	representative := votes[0]
+	for _, vote := range votes[1:] {
+		if math.Abs(vote.Overall-overall) < math.Abs(representative.Overall-overall) {
+			representative = vote
+		}
+	}
+
+	result := *representative
+	result.LLMModel = e.label
+	result.Framing = score(func(r *database.ThinkResult) float64 { return r.Framing })
+	result.Clickbait = score(func(r *database.ThinkResult) float64 { return r.Clickbait })
+	result.Persuasive = score(func(r *database.ThinkResult) float64 { return r.Persuasive })
+	result.HyperStimulus = score(func(r *database.ThinkResult) float64 { return r.HyperStimulus })
+	result.Speculative = score(func(r *database.ThinkResult) float64 { return r.Speculative })
+	result.Overall = overall
+	result.Category = majorityCategory(votes, representative.Category)
+	result.Disagreement = disagreement(votes)
+	result.EnsembleModels = models
+	result.Repairs = nil
+	for _, vote := range votes {
+		for _, repair := range vote.Repairs {
+			if !slices.Contains(result.Repairs, repair) {
+				result.Repairs = append(result.Repairs, repair)
+			}
+		}
+	}
+
+	return &result
+}
+
+// ensembleErrorKind picks the retry policy for a missed quorum. A throttled
+// member is worth waiting for; the text only counts as refused when every
+// failed member refused it.
+func ensembleErrorKind(errs []error) ErrorKind {
+	var kinds []ErrorKind
+	for _, err := range errs {
+		if err != nil {
+			kinds = append(kinds, KindOf(err))
+		}
+	}
+	if slices.Contains(kinds, ErrorRateLimited) {
+		return ErrorRateLimited
+	}
+	if len(kinds) > 0 && !slices.ContainsFunc(kinds, func(kind ErrorKind) bool { return kind != ErrorRefused }) {
+		return ErrorRefused
+	}
+	if slices.Contains(kinds, ErrorTransient) {
+		return ErrorTransient
+	}
+	if len(kinds) > 0 {
+		return kinds[0]
+	}
+	return ErrorUnknown
+}
+
+// disagreement is the population standard deviation of the overall scores.
+// Scores live in 0..1, so the value is bounded by 0.5.
+func disagreement(votes []*database.ThinkResult) float64 {
+	values := make([]float64, len(votes))
+	for i := range votes {
+		values[i] = votes[i].Overall
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func majorityCategory(votes []*database.ThinkResult, fallback string) string {
+	counts := make(map[string]int, len(votes))
+	for _, vote := range votes {
+		counts[vote.Category]++
+	}
+	best := fallback
+	for _, vote := range votes {
+		if counts[vote.Category] > counts[best] {
+			best = vote.Category
+		}
+	}
+	return best
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,97 @@
+package think
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+)
+
+// ErrorKind classifies why an analysis failed, so the queue can pick a retry policy.
+type ErrorKind string
+
+const (
+	ErrorUnknown ErrorKind = "unknown"
+	// ErrorRateLimited means the provider throttled us; retrying later will succeed.
+	ErrorRateLimited ErrorKind = "rate_limited"
+	// ErrorTransient covers network failures, timeouts and server errors of the provider.
+	ErrorTransient ErrorKind = "transient"
+	// ErrorInvalidJSON means the answer could not be decoded.
+	ErrorInvalidJSON ErrorKind = "invalid_json"
+	// ErrorSchemaViolation means the answer decoded but broke the schema, e.g. an unknown category.
+	ErrorSchemaViolation ErrorKind = "schema_violation"
+	// ErrorOutOfBounds means a score was outside 0.0 - 1.0.
+	ErrorOutOfBounds ErrorKind = "out_of_bounds"
+	// ErrorRefused means the provider declined to analyze the text, e.g. by a safety filter.
+	ErrorRefused ErrorKind = "refused"
+)
+
+// Error is a classified failure of a Run call.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, err error) error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of an error returned by Run. Errors that were not
+// classified by a provider are ErrorUnknown.
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var thinkErr *Error
+	if errors.As(err, &thinkErr) {
+		return thinkErr.Kind
+	}
+	return ErrorUnknown
+}
+
+// classifyRequestError classifies a failed call to a provider API.
+func classifyRequestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var genaiErr genai.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	case errors.As(err, &genaiErr):
+		status = genaiErr.Code
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return newError(ErrorRateLimited, err)
+	case status == http.StatusRequestTimeout || status >= http.StatusInternalServerError:
+		return newError(ErrorTransient, err)
+	case status != 0:
+		return newError(ErrorUnknown, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return newError(ErrorTransient, err)
+	}
+	return newError(ErrorUnknown, err)
+}
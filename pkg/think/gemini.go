@@ -2,9 +2,7 @@ package think
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"sync"
 	"time"
 
 	categorypkg "github.com/deframer/news-deframer/pkg/category"
@@ -33,6 +31,18 @@ var geminiSchema = &genai.Schema{
 		"overall":                       {Type: genai.TypeNumber},
 		"overall_reason":                {Type: genai.TypeString},
 		"category":                      {Type: genai.TypeString},
+		"evidence": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"dimension": {Type: genai.TypeString, Enum: EvidenceDimensions},
+					"field":     {Type: genai.TypeString, Enum: EvidenceFields},
+					"quote":     {Type: genai.TypeString},
+				},
+				Required: []string{"dimension", "field", "quote"},
+			},
+		},
 	},
 	Required: []string{
 		"title_corrected", "title_correction_reason",
@@ -43,7 +53,7 @@ var geminiSchema = &genai.Schema{
 		"hyper_stimulus", "hyper_stimulus_reason",
 		"speculative", "speculative_reason",
 		"overall", "overall_reason",
-		"category",
+		"category", "evidence",
 	},
 }
 
@@ -51,12 +61,12 @@ type gemini struct {
 	ctx    context.Context
 	model  string
 	apiKey string
-	client *genai.Client
-	mu     sync.RWMutex
-	cache  map[string]*genai.Content
+	// followUp asks the model once more when its answer could not be used
+	followUp bool
+	client   *genai.Client
 }
 
-func newGemini(ctx context.Context, model, apiKey string) (*gemini, error) {
+func newGemini(ctx context.Context, model, apiKey string, followUp bool) (*gemini, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:  apiKey,
 		Backend: genai.BackendGeminiAPI, // Use VertexAI if you are on Google Cloud
@@ -65,11 +75,11 @@ func newGemini(ctx context.Context, model, apiKey string) (*gemini, error) {
 		return nil, err
 	}
 	return &gemini{
-		ctx:    ctx,
-		model:  model,
-		apiKey: apiKey,
-		client: client,
-		cache:  make(map[string]*genai.Content),
+		ctx:      ctx,
+		model:    model,
+		apiKey:   apiKey,
+		followUp: followUp,
+		client:   client,
 	}, nil
 }
 
@@ -78,45 +88,68 @@ func (g *gemini) Run(prompt string, language string, request Request, ignoreCate
 		return nil, err
 	}
 
-	key := prompt + ":" + language
+	sysPrompt, err := getPrompt(prompt, language)
+	if err != nil {
+		return nil, err
+	}
+	sysInstruction := &genai.Content{
+		Parts: []*genai.Part{
+			{Text: sysPrompt.text},
+		},
+	}
 
-	g.mu.RLock()
-	sysInstruction, ok := g.cache[key]
-	g.mu.RUnlock()
+	contents := genai.Text(fmt.Sprintf("Title: %s\nDescription: %s", request.Title, request.Description))
 
-	if !ok {
-		sysPrompt, err := getPrompt(prompt, language)
-		if err != nil {
-			return nil, err
+	// While Gemini does offer a Context Caching feature (which stores prompts on the server to avoid re-transmission),
+	// it currently has a minimum requirement of 32,768 tokens (roughly 25,000 words). The system prompt is significantly
+	// smaller than this threshold, so using Context Caching is not applicable or cost-effective for this specific use case.
+	// Sending the text every time is the correct approach here.
+
+	var usages []database.LLMUsage
+	content, usage, err := g.complete(sysInstruction, contents)
+	if usage != nil {
+		usages = append(usages, *usage)
+	}
+	if err != nil {
+		return nil, withUsage(err, usages...)
+	}
+
+	result, err := parseThinkResult(language, request, content, ignoreCategoryErrors)
+	if err != nil && g.followUp && repairable(err) {
+		log.Debugf(g.ctx, "gemini asking for a corrected answer error=%v", err)
+		contents = append(contents,
+			genai.NewContentFromText(content, genai.RoleModel),
+			genai.NewContentFromText(followUpPrompt(err), genai.RoleUser),
+		)
+		content, usage, err = g.complete(sysInstruction, contents)
+		if usage != nil {
+			usages = append(usages, *usage)
 		}
-		sysInstruction = &genai.Content{
-			Parts: []*genai.Part{
-				{Text: sysPrompt},
-			},
+		if err == nil {
+			result, err = parseThinkResult(language, request, content, ignoreCategoryErrors)
 		}
-		g.mu.Lock()
-		// Double-check locking: ensure another goroutine didn't populate the cache
-		// while we were loading the file and creating the struct.
-		if cached, exists := g.cache[key]; exists {
-			sysInstruction = cached
-		} else {
-			g.cache[key] = sysInstruction
+		if err == nil {
+			result.Repairs = append(result.Repairs, RepairFollowUp)
 		}
-		g.mu.Unlock()
 	}
+	if err != nil {
+		return nil, withUsage(err, usages...)
+	}
+	result.LLMModel = g.model
+	result.PromptVersion = sysPrompt.version
+	result.Usage = usages
 
-	var temperature float32 = 0.0
-
-	userPrompt := genai.Text(fmt.Sprintf("Title: %s\nDescription: %s", request.Title, request.Description))
+	return result, nil
+}
 
-	// While Gemini does offer a Context Caching feature (which stores prompts on the server to avoid re-transmission),
-	// it currently has a minimum requirement of 32,768 tokens (roughly 25,000 words). The system prompt is significantly
-	// smaller than this threshold, so using Context Caching is not applicable or cost-effective for this specific use case.
-	// Sending the text every time is the correct approach here.
+// complete sends the conversation and returns the answer. The usage is nil
+// when the request failed before the model answered.
+func (g *gemini) complete(sysInstruction *genai.Content, contents []*genai.Content) (string, *database.LLMUsage, error) {
+	var temperature float32 = 0.0
 
 	start := time.Now()
 	resp, err := g.client.Models.GenerateContent(g.ctx, g.model,
-		userPrompt,
+		contents,
 		&genai.GenerateContentConfig{
 			ResponseMIMEType:  "application/json",
 			ResponseSchema:    geminiSchema,
@@ -124,11 +157,17 @@ func (g *gemini) Run(prompt string, language string, request Request, ignoreCate
 			Temperature:       &temperature,
 		},
 	)
-	log.Debugf(g.ctx, "gemini request duration duration=%s", time.Since(start))
+	latency := time.Since(start)
+	log.Debugf(g.ctx, "gemini request duration duration=%s", latency)
 	if err != nil {
-		return nil, err
+		return "", nil, classifyRequestError(err)
 	}
 
+	usage := &database.LLMUsage{
+		Provider:  "gemini",
+		LLMModel:  g.model,
+		LatencyMs: latency.Milliseconds(),
+	}
 	if resp.UsageMetadata != nil {
 		var thoughts int32
 		if resp.UsageMetadata.ThoughtsTokenCount != 0 {
@@ -141,17 +180,35 @@ func (g *gemini) Run(prompt string, language string, request Request, ignoreCate
 			thoughts,
 			resp.UsageMetadata.TotalTokenCount,
 		)
-	}
 
-	var result database.ThinkResult
-	if err := json.Unmarshal([]byte(resp.Candidates[0].Content.Parts[0].Text), &result); err != nil {
-		return nil, err
+		// Gemini bills thoughts as output but reports them apart from the candidates
+		usage.PromptTokens = int64(resp.UsageMetadata.PromptTokenCount)
+		usage.CompletionTokens = int64(resp.UsageMetadata.CandidatesTokenCount) + int64(thoughts)
+		usage.ReasoningTokens = int64(thoughts)
+		usage.TotalTokens = int64(resp.UsageMetadata.TotalTokenCount)
 	}
-	result.LLMModel = g.model
 
-	if err := validateAndNormalizeThinkResult(language, &result, ignoreCategoryErrors); err != nil {
-		return nil, err
+	if reason := geminiRefusal(resp); reason != "" {
+		return "", usage, newError(ErrorRefused, fmt.Errorf("gemini refused the request: %s", reason))
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", usage, fmt.Errorf("no candidates returned from gemini provider")
 	}
 
-	return &result, nil
+	return resp.Candidates[0].Content.Parts[0].Text, usage, nil
+}
+
+// geminiRefusal returns why Gemini blocked the prompt or the answer, or "" when it did not.
+func geminiRefusal(resp *genai.GenerateContentResponse) string {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return string(resp.PromptFeedback.BlockReason)
+	}
+	if len(resp.Candidates) == 0 {
+		return ""
+	}
+	switch reason := resp.Candidates[0].FinishReason; reason {
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent, genai.FinishReasonSPII:
+		return string(reason)
+	}
+	return ""
 }
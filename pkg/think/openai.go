@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 	"time"
 
 	categorypkg "github.com/deframer/news-deframer/pkg/category"
@@ -35,6 +34,19 @@ var openAISchemaDefinition = jsonschema.Definition{
 		"overall":                       {Type: jsonschema.Number},
 		"overall_reason":                {Type: jsonschema.String},
 		"category":                      {Type: jsonschema.String},
+		"evidence": {
+			Type: jsonschema.Array,
+			Items: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"dimension": {Type: jsonschema.String, Enum: EvidenceDimensions},
+					"field":     {Type: jsonschema.String, Enum: EvidenceFields},
+					"quote":     {Type: jsonschema.String},
+				},
+				Required:             []string{"dimension", "field", "quote"},
+				AdditionalProperties: false,
+			},
+		},
 	},
 	Required: []string{
 		"title_corrected", "title_correction_reason",
@@ -45,7 +57,7 @@ var openAISchemaDefinition = jsonschema.Definition{
 		"hyper_stimulus", "hyper_stimulus_reason",
 		"speculative", "speculative_reason",
 		"overall", "overall_reason",
-		"category",
+		"category", "evidence",
 	},
 	// strictly required for OpenAI structured outputs
 	AdditionalProperties: false,
@@ -56,13 +68,12 @@ type openaiProvider struct {
 	model   string
 	apiKey  string
 	baseURL string
-	client  *openai.Client
-	mu      sync.RWMutex
-	// Cache stores the raw system prompt text string
-	cache map[string]string
+	// followUp asks the model once more when its answer could not be used
+	followUp bool
+	client   *openai.Client
 }
 
-func newOpenAI(ctx context.Context, model, apiKey, baseURL string) (*openaiProvider, error) {
+func newOpenAI(ctx context.Context, model, apiKey, baseURL string, followUp bool) (*openaiProvider, error) {
 	config := openai.DefaultConfig(apiKey)
 
 	// Set the BaseURL (Crucial for LM Studio)
@@ -73,12 +84,12 @@ func newOpenAI(ctx context.Context, model, apiKey, baseURL string) (*openaiProvi
 	client := openai.NewClientWithConfig(config)
 
 	return &openaiProvider{
-		ctx:     ctx,
-		model:   model,
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		client:  client,
-		cache:   make(map[string]string),
+		ctx:      ctx,
+		model:    model,
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		followUp: followUp,
+		client:   client,
 	}, nil
 }
 
@@ -87,29 +98,62 @@ func (o *openaiProvider) Run(prompt string, language string, request Request, ig
 		return nil, err
 	}
 
-	key := prompt + ":" + language
+	sysPrompt, err := getPrompt(prompt, language)
+	if err != nil {
+		return nil, err
+	}
 
-	o.mu.RLock()
-	sysPromptText, ok := o.cache[key]
-	o.mu.RUnlock()
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: sysPrompt.text,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("Title: %s\nDescription: %s", request.Title, request.Description),
+		},
+	}
 
-	if !ok {
-		var err error
-		sysPromptText, err = getPrompt(prompt, language)
-		if err != nil {
-			return nil, err
-		}
+	var usages []database.LLMUsage
+	content, usage, err := o.complete(messages)
+	if usage != nil {
+		usages = append(usages, *usage)
+	}
+	if err != nil {
+		return nil, withUsage(err, usages...)
+	}
 
-		o.mu.Lock()
-		// Double-check locking
-		if cached, exists := o.cache[key]; exists {
-			sysPromptText = cached
-		} else {
-			o.cache[key] = sysPromptText
+	result, err := parseThinkResult(language, request, content, ignoreCategoryErrors)
+	if err != nil && o.followUp && repairable(err) {
+		log.Debugf(o.ctx, "openai asking for a corrected answer error=%v", err)
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: followUpPrompt(err)},
+		)
+		content, usage, err = o.complete(messages)
+		if usage != nil {
+			usages = append(usages, *usage)
+		}
+		if err == nil {
+			result, err = parseThinkResult(language, request, content, ignoreCategoryErrors)
 		}
-		o.mu.Unlock()
+		if err == nil {
+			result.Repairs = append(result.Repairs, RepairFollowUp)
+		}
+	}
+	if err != nil {
+		return nil, withUsage(err, usages...)
 	}
+	result.LLMModel = o.model
+	result.PromptVersion = sysPrompt.version
+	result.Usage = usages
 
+	return result, nil
+}
+
+// complete sends the conversation and returns the answer. The usage is nil
+// when the request failed before the model answered.
+func (o *openaiProvider) complete(messages []openai.ChatCompletionMessage) (string, *database.LLMUsage, error) {
 	var temperature float32 = 0.0
 	var topP float32 = 1.0
 	var frequencyPenalty float32 = 0.0
@@ -118,7 +162,7 @@ func (o *openaiProvider) Run(prompt string, language string, request Request, ig
 	// Prepare the schema for the API request
 	schemaBytes, err := json.Marshal(openAISchemaDefinition)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
 	start := time.Now()
@@ -139,32 +183,33 @@ func (o *openaiProvider) Run(prompt string, language string, request Request, ig
 					Schema: json.RawMessage(schemaBytes),
 				},
 			},
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: sysPromptText,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: fmt.Sprintf("Title: %s\nDescription: %s", request.Title, request.Description),
-				},
-			},
+			Messages: messages,
 		},
 	)
-	log.Debugf(o.ctx, "openai request duration duration=%s", time.Since(start))
+	latency := time.Since(start)
+	log.Debugf(o.ctx, "openai request duration duration=%s", latency)
 	if err != nil {
-		return nil, err
+		return "", nil, classifyRequestError(err)
+	}
+
+	// OpenAI puts "Reasoning/Thought" tokens in CompletionTokensDetails
+	// Note: Not all local models return this detail, but GPT-o1/o3 do.
+	var thoughts int
+	if resp.Usage.CompletionTokensDetails != nil {
+		thoughts = resp.Usage.CompletionTokensDetails.ReasoningTokens
+	}
+	usage := &database.LLMUsage{
+		Provider:         "openai",
+		LLMModel:         o.model,
+		PromptTokens:     int64(resp.Usage.PromptTokens),
+		CompletionTokens: int64(resp.Usage.CompletionTokens),
+		ReasoningTokens:  int64(thoughts),
+		TotalTokens:      int64(resp.Usage.TotalTokens),
+		LatencyMs:        latency.Milliseconds(),
 	}
 
 	// Logging Usage
 	if resp.Usage.TotalTokens > 0 {
-		// OpenAI puts "Reasoning/Thought" tokens in CompletionTokensDetails
-		// Note: Not all local models return this detail, but GPT-o1/o3 do.
-		var thoughts int
-		if resp.Usage.CompletionTokensDetails != nil {
-			thoughts = resp.Usage.CompletionTokensDetails.ReasoningTokens
-		}
-
 		log.Debugf(o.ctx, "openai token usage prompt_tokens=%d completion_tokens=%d thoughts_tokens=%d total_tokens=%d",
 			resp.Usage.PromptTokens,
 			resp.Usage.CompletionTokens,
@@ -174,19 +219,12 @@ func (o *openaiProvider) Run(prompt string, language string, request Request, ig
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned from openai provider")
+		return "", usage, fmt.Errorf("no choices returned from openai provider")
 	}
-
-	var result database.ThinkResult
-	// OpenAI returns the result in Message.Content
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+	if choice := resp.Choices[0]; choice.Message.Refusal != "" || choice.FinishReason == openai.FinishReasonContentFilter {
+		return "", usage, newError(ErrorRefused, fmt.Errorf("openai refused the request: %s %s", choice.FinishReason, choice.Message.Refusal))
 	}
-	result.LLMModel = o.model
 
-	if err := validateAndNormalizeThinkResult(language, &result, ignoreCategoryErrors); err != nil {
-		return nil, err
-	}
-
-	return &result, nil
+	// OpenAI returns the result in Message.Content
+	return resp.Choices[0].Message.Content, usage, nil
 }
@@ -0,0 +1,217 @@
+package think
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	categorypkg "github.com/deframer/news-deframer/pkg/category"
+	"goa.design/clue/log"
+)
+
+// promptWatchInterval is how often PROMPT_DIR is checked for changed files.
+const promptWatchInterval = 5 * time.Second
+
+const promptFilePattern = "*-prompt-*.md"
+
+// promptStore holds the parsed prompts keyed by "<name>:<language>". The
+// embedded prompts are always the base; files in dir overlay them.
+type promptStore struct {
+	mu          sync.RWMutex
+	dir         string
+	prompts     map[string]*prompt
+	fingerprint string
+}
+
+var prompts = newPromptStore()
+
+func newPromptStore() *promptStore {
+	s := &promptStore{}
+	if err := s.load(""); err != nil {
+		// the embedded prompts are part of the binary; this is a build error
+		panic(err)
+	}
+	return s
+}
+
+// ConfigurePrompts overlays the embedded prompts with the ones in dir.
+// The combined set must cover every language with localized categories.
+func ConfigurePrompts(dir string) error {
+	return prompts.load(dir)
+}
+
+// ReloadPrompts re-reads the configured prompt directory. On error the
+// previously loaded prompts stay active.
+func ReloadPrompts() error {
+	prompts.mu.RLock()
+	dir := prompts.dir
+	prompts.mu.RUnlock()
+	return prompts.load(dir)
+}
+
+// WatchPrompts reloads the prompts whenever a file in the configured
+// directory changes, until ctx is done. The prompts are shared by all
+// thinkers, so a process runs a single watcher.
+func WatchPrompts(ctx context.Context) {
+	ticker := time.NewTicker(promptWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		prompts.mu.RLock()
+		dir, current := prompts.dir, prompts.fingerprint
+		prompts.mu.RUnlock()
+		if dir == "" {
+			continue
+		}
+
+		fingerprint, err := promptDirFingerprint(dir)
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to check prompt dir dir=%s", dir)
+			continue
+		}
+		if fingerprint == current {
+			continue
+		}
+
+		if err := ReloadPrompts(); err != nil {
+			log.Errorf(ctx, err, "Failed to reload prompts, keeping previous prompts dir=%s", dir)
+			continue
+		}
+		log.Printf(ctx, "Reloaded prompts dir=%s", dir)
+	}
+}
+
+func (s *promptStore) load(dir string) error {
+	loaded := make(map[string]*prompt)
+
+	embedded, err := fs.Sub(promptFS, "prompts")
+	if err != nil {
+		return err
+	}
+	if err := readPromptFiles(embedded, loaded); err != nil {
+		return err
+	}
+
+	fingerprint := ""
+	if dir != "" {
+		if fingerprint, err = promptDirFingerprint(dir); err != nil {
+			return err
+		}
+		if err := readPromptFiles(os.DirFS(dir), loaded); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePrompts(loaded); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.dir = dir
+	s.prompts = loaded
+	s.fingerprint = fingerprint
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *promptStore) get(name, lang string) (*prompt, error) {
+	s.mu.RLock()
+	p, ok := s.prompts[name+":"+lang]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("prompt not found: %s-prompt-%s.md", name, lang)
+	}
+	return p, nil
+}
+
+func (s *promptStore) versions(name string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := make(map[string]string)
+	for key, p := range s.prompts {
+		promptName, lang, _ := strings.Cut(key, ":")
+		if promptName == name {
+			versions[lang] = p.version
+		}
+	}
+	return versions
+}
+
+func readPromptFiles(fsys fs.FS, into map[string]*prompt) error {
+	files, err := fs.Glob(fsys, promptFilePattern)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name, lang, ok := strings.Cut(strings.TrimSuffix(file, ".md"), "-prompt-")
+		if !ok || name == "" || lang == "" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		into[name+":"+lang] = parsePrompt(content)
+	}
+	return nil
+}
+
+// validatePrompts makes sure every prompt name exists for all category
+// languages, otherwise the thinker would fail for whole feeds at runtime.
+func validatePrompts(loaded map[string]*prompt) error {
+	names := make(map[string]struct{})
+	for key := range loaded {
+		name, _, _ := strings.Cut(key, ":")
+		names[name] = struct{}{}
+	}
+
+	var missing []string
+	for name := range names {
+		for _, lang := range categorypkg.Languages() {
+			if _, ok := loaded[name+":"+lang]; !ok {
+				missing = append(missing, fmt.Sprintf("%s-prompt-%s.md", name, lang))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing prompts: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func promptDirFingerprint(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if ok, _ := path.Match(promptFilePattern, entry.Name()); !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
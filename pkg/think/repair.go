@@ -0,0 +1,139 @@
+package think
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/deframer/news-deframer/pkg/database"
+)
+
+// Repairs recorded in ThinkResult.Repairs.
+const (
+	RepairExtractedJSON   = "extracted_json"
+	RepairClampedScores   = "clamped_scores"
+	RepairRescaledTen     = "rescaled_scores_0_10"
+	RepairRescaledPercent = "rescaled_scores_percent"
+	RepairFollowUp        = "follow_up"
+)
+
+// scores slightly outside 0..1 are rounding noise and get clamped
+const clampTolerance = 0.1
+
+// parseThinkResult decodes and validates the answer of a provider, repairing
+// what can be repaired without asking the LLM again.
+func parseThinkResult(language string, request Request, content string, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	result, err := decodeThinkResult(content)
+	if err != nil {
+		return nil, err
+	}
+	if repair := repairScores(result); repair != "" {
+		result.Repairs = append(result.Repairs, repair)
+	}
+	if err := validateAndNormalizeThinkResult(language, request, result, ignoreCategoryErrors); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodeThinkResult unmarshals the answer. Models without structured output
+// like to wrap the JSON in a markdown fence or a sentence; the outermost
+// object is tried before giving up.
+func decodeThinkResult(content string) (*database.ThinkResult, error) {
+	var result database.ThinkResult
+	err := json.Unmarshal([]byte(content), &result)
+	if err == nil {
+		return &result, nil
+	}
+	if extracted, ok := extractJSONObject(content); ok && extracted != content {
+		result = database.ThinkResult{}
+		if json.Unmarshal([]byte(extracted), &result) == nil {
+			result.Repairs = append(result.Repairs, RepairExtractedJSON)
+			return &result, nil
+		}
+	}
+	return nil, newError(ErrorInvalidJSON, fmt.Errorf("failed to unmarshal result: %w", err))
+}
+
+func extractJSONObject(content string) (string, bool) {
+	if _, fenced, ok := strings.Cut(content, "```"); ok {
+		fenced, _, _ = strings.Cut(fenced, "```")
+		// drop the info string of the fence, e.g. ```json
+		if tag, rest, ok := strings.Cut(fenced, "\n"); ok && !strings.Contains(tag, "{") {
+			fenced = rest
+		}
+		content = fenced
+	}
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return "", false
+	}
+	return content[start : end+1], true
+}
+
+// repairScores brings mis-scaled scores back into 0..1 and returns the
+// repair it applied, or "" when the scores were fine or beyond repair. Only
+// answers clearly on another scale, all whole numbers or more than one score
+// above 1, are read as a 0-10 or a percentage scale; all scores of the answer
+// are scaled alike so their relation is kept. A single stray score of up to
+// 10 in an otherwise fractional answer is clamped instead.
+func repairScores(res *database.ThinkResult) string {
+	scores := []*float64{&res.Framing, &res.Clickbait, &res.Persuasive, &res.HyperStimulus, &res.Speculative, &res.Overall}
+	values := make([]float64, len(scores))
+	whole, aboveOne := true, 0
+	for i, score := range scores {
+		values[i] = *score
+		if *score != math.Trunc(*score) {
+			whole = false
+		}
+		if *score > 1.0 {
+			aboveOne++
+		}
+	}
+	low, high := slices.Min(values), slices.Max(values)
+	if low >= 0.0 && high <= 1.0 {
+		return ""
+	}
+	if low < -clampTolerance {
+		return ""
+	}
+
+	var divisor float64
+	repair := RepairClampedScores
+	switch {
+	case high <= 1.0+clampTolerance:
+		divisor = 1
+	case !whole && aboveOne == 1:
+		if high > 10 {
+			return ""
+		}
+		divisor = 1
+	case high <= 10:
+		divisor, repair = 10, RepairRescaledTen
+	case high <= 100:
+		divisor, repair = 100, RepairRescaledPercent
+	default:
+		return ""
+	}
+	for _, score := range scores {
+		*score = min(max(*score/divisor, 0.0), 1.0)
+	}
+	return repair
+}
+
+// repairable reports whether asking the LLM to fix its answer can help.
+func repairable(err error) bool {
+	switch KindOf(err) {
+	case ErrorInvalidJSON, ErrorSchemaViolation, ErrorOutOfBounds:
+		return true
+	}
+	return false
+}
+
+func followUpPrompt(err error) string {
+	return fmt.Sprintf("Your answer could not be used: %v. Reply again with only the corrected JSON object, "+
+		"all scores between 0.0 and 1.0 and the category from the allowed list.", err)
+}
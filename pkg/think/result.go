@@ -2,12 +2,21 @@ package think
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	categorypkg "github.com/deframer/news-deframer/pkg/category"
 	"github.com/deframer/news-deframer/pkg/database"
 )
 
-func validateAndNormalizeThinkResult(language string, res *database.ThinkResult, ignoreCategoryErrors bool) error {
+// EvidenceDimensions are the scores the LLM may back with evidence spans.
+var EvidenceDimensions = []string{"framing", "clickbait", "persuasive", "hyper_stimulus", "speculative"}
+
+// EvidenceFields are the parts of the request an evidence span can quote.
+var EvidenceFields = []string{"title", "description"}
+
+func validateAndNormalizeThinkResult(language string, request Request, res *database.ThinkResult, ignoreCategoryErrors bool) error {
 	const errFmt = "ThinkResult is out of bounds 0.0 - 1.0: %s is %.1f"
 
 	if res == nil {
@@ -15,33 +24,86 @@ func validateAndNormalizeThinkResult(language string, res *database.ThinkResult,
 	}
 
 	if res.Framing < 0.0 || res.Framing > 1.0 {
-		return fmt.Errorf(errFmt, "Framing", res.Framing)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "Framing", res.Framing))
 	}
 	if res.Clickbait < 0.0 || res.Clickbait > 1.0 {
-		return fmt.Errorf(errFmt, "Clickbait", res.Clickbait)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "Clickbait", res.Clickbait))
 	}
 	if res.Persuasive < 0.0 || res.Persuasive > 1.0 {
-		return fmt.Errorf(errFmt, "Persuasive", res.Persuasive)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "Persuasive", res.Persuasive))
 	}
 	if res.HyperStimulus < 0.0 || res.HyperStimulus > 1.0 {
-		return fmt.Errorf(errFmt, "HyperStimulus", res.HyperStimulus)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "HyperStimulus", res.HyperStimulus))
 	}
 	if res.Speculative < 0.0 || res.Speculative > 1.0 {
-		return fmt.Errorf(errFmt, "Speculative", res.Speculative)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "Speculative", res.Speculative))
 	}
 	if res.Overall < 0.0 || res.Overall > 1.0 {
-		return fmt.Errorf(errFmt, "Overall", res.Overall)
+		return newError(ErrorOutOfBounds, fmt.Errorf(errFmt, "Overall", res.Overall))
 	}
 
 	category, err := normalizeThinkResultCategory(language, res.Category, ignoreCategoryErrors)
 	if err != nil {
-		return err
+		return newError(ErrorSchemaViolation, err)
 	}
 	res.Category = category
+	res.Evidence = resolveEvidence(request, res.Evidence)
 
 	return nil
 }
 
+// resolveEvidence locates the quotes of the LLM in the request and fills in
+// their offsets. Models paraphrase now and then; spans that do not quote the
+// text are dropped instead of failing the whole analysis.
+func resolveEvidence(request Request, spans []database.EvidenceSpan) []database.EvidenceSpan {
+	var resolved []database.EvidenceSpan
+	for _, span := range spans {
+		if !slices.Contains(EvidenceDimensions, span.Dimension) {
+			continue
+		}
+		text := request.Title
+		if span.Field == "description" {
+			text = request.Description
+		} else if span.Field != "title" {
+			continue
+		}
+
+		start, end, ok := findQuote(text, strings.TrimSpace(span.Quote))
+		if !ok {
+			continue
+		}
+		span.Quote = string([]rune(text)[start:end])
+		span.Start, span.End = start, end
+		if slices.ContainsFunc(resolved, func(other database.EvidenceSpan) bool {
+			return other.Dimension == span.Dimension && other.Field == span.Field && other.Start == span.Start && other.End == span.End
+		}) {
+			continue
+		}
+		resolved = append(resolved, span)
+	}
+	return resolved
+}
+
+// findQuote returns the rune offsets of the first occurrence of quote in
+// text, preferring an exact match over a case-insensitive one.
+func findQuote(text, quote string) (int, int, bool) {
+	if quote == "" {
+		return 0, 0, false
+	}
+	if i := strings.Index(text, quote); i >= 0 {
+		start := utf8.RuneCountInString(text[:i])
+		return start, start + utf8.RuneCountInString(quote), true
+	}
+
+	runes, needle := []rune(text), []rune(quote)
+	for start := 0; start+len(needle) <= len(runes); start++ {
+		if strings.EqualFold(string(runes[start:start+len(needle)]), quote) {
+			return start, start + len(needle), true
+		}
+	}
+	return 0, 0, false
+}
+
 func normalizeThinkResultCategory(language, category string, ignoreCategoryErrors bool) (string, error) {
 	normalizedCategory, err := categorypkg.NormalizeCategory(language, category)
 	if err != nil {
@@ -2,8 +2,11 @@ package think
 
 import (
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
@@ -17,6 +20,31 @@ type Think interface {
 }
 
 func New(ctx context.Context, cfg *config.Config) (Think, error) {
+	if cfg.PromptDir != "" {
+		if err := ConfigurePrompts(cfg.PromptDir); err != nil {
+			return nil, fmt.Errorf("prompt dir %s: %w", cfg.PromptDir, err)
+		}
+	}
+
+	if len(cfg.LLM_EnsembleModels) == 0 {
+		return newProvider(ctx, cfg, cfg.LLM_Model)
+	}
+
+	members := make([]ensembleMember, 0, len(cfg.LLM_EnsembleModels))
+	for _, model := range cfg.LLM_EnsembleModels {
+		t, err := newProvider(ctx, cfg, model)
+		if err != nil {
+			return nil, fmt.Errorf("ensemble model %s: %w", model, err)
+		}
+		members = append(members, ensembleMember{model: model, think: t})
+	}
+	if len(members) < 2 {
+		return nil, fmt.Errorf("ensemble needs at least two models, got %d", len(members))
+	}
+	return newEnsemble(cfg.ThinkModel(), cfg.LLM_EnsembleAggregation, members), nil
+}
+
+func newProvider(ctx context.Context, cfg *config.Config, model string) (Think, error) {
 	t := cfg.LLM_Type
 	switch t {
 	case config.Dummy:
@@ -24,21 +52,41 @@ func New(ctx context.Context, cfg *config.Config) (Think, error) {
 	case config.Fail:
 		return newFail(), nil
 	case config.Gemini:
-		return newGemini(ctx, cfg.LLM_Model, cfg.LLM_APIKey)
+		return newGemini(ctx, model, cfg.LLM_APIKey, cfg.LLM_RepairFollowUp)
 	case config.OpenAI:
-		return newOpenAI(ctx, cfg.LLM_Model, cfg.LLM_APIKey, cfg.LLM_BaseURL)
+		return newOpenAI(ctx, model, cfg.LLM_APIKey, cfg.LLM_BaseURL, cfg.LLM_RepairFollowUp)
 	default:
 		return nil, fmt.Errorf("unknown think type: %v", t)
 	}
 }
 
-func getPrompt(name, lang string) (string, error) {
-	filename := fmt.Sprintf("prompts/%s-prompt-%s.md", name, lang)
-	content, err := promptFS.ReadFile(filename)
-	if err != nil {
-		return "", err
+// promptVersionHeader lets a prompt pin its version explicitly, e.g. a first
+// line of "<!-- version: 2026-03-01 -->". The header is not sent to the LLM.
+var promptVersionHeader = regexp.MustCompile(`^<!--\s*version:\s*(\S+)\s*-->[ \t]*\r?\n?`)
+
+// prompt is a system prompt together with the version of its content.
+type prompt struct {
+	text    string
+	version string
+}
+
+func getPrompt(name, lang string) (*prompt, error) {
+	return prompts.get(name, lang)
+}
+
+// parsePrompt uses the explicit version header when present and otherwise
+// falls back to a short content hash, so any edit yields a new version.
+func parsePrompt(content []byte) *prompt {
+	if m := promptVersionHeader.FindSubmatch(content); m != nil {
+		return &prompt{text: string(content[len(m[0]):]), version: string(m[1])}
 	}
-	return string(content), nil
+	sum := sha256.Sum256(content)
+	return &prompt{text: string(content), version: hex.EncodeToString(sum[:])[:12]}
+}
+
+// PromptVersions returns the current version of the named prompt per language.
+func PromptVersions(name string) map[string]string {
+	return prompts.versions(name)
 }
 
 type Request struct {
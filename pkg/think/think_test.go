@@ -2,23 +2,50 @@ package think
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	categorypkg "github.com/deframer/news-deframer/pkg/category"
 	"github.com/deframer/news-deframer/pkg/config"
 	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
 )
 
 func TestGetPrompt(t *testing.T) {
 	p, err := getPrompt("deframer", "en")
 	assert.NoError(t, err)
-	assert.Contains(t, p, "System Prompt")
+	assert.Contains(t, p.text, "System Prompt")
+	assert.NotEmpty(t, p.version)
 
 	_, err = getPrompt("foo", "bar")
 	assert.Error(t, err)
 }
 
+// A language is only usable when its prompt offers exactly the categories
+// the result is validated against.
+func TestPromptsListLocalizedCategories(t *testing.T) {
+	for _, lang := range categorypkg.Languages() {
+		p, err := getPrompt("deframer", lang)
+		if !assert.NoError(t, err, lang) {
+			continue
+		}
+		categories, err := categorypkg.LocalizedCategoriesFor(lang)
+		assert.NoError(t, err, lang)
+		for _, category := range categories {
+			assert.Contains(t, p.text, "`"+category+"`", "deframer-prompt-%s.md", lang)
+		}
+	}
+}
+
 func TestLocalizedCategoryValidation(t *testing.T) {
 	err := categorypkg.ValidateLocalizedCategory("en", "business")
 	assert.NoError(t, err)
@@ -154,18 +181,18 @@ func TestNew_OpenAI(t *testing.T) {
 }
 
 func TestValidateAndNormalizeThinkResult(t *testing.T) {
-	err := validateAndNormalizeThinkResult("en", nil, false)
+	err := validateAndNormalizeThinkResult("en", Request{}, nil, false)
 	assert.NoError(t, err)
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Category: "other"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Category: "other"}, false)
 	assert.NoError(t, err)
 
 	res := &database.ThinkResult{Category: "meinung"}
-	err = validateAndNormalizeThinkResult("de", res, false)
+	err = validateAndNormalizeThinkResult("de", Request{}, res, false)
 	assert.NoError(t, err)
 	assert.Equal(t, "opinion", res.Category)
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{
 		Framing:       0.5,
 		Clickbait:     0.5,
 		Persuasive:    0.5,
@@ -176,44 +203,515 @@ func TestValidateAndNormalizeThinkResult(t *testing.T) {
 	}, false)
 	assert.NoError(t, err)
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Framing: -0.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Framing: -0.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Framing")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Framing: 1.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Framing: 1.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Framing")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Clickbait: -0.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Clickbait: -0.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Clickbait")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Persuasive: 1.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Persuasive: 1.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Persuasive")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{HyperStimulus: -0.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{HyperStimulus: -0.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "HyperStimulus")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Speculative: 1.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Speculative: 1.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Speculative")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Overall: -0.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Overall: -0.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Overall")
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Overall: 1.1, Category: "business"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Overall: 1.1, Category: "business"}, false)
 	assert.ErrorContains(t, err, "Overall")
+	assert.Equal(t, ErrorOutOfBounds, KindOf(err))
 
-	err = validateAndNormalizeThinkResult("en", &database.ThinkResult{Category: "wirtschaft"}, false)
+	err = validateAndNormalizeThinkResult("en", Request{}, &database.ThinkResult{Category: "wirtschaft"}, false)
 	assert.ErrorContains(t, err, "invalid category")
+	assert.Equal(t, ErrorSchemaViolation, KindOf(err))
 
-	err = validateAndNormalizeThinkResult("fr", &database.ThinkResult{Category: "opinion"}, false)
+	err = validateAndNormalizeThinkResult("fr", Request{}, &database.ThinkResult{Category: "opinion"}, false)
 	assert.NoError(t, err)
 
-	err = validateAndNormalizeThinkResult("es", &database.ThinkResult{Category: "negocios"}, false)
+	err = validateAndNormalizeThinkResult("es", Request{}, &database.ThinkResult{Category: "negocios"}, false)
 	assert.NoError(t, err)
 
-	err = validateAndNormalizeThinkResult("nl", &database.ThinkResult{Category: "bedrijfsleven"}, false)
+	err = validateAndNormalizeThinkResult("nl", Request{}, &database.ThinkResult{Category: "bedrijfsleven"}, false)
 	assert.NoError(t, err)
 
 	res = &database.ThinkResult{Category: "wirtschaft"}
-	err = validateAndNormalizeThinkResult("en", res, true)
+	err = validateAndNormalizeThinkResult("en", Request{}, res, true)
 	assert.NoError(t, err)
 	assert.Equal(t, "other", res.Category)
 }
+
+func TestParseThinkResult(t *testing.T) {
+	res, err := parseThinkResult("en", Request{}, `{"framing": 0.2, "overall": 0.4, "category": "business"}`, false)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Repairs)
+
+	res, err = parseThinkResult("en", Request{}, "Sure! Here is the analysis:\n```json\n{\"framing\": 0.2, \"category\": \"business\"}\n```\nLet me know.", false)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.2, res.Framing, 1e-9)
+	assert.Equal(t, []string{RepairExtractedJSON}, res.Repairs)
+
+	res, err = parseThinkResult("en", Request{}, `{"framing": 7, "clickbait": 10, "overall": 5.5, "category": "business"}`, false)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.7, res.Framing, 1e-9)
+	assert.InDelta(t, 1.0, res.Clickbait, 1e-9)
+	assert.InDelta(t, 0.55, res.Overall, 1e-9)
+	assert.Equal(t, []string{RepairRescaledTen}, res.Repairs)
+
+	res, err = parseThinkResult("en", Request{}, `{"framing": 40, "overall": 75, "category": "business"}`, false)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.4, res.Framing, 1e-9)
+	assert.Equal(t, []string{RepairRescaledPercent}, res.Repairs)
+
+	res, err = parseThinkResult("en", Request{}, `{"framing": 1.05, "speculative": -0.02, "category": "business"}`, false)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, res.Framing, 1e-9)
+	assert.InDelta(t, 0.0, res.Speculative, 1e-9)
+	assert.Equal(t, []string{RepairClampedScores}, res.Repairs)
+
+	_, err = parseThinkResult("en", Request{}, `{"framing": -3, "category": "business"}`, false)
+	assert.Equal(t, ErrorOutOfBounds, KindOf(err))
+
+	_, err = parseThinkResult("en", Request{}, `{"framing": 250, "category": "business"}`, false)
+	assert.Equal(t, ErrorOutOfBounds, KindOf(err))
+
+	_, err = parseThinkResult("en", Request{}, "I cannot answer that.", false)
+	assert.Equal(t, ErrorInvalidJSON, KindOf(err))
+}
+
+func TestRepairScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing float64
+		overall float64
+		want    [2]float64
+		repair  string
+	}{
+		{name: "in range", framing: 0.9, overall: 0.5, want: [2]float64{0.9, 0.5}},
+		{name: "rounding noise", framing: 1.05, overall: 0.5, want: [2]float64{1.0, 0.5}, repair: RepairClampedScores},
+		{name: "single stray score", framing: 0.9, overall: 1.5, want: [2]float64{0.9, 1.0}, repair: RepairClampedScores},
+		{name: "whole numbers", framing: 0, overall: 7, want: [2]float64{0.0, 0.7}, repair: RepairRescaledTen},
+		{name: "two scores above one", framing: 2.5, overall: 5.5, want: [2]float64{0.25, 0.55}, repair: RepairRescaledTen},
+		{name: "percent", framing: 40, overall: 75, want: [2]float64{0.4, 0.75}, repair: RepairRescaledPercent},
+		{name: "stray score beyond ten", framing: 0.9, overall: 12.5, want: [2]float64{0.9, 12.5}},
+		{name: "negative", framing: -3, overall: 0.5, want: [2]float64{-3, 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &database.ThinkResult{Framing: tt.framing, Overall: tt.overall}
+			assert.Equal(t, tt.repair, repairScores(res))
+			assert.InDelta(t, tt.want[0], res.Framing, 1e-9)
+			assert.InDelta(t, tt.want[1], res.Overall, 1e-9)
+		})
+	}
+}
+
+func TestOpenAI_FollowUp(t *testing.T) {
+	answers := []string{
+		`{"framing": 0.2, "category": "gossip"}`,
+		`{"framing": 0.2, "overall": 0.3, "category": "business"}`,
+	}
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: answers[len(requests)-1]}}},
+			Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		}))
+	}))
+	defer server.Close()
+
+	o, err := newOpenAI(context.Background(), "m", "key", server.URL, false)
+	assert.NoError(t, err)
+	_, err = o.Run("deframer", "en", Request{Title: "t"}, false)
+	assert.Equal(t, ErrorSchemaViolation, KindOf(err))
+	assert.Len(t, UsageOf(nil, err), 1)
+
+	requests = nil
+	o.followUp = true
+	res, err := o.Run("deframer", "en", Request{Title: "t"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "business", res.Category)
+	assert.Equal(t, []string{RepairFollowUp}, res.Repairs)
+	assert.Len(t, res.Usage, 2)
+	if assert.Len(t, requests, 2) {
+		messages := requests[1].Messages
+		assert.Len(t, messages, 4)
+		assert.Equal(t, openai.ChatMessageRoleAssistant, messages[2].Role)
+		assert.Contains(t, messages[3].Content, "invalid category")
+	}
+}
+
+func TestResolveEvidence(t *testing.T) {
+	request := Request{
+		Title:       "SHOCKING: Straße gesperrt – you won't believe why",
+		Description: "The city could close more roads, insiders say.",
+	}
+	res := &database.ThinkResult{
+		Category: "other",
+		Evidence: []database.EvidenceSpan{
+			{Dimension: "hyper_stimulus", Field: "title", Quote: "shocking"},
+			{Dimension: "clickbait", Field: "title", Quote: " you won't believe why "},
+			{Dimension: "clickbait", Field: "title", Quote: "you won't believe why"}, // duplicate
+			{Dimension: "speculative", Field: "description", Quote: "could close more roads"},
+			{Dimension: "speculative", Field: "description", Quote: "might close roads"}, // paraphrased
+			{Dimension: "overall", Field: "title", Quote: "SHOCKING"},                    // no evidence for overall
+			{Dimension: "framing", Field: "body", Quote: "SHOCKING"},                     // unknown field
+			{Dimension: "framing", Field: "title", Quote: ""},
+		},
+	}
+
+	err := validateAndNormalizeThinkResult("en", request, res, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []database.EvidenceSpan{
+		{Dimension: "hyper_stimulus", Field: "title", Quote: "SHOCKING", Start: 0, End: 8},
+		{Dimension: "clickbait", Field: "title", Quote: "you won't believe why", Start: 28, End: 49},
+		{Dimension: "speculative", Field: "description", Quote: "could close more roads", Start: 9, End: 31},
+	}, res.Evidence)
+
+	// offsets count characters, not bytes
+	runes := []rune(request.Title)
+	assert.Equal(t, "you won't believe why", string(runes[28:49]))
+}
+
+type stubThink struct {
+	res *database.ThinkResult
+	err error
+}
+
+func (s *stubThink) Run(prompt string, language string, request Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	res := *s.res
+	return &res, nil
+}
+
+func TestEnsemble_Run(t *testing.T) {
+	members := []ensembleMember{
+		{model: "a", think: &stubThink{res: &database.ThinkResult{Framing: 0.1, Overall: 0.2, OverallReason: "a", Category: "business"}}},
+		{model: "b", think: &stubThink{res: &database.ThinkResult{Framing: 0.3, Overall: 0.4, OverallReason: "b", Category: "politics"}}},
+		{model: "c", think: &stubThink{res: &database.ThinkResult{Framing: 0.9, Overall: 0.9, OverallReason: "c", Category: "business"}}},
+	}
+
+	t.Run("median", func(t *testing.T) {
+		e := newEnsemble("ensemble:median:a+b+c", config.Median, members)
+		res, err := e.Run("deframer", "en", Request{}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "ensemble:median:a+b+c", res.LLMModel)
+		assert.InDelta(t, 0.3, res.Framing, 1e-9)
+		assert.InDelta(t, 0.4, res.Overall, 1e-9)
+		assert.Equal(t, "b", res.OverallReason)
+		assert.Equal(t, "business", res.Category)
+		assert.Equal(t, []string{"a", "b", "c"}, res.EnsembleModels)
+		assert.InDelta(t, 0.2943920, res.Disagreement, 1e-6)
+	})
+
+	t.Run("mean", func(t *testing.T) {
+		e := newEnsemble("ensemble:mean:a+b+c", config.Mean, members)
+		res, err := e.Run("deframer", "en", Request{}, false)
+		assert.NoError(t, err)
+		assert.InDelta(t, 0.5, res.Overall, 1e-9)
+		assert.InDelta(t, 13.0/30.0, res.Framing, 1e-9)
+		assert.Equal(t, "b", res.OverallReason)
+	})
+
+	t.Run("quorum", func(t *testing.T) {
+		failing := []ensembleMember{
+			members[0],
+			{model: "x", think: newFail()},
+			{model: "y", think: newFail()},
+		}
+		_, err := newEnsemble("e", config.Median, failing).Run("deframer", "en", Request{}, false)
+		assert.ErrorContains(t, err, "quorum not reached (1/3)")
+
+		failing[2] = members[1]
+		res, err := newEnsemble("e", config.Median, failing).Run("deframer", "en", Request{}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, res.EnsembleModels)
+		assert.InDelta(t, 0.3, res.Overall, 1e-9)
+	})
+
+	t.Run("error kind", func(t *testing.T) {
+		limited := []ensembleMember{
+			{model: "x", think: &stubThink{err: newError(ErrorRefused, errors.New("refused"))}},
+			{model: "y", think: &stubThink{err: newError(ErrorRateLimited, errors.New("slow down"))}},
+		}
+		_, err := newEnsemble("e", config.Median, limited).Run("deframer", "en", Request{}, false)
+		assert.Equal(t, ErrorRateLimited, KindOf(err))
+	})
+}
+
+func TestEnsembleErrorKind(t *testing.T) {
+	refused := newError(ErrorRefused, errors.New("refused"))
+	transient := newError(ErrorTransient, errors.New("timeout"))
+	invalid := newError(ErrorInvalidJSON, errors.New("bad json"))
+
+	assert.Equal(t, ErrorRefused, ensembleErrorKind([]error{refused, nil, refused}))
+	assert.Equal(t, ErrorTransient, ensembleErrorKind([]error{refused, invalid, transient}))
+	assert.Equal(t, ErrorInvalidJSON, ensembleErrorKind([]error{invalid, refused}))
+	assert.Equal(t, ErrorUnknown, ensembleErrorKind([]error{errors.New("plain")}))
+	assert.Equal(t, ErrorUnknown, ensembleErrorKind(nil))
+}
+
+func TestClassifyRequestError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"openai 429", &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "rate limit"}, ErrorRateLimited},
+		{"openai 503", &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable, Message: "overloaded"}, ErrorTransient},
+		{"openai 401", &openai.APIError{HTTPStatusCode: http.StatusUnauthorized, Message: "bad key"}, ErrorUnknown},
+		{"openai request 408", &openai.RequestError{HTTPStatusCode: http.StatusRequestTimeout, Err: errors.New("timeout")}, ErrorTransient},
+		{"gemini 429", fmt.Errorf("generate: %w", genai.APIError{Code: http.StatusTooManyRequests, Message: "quota"}), ErrorRateLimited},
+		{"gemini 500", genai.APIError{Code: http.StatusInternalServerError, Message: "internal"}, ErrorTransient},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ErrorTransient},
+		{"deadline", context.DeadlineExceeded, ErrorTransient},
+		{"other", errors.New("boom"), ErrorUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyRequestError(tt.err)
+			assert.Equal(t, tt.want, KindOf(err))
+			assert.Equal(t, tt.err, errors.Unwrap(err))
+		})
+	}
+
+	assert.Equal(t, context.Canceled, classifyRequestError(context.Canceled))
+	assert.Equal(t, ErrorKind(""), KindOf(nil))
+}
+
+func TestUsageOf(t *testing.T) {
+	usage := database.LLMUsage{LLMModel: "a", PromptTokens: 10, CompletionTokens: 2}
+
+	assert.Equal(t, []database.LLMUsage{usage}, UsageOf(&database.ThinkResult{Usage: []database.LLMUsage{usage}}, nil))
+
+	err := fmt.Errorf("wrapped: %w", withUsage(errors.New("bad json"), usage))
+	assert.Equal(t, []database.LLMUsage{usage}, UsageOf(nil, err))
+	assert.EqualError(t, err, "wrapped: bad json")
+	assert.Nil(t, UsageOf(nil, errors.New("no call made")))
+	assert.Nil(t, withUsage(nil))
+
+	t.Run("ensemble", func(t *testing.T) {
+		billedFailure := &stubThink{err: withUsage(errors.New("bad json"), database.LLMUsage{LLMModel: "y", PromptTokens: 5})}
+		members := []ensembleMember{
+			{model: "x", think: &stubThink{res: &database.ThinkResult{Overall: 0.5, Category: "business", Usage: []database.LLMUsage{{LLMModel: "x", PromptTokens: 7}}}}},
+			{model: "y", think: billedFailure},
+			{model: "z", think: newFail()},
+		}
+		_, err := newEnsemble("e", config.Median, members).Run("deframer", "en", Request{}, false)
+		assert.Error(t, err)
+		assert.ElementsMatch(t, []string{"x", "y"}, usageModels(UsageOf(nil, err)))
+
+		members[2] = ensembleMember{model: "z", think: &stubThink{res: &database.ThinkResult{Overall: 0.7, Category: "business", Usage: []database.LLMUsage{{LLMModel: "z", PromptTokens: 3}}}}}
+		res, err := newEnsemble("e", config.Median, members).Run("deframer", "en", Request{}, false)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"x", "y", "z"}, usageModels(UsageOf(res, err)))
+	})
+}
+
+func usageModels(usage []database.LLMUsage) []string {
+	models := make([]string, 0, len(usage))
+	for _, u := range usage {
+		models = append(models, u.LLMModel)
+	}
+	return models
+}
+
+func TestNew_Ensemble(t *testing.T) {
+	cfg := &config.Config{LLM_Type: config.Dummy, LLM_EnsembleModels: []string{"m1", "m2"}}
+	th, err := New(context.Background(), cfg)
+	assert.NoError(t, err)
+
+	res, err := th.Run("deframer", "en", Request{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "ensemble:median:m1+m2", res.LLMModel)
+	assert.Equal(t, cfg.ThinkModel(), res.LLMModel)
+
+	_, err = New(context.Background(), &config.Config{LLM_Type: config.Dummy, LLM_EnsembleModels: []string{"m1"}})
+	assert.Error(t, err)
+}
+
+func TestPromptVersion(t *testing.T) {
+	p := parsePrompt([]byte("<!-- version: 2026-03-01 -->\n**System Prompt:**"))
+	assert.Equal(t, "2026-03-01", p.version)
+	assert.Equal(t, "**System Prompt:**", p.text)
+
+	p = parsePrompt([]byte("**System Prompt:**"))
+	assert.Len(t, p.version, 12)
+	assert.Equal(t, "**System Prompt:**", p.text)
+	assert.NotEqual(t, p.version, parsePrompt([]byte("**System Prompt:** edited")).version)
+
+	versions := PromptVersions("deframer")
+	for _, lang := range []string{"da", "de", "en", "es", "fr", "nl"} {
+		assert.NotEmpty(t, versions[lang], lang)
+	}
+
+	res, err := newDummy().Run("deframer", "en", Request{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, versions["en"], res.PromptVersion)
+}
+
+func TestConfigurePrompts(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NoError(t, ConfigurePrompts(""))
+	})
+
+	embedded, err := getPrompt("deframer", "en")
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deframer-prompt-en.md")
+	assert.NoError(t, os.WriteFile(path, []byte("<!-- version: custom-1 -->\ncustom prompt"), 0o600))
+
+	assert.NoError(t, ConfigurePrompts(dir))
+	p, err := getPrompt("deframer", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom prompt", p.text)
+	assert.Equal(t, "custom-1", PromptVersions("deframer")["en"])
+
+	// languages without an override keep the embedded prompt
+	de, err := getPrompt("deframer", "de")
+	assert.NoError(t, err)
+	assert.Contains(t, de.text, "System Prompt")
+
+	assert.NoError(t, os.WriteFile(path, []byte("<!-- version: custom-2 -->\nedited prompt"), 0o600))
+	assert.NoError(t, ReloadPrompts())
+	p, err = getPrompt("deframer", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-2", p.version)
+
+	// a new prompt name must exist for every language; the previous set stays active
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other-prompt-en.md"), []byte("other"), 0o600))
+	err = ReloadPrompts()
+	assert.ErrorContains(t, err, "other-prompt-de.md")
+	p, err = getPrompt("deframer", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-2", p.version)
+
+	assert.NoError(t, ConfigurePrompts(""))
+	p, err = getPrompt("deframer", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, embedded.version, p.version)
+}
+
+type memoryCache struct {
+	entries map[string]*database.ThinkCache
+	finds   int
+}
+
+func (m *memoryCache) FindThinkCache(key string) (*database.ThinkResult, error) {
+	m.finds++
+	if entry, ok := m.entries[key]; ok {
+		res := *entry.ThinkResult
+		return &res, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryCache) UpsertThinkCache(entry *database.ThinkCache) error {
+	m.entries[entry.Key] = entry
+	return nil
+}
+
+type countingThink struct {
+	Think
+	calls int
+}
+
+func (c *countingThink) Run(prompt string, language string, request Request, ignoreCategoryErrors bool) (*database.ThinkResult, error) {
+	c.calls++
+	return c.Think.Run(prompt, language, request, ignoreCategoryErrors)
+}
+
+func TestCached_Run(t *testing.T) {
+	store := &memoryCache{entries: map[string]*database.ThinkCache{}}
+	inner := &countingThink{Think: newDummy()}
+	c := NewCached(context.Background(), inner, store, "dummy")
+
+	first, err := c.Run("deframer", "en", Request{Title: "Stocks  fall", Description: "Markets\nreact."}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+	assert.Len(t, store.entries, 1)
+
+	// whitespace differences hit the same entry
+	second, err := c.Run("deframer", "en", Request{Title: " Stocks fall ", Description: "Markets react."}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, first.Overall, second.Overall)
+
+	// case, language and failures are not folded
+	_, err = c.Run("deframer", "en", Request{Title: "STOCKS FALL", Description: "Markets react."}, false)
+	assert.NoError(t, err)
+	_, err = c.Run("deframer", "de", Request{Title: "Stocks fall", Description: "Markets react."}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, inner.calls)
+
+	failing := NewCached(context.Background(), newFail(), store, "fail")
+	_, err = failing.Run("deframer", "en", Request{Title: "x"}, false)
+	assert.Error(t, err)
+	assert.Len(t, store.entries, 3)
+}
+
+func TestCached_RunSkipsLenientResults(t *testing.T) {
+	store := &memoryCache{entries: map[string]*database.ThinkCache{}}
+	inner := &countingThink{Think: newDummy()}
+	c := NewCached(context.Background(), inner, store, "dummy")
+	req := Request{Title: "Stocks fall", Description: "Markets react."}
+
+	_, err := c.Run("deframer", "en", req, true)
+	assert.NoError(t, err)
+	assert.Empty(t, store.entries)
+
+	// a strict run still analyzes and validates the text
+	_, err = c.Run("deframer", "en", req, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+	assert.Len(t, store.entries, 1)
+
+	// a lenient run may use what a strict run stored
+	_, err = c.Run("deframer", "en", req, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCached_RunResolvesEvidence(t *testing.T) {
+	store := &memoryCache{entries: map[string]*database.ThinkCache{}}
+	inner := &countingThink{Think: newDummy()}
+	c := NewCached(context.Background(), inner, store, "dummy")
+
+	p, err := getPrompt("deframer", "en")
+	assert.NoError(t, err)
+	stored := Request{Title: "Stocks crash", Description: "Markets react."}
+	store.entries[cacheKey(p.version, "dummy", "en", stored)] = &database.ThinkCache{ThinkResult: &database.ThinkResult{
+		Evidence: []database.EvidenceSpan{
+			{Dimension: "clickbait", Field: "title", Quote: "crash", Start: 7, End: 12},
+		},
+	}}
+
+	res, err := c.Run("deframer", "en", Request{Title: "  Stocks   crash", Description: "Markets react."}, false)
+	assert.NoError(t, err)
+	assert.Zero(t, inner.calls)
+	assert.Equal(t, []database.EvidenceSpan{
+		{Dimension: "clickbait", Field: "title", Quote: "crash", Start: 11, End: 16},
+	}, res.Evidence)
+}
+
+func TestCacheKey(t *testing.T) {
+	req := Request{Title: "Café", Description: "d"}
+	decomposed := Request{Title: "Café", Description: "d"}
+	assert.Equal(t, cacheKey("v1", "m", "en", req), cacheKey("v1", "m", "en", decomposed))
+	assert.NotEqual(t, cacheKey("v1", "m", "en", req), cacheKey("v2", "m", "en", req))
+	assert.NotEqual(t, cacheKey("v1", "m", "en", req), cacheKey("v1", "m2", "en", req))
+	assert.Len(t, cacheKey("v1", "m", "en", req), 64)
+}
@@ -0,0 +1,41 @@
+package think
+
+import (
+	"errors"
+
+	"github.com/deframer/news-deframer/pkg/database"
+)
+
+// UsageError is returned when the LLM answered (and billed) but the answer
+// could not be used, so the tokens are still accounted for.
+type UsageError struct {
+	Usage []database.LLMUsage
+	Err   error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+func withUsage(err error, usage ...database.LLMUsage) error {
+	if len(usage) == 0 {
+		return err
+	}
+	return &UsageError{Usage: usage, Err: err}
+}
+
+// UsageOf returns the LLM usage behind a Run call, whether it succeeded or not.
+func UsageOf(res *database.ThinkResult, err error) []database.LLMUsage {
+	if res != nil {
+		return res.Usage
+	}
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return usageErr.Usage
+	}
+	return nil
+}
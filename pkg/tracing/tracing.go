@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	goa "goa.design/goa/v3/pkg"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+
+	instrumentationName = "github.com/deframer/news-deframer"
+)
+
+// Attribute keys shared by the spans of all components.
+const (
+	FeedIDKey = attribute.Key("deframer.feed.id")
+	ItemIDKey = attribute.Key("deframer.item.id")
+)
+
+// Setup installs the global tracer provider for cfg.TracesExporter and
+// returns the function flushing the pending spans on shutdown. Without an
+// exporter the no-op provider stays in place and spans cost next to nothing.
+// The OTLP exporter reads OTEL_EXPORTER_OTLP_ENDPOINT and friends, the
+// provider OTEL_TRACES_SAMPLER and OTEL_RESOURCE_ATTRIBUTES.
+func Setup(ctx context.Context, cfg *config.Config, serviceName string) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(cfg.TracesExporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown traces exporter: %s (expected %s, %s or %s)", cfg.TracesExporter, ExporterNone, ExporterOTLP, ExporterStdout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.TracesExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the defaults
+	if env, err := resource.New(ctx, resource.WithFromEnv()); err == nil {
+		if merged, err := resource.Merge(res, env); err == nil {
+			res = merged
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the global provider, so spans follow a
+// provider installed after the caller was created.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func FeedID(id uuid.UUID) attribute.KeyValue {
+	return FeedIDKey.String(id.String())
+}
+
+func ItemID(id uuid.UUID) attribute.KeyValue {
+	return ItemIDKey.String(id.String())
+}
+
+// Endpoint is a Goa endpoint middleware naming the span of the HTTP request
+// after the Goa service and method, e.g. "web.items".
+func Endpoint(e goa.Endpoint) goa.Endpoint {
+	return func(ctx context.Context, req any) (any, error) {
+		service, _ := ctx.Value(goa.ServiceKey).(string)
+		method, _ := ctx.Value(goa.MethodKey).(string)
+		span := trace.SpanFromContext(ctx)
+		span.SetName(service + "." + method)
+		span.SetAttributes(attribute.String("goa.service", service), attribute.String("goa.method", method))
+		return e(ctx, req)
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	goa "goa.design/goa/v3/pkg"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(context.Background(), &config.Config{TracesExporter: ExporterNone}, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, previous, otel.GetTracerProvider(), "no exporter keeps the no-op provider")
+
+	_, err = Setup(context.Background(), &config.Config{TracesExporter: "zipkin"}, "test")
+	assert.ErrorContains(t, err, "unknown traces exporter")
+
+	shutdown, err = Setup(context.Background(), &config.Config{TracesExporter: ExporterStdout}, "test")
+	assert.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := useRecorder(t)
+	feedID, itemID := uuid.New(), uuid.New()
+
+	ctx, parent := Start(context.Background(), "parent", FeedID(feedID))
+	_, child := Start(ctx, "child", ItemID(itemID))
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "child", spans[0].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Contains(t, spans[0].Attributes(), ItemIDKey.String(itemID.String()))
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Len(t, spans[0].Events(), 1, "the error is recorded")
+
+		assert.Contains(t, spans[1].Attributes(), FeedIDKey.String(feedID.String()))
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	recorder := useRecorder(t)
+
+	ctx, span := Start(context.Background(), "http.request")
+	ctx = context.WithValue(ctx, goa.ServiceKey, "web")
+	ctx = context.WithValue(ctx, goa.MethodKey, "items")
+	res, err := Endpoint(func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	})(ctx, "payload")
+	span.End()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "payload", res)
+	if spans := recorder.Ended(); assert.Len(t, spans, 1) {
+		assert.Equal(t, "web.items", spans[0].Name())
+	}
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/config"
+	"github.com/deframer/news-deframer/pkg/database"
+	"goa.design/clue/log"
+)
+
+const (
+	batchSize      = 20
+	requestTimeout = 10 * time.Second
+	// the claim of a batch outlives the delivery of all its requests, so
+	// another dispatcher never sends the same delivery twice
+	lockDuration    = batchSize*requestTimeout + time.Minute
+	MaxAttempts     = 8
+	initialBackoff  = 30 * time.Second
+	maxBackoff      = 6 * time.Hour
+	burstScanPeriod = 15 * time.Minute
+	// a topic bursts with three times its usual daily frequency on a domain
+	burstMinRatio     = 3.0
+	burstMinFrequency = 5
+	maxErrorLength    = 500
+	userAgent         = "news-deframer-webhook"
+)
+
+const (
+	HeaderEvent     = "X-Deframer-Event"
+	HeaderDelivery  = "X-Deframer-Delivery"
+	HeaderTimestamp = "X-Deframer-Timestamp"
+	HeaderSignature = "X-Deframer-Signature"
+)
+
+// Envelope is the JSON body of every delivery.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher delivers queued webhook events and detects trend bursts.
+type Dispatcher struct {
+	ctx      context.Context
+	repo     database.WebhookRepository
+	client   *http.Client
+	now      func() time.Time
+	lastScan time.Time
+}
+
+func New(ctx context.Context, repo database.WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		ctx:    ctx,
+		repo:   repo,
+		client: &http.Client{Timeout: requestTimeout},
+		now:    time.Now,
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers compare
+// it with the X-Deframer-Signature header (after the "sha256=" prefix) and
+// should reject old timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the delay after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) Poll() {
+	for {
+		if d.ctx.Err() != nil {
+			log.Printf(d.ctx, "Stopping poller")
+			return
+		}
+
+		if d.now().Sub(d.lastScan) >= burstScanPeriod {
+			d.lastScan = d.now()
+			if err := d.scanTrendBursts(); err != nil {
+				log.Errorf(d.ctx, err, "Failed to scan trend bursts")
+			}
+		}
+
+		delivered, err := d.deliverBatch()
+		if err != nil {
+			log.Errorf(d.ctx, err, "Failed to deliver webhooks")
+		}
+		if delivered > 0 {
+			continue
+		}
+
+		log.Debugf(d.ctx, "Sleeping duration=%s", config.IdleSleepTime)
+
+		select {
+		case <-d.ctx.Done():
+			log.Printf(d.ctx, "Stopping poller")
+			return
+		case <-time.After(config.IdleSleepTime):
+		}
+	}
+}
+
+// scanTrendBursts queues the bursts of today; each one is delivered once a day.
+func (d *Dispatcher) scanTrendBursts() error {
+	now := d.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	bursts, err := d.repo.FindTrendBursts(today, burstMinRatio, burstMinFrequency)
+	if err != nil {
+		return err
+	}
+	for _, burst := range bursts {
+		if err := d.repo.EnqueueWebhookEvent(database.WebhookEvent{
+			Type:       database.WebhookEventTrendBurst,
+			Key:        fmt.Sprintf("%s:%s:%s:%s", burst.RootDomain, burst.Language, burst.TrendTopic, burst.TimeSlice.Format(time.DateOnly)),
+			RootDomain: burst.RootDomain,
+			Data:       burst,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliverBatch() (int, error) {
+	deliveries, err := d.repo.BeginWebhookDeliveries(batchSize, lockDuration)
+	if err != nil {
+		return 0, err
+	}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		d.deliver(delivery)
+		if err := d.repo.UpdateWebhookDelivery(delivery); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends the delivery once and records the outcome on it.
+func (d *Dispatcher) deliver(delivery *database.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := d.send(delivery)
+	delivery.LastStatus = status
+	now := d.now()
+
+	if err == nil {
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+		log.Printf(d.ctx, "Delivered webhook event=%s delivery=%s url=%s", delivery.Event, delivery.ID, delivery.Webhook.URL)
+		return
+	}
+
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	delivery.LastError = &message
+	if delivery.Attempts >= MaxAttempts {
+		delivery.FailedAt = &now
+		log.Warnf(d.ctx, "Giving up webhook delivery=%s url=%s attempts=%d error=%s", delivery.ID, delivery.Webhook.URL, delivery.Attempts, message)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+	log.Warnf(d.ctx, "Webhook delivery failed delivery=%s url=%s attempts=%d retry_at=%s error=%s", delivery.ID, delivery.Webhook.URL, delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339), message)
+}
+
+func (d *Dispatcher) send(delivery *database.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.ID.String(),
+		Event:     delivery.Event,
+		CreatedAt: delivery.CreatedAt,
+		Data:      json.RawMessage(delivery.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.now().Unix()
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
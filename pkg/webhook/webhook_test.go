@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/deframer/news-deframer/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepo implements the methods the dispatcher uses.
+type fakeRepo struct {
+	database.WebhookRepository
+	due      []database.WebhookDelivery
+	updated  []database.WebhookDelivery
+	bursts   []database.TrendBurst
+	enqueued []database.WebhookEvent
+}
+
+func (r *fakeRepo) BeginWebhookDeliveries(limit int, lockDuration time.Duration) ([]database.WebhookDelivery, error) {
+	due := r.due
+	r.due = nil
+	return due, nil
+}
+
+func (r *fakeRepo) UpdateWebhookDelivery(delivery *database.WebhookDelivery) error {
+	r.updated = append(r.updated, *delivery)
+	return nil
+}
+
+func (r *fakeRepo) FindTrendBursts(since time.Time, minRatio float64, minFrequency int) ([]database.TrendBurst, error) {
+	return r.bursts, nil
+}
+
+func (r *fakeRepo) EnqueueWebhookEvent(event database.WebhookEvent) error {
+	r.enqueued = append(r.enqueued, event)
+	return nil
+}
+
+func newTestDelivery(url string) database.WebhookDelivery {
+	return database.WebhookDelivery{
+		ID:        uuid.New(),
+		CreatedAt: time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC),
+		Webhook:   database.Webhook{URL: url, Secret: "s3cret"},
+		Event:     database.WebhookEventItemAnalyzed,
+		Payload:   `{"hash":"abc"}`,
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "1698a50bc74d1ff1db85c4e0a5297c2ad9fdba245d5737cdb789e4cc6e098940", Sign("s3cret", 1700000000, []byte(`{"a":1}`)))
+	assert.NotEqual(t, Sign("s3cret", 1700000000, []byte(`{"a":1}`)), Sign("other", 1700000000, []byte(`{"a":1}`)))
+	assert.NotEqual(t, Sign("s3cret", 1700000000, []byte(`{"a":1}`)), Sign("s3cret", 1700000001, []byte(`{"a":1}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, maxBackoff, Backoff(20))
+}
+
+func TestDeliverSigned(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := &fakeRepo{due: []database.WebhookDelivery{newTestDelivery(server.URL)}}
+	d := New(context.Background(), repo)
+
+	delivered, err := d.deliverBatch()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	if assert.Len(t, repo.updated, 1) {
+		delivery := repo.updated[0]
+		assert.NotNil(t, delivery.DeliveredAt)
+		assert.Nil(t, delivery.FailedAt)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusNoContent, delivery.LastStatus)
+	}
+
+	if assert.NotNil(t, received) {
+		assert.Equal(t, database.WebhookEventItemAnalyzed, received.Header.Get(HeaderEvent))
+		timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, "sha256="+Sign("s3cret", timestamp, body), received.Header.Get(HeaderSignature))
+		assert.JSONEq(t, `{"id":"`+repo.updated[0].ID.String()+`","event":"item.analyzed","created_at":"2026-05-17T12:00:00Z","data":{"hash":"abc"}}`, string(body))
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC)
+	d := New(context.Background(), &fakeRepo{})
+	d.now = func() time.Time { return now }
+
+	delivery := newTestDelivery(server.URL)
+	delivery.Attempts = 2
+	d.deliver(&delivery)
+
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusBadGateway, delivery.LastStatus)
+	assert.Nil(t, delivery.DeliveredAt)
+	assert.Nil(t, delivery.FailedAt)
+	assert.Equal(t, now.Add(2*time.Minute), delivery.NextAttemptAt)
+	if assert.NotNil(t, delivery.LastError) {
+		assert.Contains(t, *delivery.LastError, "502")
+	}
+
+	// the last attempt gives up
+	delivery.Attempts = MaxAttempts - 1
+	d.deliver(&delivery)
+	assert.NotNil(t, delivery.FailedAt)
+}
+
+func TestDeliverUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	d := New(context.Background(), &fakeRepo{})
+	delivery := newTestDelivery(server.URL)
+	d.deliver(&delivery)
+
+	assert.Equal(t, 0, delivery.LastStatus)
+	assert.Nil(t, delivery.DeliveredAt)
+	assert.NotNil(t, delivery.LastError)
+}
+
+func TestScanTrendBursts(t *testing.T) {
+	repo := &fakeRepo{bursts: []database.TrendBurst{
+		{TrendTopic: "flood", Language: "en", RootDomain: "example.com", Frequency: 12, OutlierRatio: 6, TimeSlice: time.Date(2026, 5, 17, 0, 0, 0, 0, time.UTC)},
+	}}
+	d := New(context.Background(), repo)
+
+	assert.NoError(t, d.scanTrendBursts())
+	if assert.Len(t, repo.enqueued, 1) {
+		event := repo.enqueued[0]
+		assert.Equal(t, database.WebhookEventTrendBurst, event.Type)
+		assert.Equal(t, "example.com:en:flood:2026-05-17", event.Key)
+		assert.Equal(t, "example.com", event.RootDomain)
+	}
+}